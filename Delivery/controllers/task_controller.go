@@ -1,8 +1,14 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	// "time"
+	"strconv"
+	"strings"
+	"time"
 
 	domain "task_management/Domain"
 	usecases "task_management/usecases"
@@ -14,6 +20,7 @@ import (
 //holds a reference to the user usecase
 type UserController struct {
 	UserUseCase *usecases.UserUseCase
+	AuthService usecases.IAuthService
 }
 type TaskController struct{
 	TaskUseCase *usecases.TaskUseCase
@@ -23,11 +30,26 @@ type RegisterUserInputDTO struct{
 	Password string   `json:"password"`
 }
 
+// LoginInputDTO is the login request, IncludeTokens also returns the tokens in the body
+// for clients that send them in the Authorization header instead of cookies
+type LoginInputDTO struct {
+	RegisterUserInputDTO
+	IncludeTokens bool `json:"includeTokens"`
+}
+
+// RefreshInputDTO is the optional body of a refresh or logout, the refresh token in it
+// is used instead of the refresh_token cookie
+type RefreshInputDTO struct {
+	RefreshToken  string `json:"refreshToken"`
+	IncludeTokens bool   `json:"includeTokens"`
+}
+
 //constructor
 
-func NewUserController (uc *usecases.UserUseCase) *UserController{
+func NewUserController (uc *usecases.UserUseCase, authService usecases.IAuthService) *UserController{
 	return &UserController{
 		UserUseCase: uc,
+		AuthService: authService,
 	}
 }
 
@@ -42,13 +64,13 @@ func (userctrl *UserController) Register(c *gin.Context) {
 	var newUser RegisterUserInputDTO
 
 	if err := c.ShouldBindJSON(&newUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input:" + err.Error()})
+		_ = c.Error(bindingError(err, "invalid input: request body must be a JSON object with username and password"))
 		return
 	}
 
-	user, err := userctrl.UserUseCase.Register(userctrl.ChangeToDomain(&newUser))
+	user, err := userctrl.UserUseCase.Register(c.Request.Context(), userctrl.ChangeToDomain(&newUser))
 	if err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -58,55 +80,269 @@ func (userctrl *UserController) Register(c *gin.Context) {
 
 // Login controller
 func (userctrl *UserController) Login(c *gin.Context) {
-	var input RegisterUserInputDTO
+	var input LoginInputDTO
 
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "invalid input format"})
+		_ = c.Error(bindingError(err, "invalid input format"))
+		return
+	}
+
+	client := domain.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+	pair, user, err := userctrl.UserUseCase.Login(c.Request.Context(), *userctrl.ChangeToDomain(&input.RegisterUserInputDTO), client)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setTokenCookies(c, pair)
+	c.IndentedJSON(http.StatusOK, tokenResponse("login successful", user, pair, input.IncludeTokens))
+}
+
+// Refresh controller, swaps the refresh token of the body or the refresh_token cookie
+// for a new pair of tokens
+func (userctrl *UserController) Refresh(c *gin.Context) {
+	input, ok := bindRefreshInput(c)
+	if !ok {
 		return
 	}
 
-	token, user, err := userctrl.UserUseCase.Login( *userctrl.ChangeToDomain(&input))
+	pair, user, err := userctrl.UserUseCase.Refresh(c.Request.Context(), input.RefreshToken)
 	if err != nil {
-		c.IndentedJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		//a dead refresh token is of no use to the client anymore
+		clearTokenCookies(c)
+		_ = c.Error(err)
 		return
 	}
 
-	c.SetCookie("auth_token", token, 3600*24, "/", "", false, true)
+	setTokenCookies(c, pair)
+	c.IndentedJSON(http.StatusOK, tokenResponse("token refreshed", user, pair, input.IncludeTokens))
+}
+
+//Logout controller, revokes both tokens before clearing the cookies
+func (userctrl *UserController) Logout(c *gin.Context) {
+	input, ok := bindRefreshInput(c)
+	if !ok {
+		return
+	}
+	//the token the auth middleware would check, a request without one only ends the refresh token
+	accessToken, _ := userctrl.AuthService.AccessToken(c.Request)
+	if err := userctrl.UserUseCase.Logout(c.Request.Context(), accessToken, input.RefreshToken); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	clearTokenCookies(c)
 	c.IndentedJSON(http.StatusOK, gin.H{
-		"message": "login successful",
+		"message": "logged out successfully",
+	})
+}
+
+// reads the optional refresh body, the refresh_token cookie fills in a missing token.
+// adds a 400 error and returns false when the body is not valid JSON
+func bindRefreshInput(c *gin.Context) (RefreshInputDTO, bool) {
+	var input RefreshInputDTO
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		_ = c.Error(bindingError(err, "invalid input: request body must be a JSON object with refreshToken"))
+		return input, false
+	}
+	if input.RefreshToken == "" {
+		input.RefreshToken, _ = c.Cookie("refresh_token")
+	}
+	return input, true
+}
+
+// builds the login and refresh response, the tokens are only in the body when asked for
+func tokenResponse(message string, user *domain.User, pair *domain.TokenPair, includeTokens bool) gin.H {
+	body := gin.H{
+		"message": message,
 		"user": gin.H{
-			"id":       user.ID.Hex(),
+			"id":       user.ID,
 			"username": user.Username,
 			"role":     user.Role,
 		},
-	})
+	}
+	if includeTokens {
+		body["tokens"] = gin.H{
+			"tokenType":        "Bearer",
+			"accessToken":      pair.AccessToken,
+			"accessExpiresAt":  pair.AccessExpiresAt,
+			"refreshToken":     pair.RefreshToken,
+			"refreshExpiresAt": pair.RefreshExpiresAt,
+		}
+	}
+	return body
 }
-//Logout controller
-func (userctrl *UserController) Logout(c *gin.Context) {
+
+// the cookies live as long as the tokens they carry
+func setTokenCookies(c *gin.Context, pair *domain.TokenPair) {
+	c.SetCookie("auth_token", pair.AccessToken, secondsUntil(pair.AccessExpiresAt), "/", "", false, true)
+	c.SetCookie("refresh_token", pair.RefreshToken, secondsUntil(pair.RefreshExpiresAt), "/", "", false, true)
+}
+
+func clearTokenCookies(c *gin.Context) {
 	c.SetCookie("auth_token", "", -1, "/", "", false, true)
-	c.IndentedJSON(http.StatusOK, gin.H{
-		"message": "logged out successfully",
-	})
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+}
+
+func secondsUntil(t time.Time) int {
+	return int(time.Until(t).Seconds())
 }
 
-// PromoteUser controller
-func (userctrl *UserController) PromoteUser(c *gin.Context) {
+// ChangeRole controller, grants or revokes the admin role of a user
+func (userctrl *UserController) ChangeRole(c *gin.Context) {
 	var req struct {
-		UserID string `json:"userId"`
+		Role domain.Role `json:"role"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		_ = c.Error(bindingError(err, "invalid request"))
+		return
+	}
+
+	user, err := userctrl.UserUseCase.ChangeRole(c.Request.Context(), actorFromContext(c), c.Param("id"), req.Role)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// ListUsers controller, ?search=<text>&role=<role> with the usual limit and cursor paging
+func (userctrl *UserController) ListUsers(c *gin.Context) {
+	filter := domain.UserFilter{
+		Search: c.Query("search"),
+		Role:   domain.Role(c.Query("role")),
+		Cursor: c.Query("cursor"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			_ = c.Error(domain.FieldValidation("limit", "limit must be a number"))
+			return
+		}
+		filter.Limit = n
+	}
+
+	page, err := userctrl.UserUseCase.ListUsers(c.Request.Context(), actorFromContext(c), filter)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
+	c.IndentedJSON(http.StatusOK, page)
+}
 
-	err := userctrl.UserUseCase.PromoteUser( req.UserID)
+// GetUser controller
+func (userctrl *UserController) GetUser(c *gin.Context) {
+	user, err := userctrl.UserUseCase.GetUser(c.Request.Context(), actorFromContext(c), c.Param("id"))
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
+	c.IndentedJSON(http.StatusOK, user)
+}
 
-	c.IndentedJSON(http.StatusOK, gin.H{"message": "user promoted to admin"})
+// DeactivateUser controller
+func (userctrl *UserController) DeactivateUser(c *gin.Context) {
+	user, err := userctrl.UserUseCase.DeactivateUser(c.Request.Context(), actorFromContext(c), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// ListSessions controller, the logins of the current user
+func (userctrl *UserController) ListSessions(c *gin.Context) {
+	sessions, err := userctrl.UserUseCase.ListSessions(c.Request.Context(), actorFromContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, sessions)
+}
+
+// RevokeSession controller, logs one session of the current user out
+func (userctrl *UserController) RevokeSession(c *gin.Context) {
+	if err := userctrl.UserUseCase.RevokeSession(c.Request.Context(), actorFromContext(c), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"message": "session revoked",
+	})
+}
+
+// CreatePersonalToken controller, the token is in the response once and never again
+func (userctrl *UserController) CreatePersonalToken(c *gin.Context) {
+	var input domain.PersonalTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(bindingError(err, "invalid input: request body must be a JSON object with name, scopes and an optional expiresAt"))
+		return
+	}
+
+	personal, token, err := userctrl.UserUseCase.CreatePersonalToken(c.Request.Context(), actorFromContext(c), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusCreated, struct {
+		*domain.PersonalToken
+		Token string `json:"token"`
+	}{personal, token})
+}
+
+// ListPersonalTokens controller, lists the usable personal access tokens of the current user
+func (userctrl *UserController) ListPersonalTokens(c *gin.Context) {
+	tokens, err := userctrl.UserUseCase.ListPersonalTokens(c.Request.Context(), actorFromContext(c))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, tokens)
+}
+
+// RevokePersonalToken controller, revokes one personal access token of the current user
+func (userctrl *UserController) RevokePersonalToken(c *gin.Context) {
+	if err := userctrl.UserUseCase.RevokePersonalToken(c.Request.Context(), actorFromContext(c), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"message": "personal token revoked",
+	})
+}
+
+// LogoutEverywhere controller, revokes every token of the user
+func (userctrl *UserController) LogoutEverywhere(c *gin.Context) {
+	if err := userctrl.UserUseCase.LogoutEverywhere(c.Request.Context(), actorFromContext(c), c.Param("id")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{
+		"message": "user logged out everywhere",
+	})
+}
+
+// ReactivateUser controller
+func (userctrl *UserController) ReactivateUser(c *gin.Context) {
+	user, err := userctrl.UserUseCase.ReactivateUser(c.Request.Context(), actorFromContext(c), c.Param("id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, user)
+}
+
+// DeleteUser controller, ?tasks=transfer|trash decides what happens to the tasks
+// of the user and ?to=<userId> picks who receives them on a transfer
+func (userctrl *UserController) DeleteUser(c *gin.Context) {
+	tasks := domain.OrphanedTasks(c.Query("tasks"))
+	err := userctrl.UserUseCase.DeleteUser(c.Request.Context(), actorFromContext(c), c.Param("id"), tasks, c.Query("to"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "user deleted"})
 }
 //function to change the dto to domain
 func (userctrl *UserController)ChangeToDomain(input *RegisterUserInputDTO)*domain.RegisterUserInput{
@@ -115,26 +351,167 @@ func (userctrl *UserController)ChangeToDomain(input *RegisterUserInputDTO)*domai
 	user.Password = input.Password
 	return &user
 }
+// bindingError turns a request body decoding error into a validation error,
+// type mismatches are reported on the field they happened on
+func bindingError(err error, message string) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return domain.InvalidFields(message, []domain.FieldError{
+			{Field: typeErr.Field, Message: fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type.Kind())},
+		})
+	}
+	return domain.Validation(message)
+}
+
+// builds the actor from the user info the auth middleware put in the context
+func actorFromContext(c *gin.Context) domain.Actor {
+	return domain.Actor{
+		UserID:    c.GetString("userID"),
+		Role:      domain.Role(c.GetString("userRole")),
+		SessionID: c.GetString("sessionID"),
+	}
+}
+
+// formats a task version as a strong ETag
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// reads the task version the client last saw from the If-Match header.
+// adds a 428/412 error and returns false when the header is missing or unusable
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		_ = c.Error(&statusError{status: http.StatusPreconditionRequired, message: "If-Match header with the task ETag is required"})
+		return 0, false
+	}
+	tag := strings.TrimSpace(header)
+	if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+		_ = c.Error(&statusError{status: http.StatusPreconditionFailed, message: "If-Match must be a task ETag such as \"3\""})
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		_ = c.Error(&statusError{status: http.StatusPreconditionFailed, message: "If-Match must be a task ETag such as \"3\""})
+		return 0, false
+	}
+	return version, true
+}
+
+// statusError is a request error with no domain meaning, e.g. a missing header
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// StatusCode is read by the error handler middleware
+func (e *statusError) StatusCode() int {
+	return e.status
+}
+
 //get all tasks controller
 
 func (taskctrl *TaskController)GetTasks(c *gin.Context) {
-	tasks,err := taskctrl.TaskUseCase.GetAllTasks()
-	if err!=nil{
-		c.IndentedJSON(http.StatusInternalServerError,  gin.H{"error":err.Error()})
+	filter, err := taskFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page,err := taskctrl.TaskUseCase.GetAllTasks(c.Request.Context(), actorFromContext(c), filter)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, tasks)
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// reads the filtering, sorting and paging query parameters of GET /tasks
+// e.g. ?status=in-progress&title=report&sort=dueDate&order=desc&limit=10&cursor=...
+func taskFilterFromQuery(c *gin.Context) (domain.TaskFilter, error) {
+	filter := domain.TaskFilter{
+		Status:        domain.TaskStatus(c.Query("status")),
+		TitleContains: c.Query("title"),
+		SortBy:        c.Query("sort"),
+		Cursor:        c.Query("cursor"),
+	}
+
+	//?assignee=me or ?assignee=<userId> narrows the list down to someone's queue
+	if assignee := c.Query("assignee"); assignee == "me" {
+		filter.AssigneeID = c.GetString("userID")
+	} else {
+		filter.AssigneeID = assignee
+	}
+
+	//?overdue=true or ?dueWithin=<days>
+	if overdue := c.Query("overdue"); overdue != "" {
+		b, err := strconv.ParseBool(overdue)
+		if err != nil {
+			return filter, domain.FieldValidation("overdue", "overdue must be true or false")
+		}
+		filter.Overdue = b
+	}
+	if days := c.Query("dueWithin"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return filter, domain.FieldValidation("dueWithin", "dueWithin must be a number of days")
+		}
+		filter.DueWithinDays = n
+	}
+
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, domain.FieldValidation("order", "order must be asc or desc")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return filter, domain.FieldValidation("limit", "limit must be a number")
+		}
+		filter.Limit = n
+	}
+
+	var err error
+	if filter.DueAfter, err = timeQuery(c, "dueAfter"); err != nil {
+		return filter, err
+	}
+	if filter.DueBefore, err = timeQuery(c, "dueBefore"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parses an optional RFC3339 query parameter
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, domain.FieldValidation(name, fmt.Sprintf("%s must be an RFC3339 date, e.g. 2006-01-02T15:04:05Z", name))
+	}
+	return &t, nil
 }
 
 func (taskctrl *TaskController)GetTaskByID(c *gin.Context) {
 	id := c.Param("id")
 
 	
-	task, err := taskctrl.TaskUseCase.GetTaskByID(id)
+	task, err := taskctrl.TaskUseCase.GetTaskByID(c.Request.Context(), actorFromContext(c), id)
 	if err !=nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", versionETag(task.Version))
 	c.IndentedJSON(http.StatusOK, task)
 }
 
@@ -142,12 +519,12 @@ func (taskctrl *TaskController) AddTask(c *gin.Context) {
 	var newTask domain.InputTask
 
 	if err := c.ShouldBindJSON(&newTask); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error here ": err.Error()})
+		_ = c.Error(bindingError(err, "invalid input: request body must be a task JSON object"))
 		return
 	}
-	tasknew,err := taskctrl.TaskUseCase.AddTask(&newTask)
-	if err !=nil  {
-		c.IndentedJSON(http.StatusConflict, gin.H{"error": "task already exits"})
+	tasknew,err := taskctrl.TaskUseCase.AddTask(c.Request.Context(), actorFromContext(c), &newTask)
+	if err !=nil {
+		_ = c.Error(err)
 		return
 	}
 	c.IndentedJSON(http.StatusCreated, tasknew)
@@ -156,38 +533,245 @@ func (taskctrl *TaskController) AddTask(c *gin.Context) {
 //controller to delete a task
 func (taskctrl *TaskController) DeleteTaskByID(c *gin.Context) {
 	id := c.Param("id")
-	
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
 
-	
-	err :=taskctrl.TaskUseCase.DeleteTaskByID(id)
+	err :=taskctrl.TaskUseCase.DeleteTaskByID(c.Request.Context(), actorFromContext(c), id, version)
 	if err !=nil {
-		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		_ = c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, gin.H{"message": "task deleted"})
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "task moved to trash"})
 
 }
 
 //controller to update task by id 
 func (taskctrl *TaskController) UpdateTaskByID(c *gin.Context) {
 	id := c.Param("id")
-	
-	
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
 
-	var updatedTask domain.Task
-	if err := c.ShouldBindJSON(&updatedTask); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var input domain.InputTask
+	if err := c.ShouldBindJSON(&input); err != nil {
+		_ = c.Error(bindingError(err, "invalid input: request body must be a task JSON object"))
 		return
 	}
 
-	err := taskctrl.TaskUseCase.UpdateTaskByID(id, &updatedTask)
+	updatedTask, err := taskctrl.TaskUseCase.UpdateTaskByID(c.Request.Context(), actorFromContext(c), id, &input, version)
 	if err !=nil{
-		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", versionETag(updatedTask.Version))
 	c.IndentedJSON(http.StatusOK, updatedTask)
 
 }
 
+//controller to partially update a task with a JSON merge patch (RFC 7396)
+//or a JSON patch (RFC 6902), plain application/json is read as a merge patch
+func (taskctrl *TaskController) PatchTaskByID(c *gin.Context) {
+	id := c.Param("id")
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
 
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		_ = c.Error(domain.Validation("could not read request body"))
+		return
+	}
 
+	var patch *domain.TaskPatch
+	switch c.ContentType() {
+	case mergePatchContentType, "application/json":
+		patch, err = parseMergePatch(body)
+	case jsonPatchContentType:
+		patch, err = parseJSONPatch(body)
+	default:
+		_ = c.Error(&statusError{status: http.StatusUnsupportedMediaType, message: "use " + mergePatchContentType + " or " + jsonPatchContentType})
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	task, err := taskctrl.TaskUseCase.PatchTask(c.Request.Context(), actorFromContext(c), id, patch, version)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Header("ETag", versionETag(task.Version))
+	c.IndentedJSON(http.StatusOK, task)
+}
+
+//controller to assign a user to a task
+func (taskctrl *TaskController) AddAssignee(c *gin.Context) {
+	id := c.Param("id")
+
+	var req struct {
+		UserID string `json:"userId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.UserID == "" {
+		_ = c.Error(domain.FieldValidation("userId", "userId is required"))
+		return
+	}
+
+	task, err := taskctrl.TaskUseCase.AddAssignee(c.Request.Context(), actorFromContext(c), id, req.UserID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, task)
+}
+
+//controller to remove a user from the task assignees
+func (taskctrl *TaskController) RemoveAssignee(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.Param("userId")
+
+	task, err := taskctrl.TaskUseCase.RemoveAssignee(c.Request.Context(), actorFromContext(c), id, userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, task)
+}
+
+//controller to move a task to another status
+func (taskctrl *TaskController) TransitionTask(c *gin.Context) {
+	id := c.Param("id")
+
+	var req struct {
+		To domain.TaskStatus `json:"to"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.To == "" {
+		_ = c.Error(domain.FieldValidation("to", "to is required"))
+		return
+	}
+
+	task, err := taskctrl.TaskUseCase.TransitionTask(c.Request.Context(), actorFromContext(c), id, req.To)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, task)
+}
+
+//controller to list the status transitions of a task
+func (taskctrl *TaskController) GetTaskTransitions(c *gin.Context) {
+	id := c.Param("id")
+
+	transitions, err := taskctrl.TaskUseCase.GetTaskTransitions(c.Request.Context(), actorFromContext(c), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, transitions)
+}
+
+//controller to list the tasks in the trash, takes the same query parameters as GET /tasks
+func (taskctrl *TaskController) GetTrash(c *gin.Context) {
+	filter, err := taskFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page, err := taskctrl.TaskUseCase.GetTrash(c.Request.Context(), actorFromContext(c), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+//controller to take a task back out of the trash
+func (taskctrl *TaskController) RestoreTask(c *gin.Context) {
+	id := c.Param("id")
+
+	task, err := taskctrl.TaskUseCase.RestoreTask(c.Request.Context(), actorFromContext(c), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Header("ETag", versionETag(task.Version))
+	c.IndentedJSON(http.StatusOK, task)
+}
+
+//controller to permanently remove a trashed task, admins only
+func (taskctrl *TaskController) PurgeTask(c *gin.Context) {
+	id := c.Param("id")
+
+	err := taskctrl.TaskUseCase.PurgeTask(c.Request.Context(), actorFromContext(c), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "task purged"})
+}
+
+//controller to list the change history of a task, newest first
+func (taskctrl *TaskController) GetTaskHistory(c *gin.Context) {
+	id := c.Param("id")
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	page, err := taskctrl.TaskUseCase.GetTaskHistory(c.Request.Context(), actorFromContext(c), id, filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+//controller to search the audit trail of every task, admins only
+//e.g. ?actor=<userId>&task=<taskId>&from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z
+func (taskctrl *TaskController) GetAuditTrail(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	filter.TaskID = c.Query("task")
+	filter.UserID = c.Query("user")
+
+	page, err := taskctrl.TaskUseCase.GetAuditTrail(c.Request.Context(), actorFromContext(c), filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// reads the actor, time range and paging query parameters of the audit endpoints
+func auditFilterFromQuery(c *gin.Context) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		ActorID: c.Query("actor"),
+		Cursor:  c.Query("cursor"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return filter, domain.FieldValidation("limit", "limit must be a number")
+		}
+		filter.Limit = n
+	}
+
+	var err error
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
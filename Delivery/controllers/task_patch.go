@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	domain "task_management/Domain"
+)
+
+// content types accepted by PATCH /tasks/:id
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// jsonPatchOperation is one operation of an RFC 6902 JSON Patch document
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// parseMergePatch reads an RFC 7396 merge patch, null removes a member
+func parseMergePatch(body []byte) (*domain.TaskPatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, domain.Validation("merge patch must be a JSON object")
+	}
+
+	patch := &domain.TaskPatch{}
+	for field, value := range members {
+		if isJSONNull(value) {
+			if err := removeTaskField(patch, field); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := setTaskField(patch, field, value); err != nil {
+			return nil, err
+		}
+	}
+	return patch, nil
+}
+
+// parseJSONPatch reads an RFC 6902 JSON Patch, only add, replace and remove on
+// top level task fields are supported
+func parseJSONPatch(body []byte) (*domain.TaskPatch, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return nil, domain.Validation("JSON patch must be an array of operations")
+	}
+
+	patch := &domain.TaskPatch{}
+	for _, operation := range operations {
+		field := strings.TrimPrefix(operation.Path, "/")
+		if field == operation.Path || strings.Contains(field, "/") {
+			return nil, domain.Validation(fmt.Sprintf("unsupported path %q", operation.Path))
+		}
+
+		var err error
+		switch operation.Op {
+		case "add", "replace":
+			if operation.Value == nil {
+				return nil, domain.Validation(fmt.Sprintf("%s operation on %q needs a value", operation.Op, operation.Path))
+			}
+			err = setTaskField(patch, field, operation.Value)
+		case "remove":
+			err = removeTaskField(patch, field)
+		default:
+			err = domain.Validation(fmt.Sprintf("unsupported operation %q", operation.Op))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return patch, nil
+}
+
+// setTaskField decodes the new value of a patchable task field
+func setTaskField(patch *domain.TaskPatch, field string, value json.RawMessage) error {
+	var target interface{}
+	switch field {
+	case "title":
+		patch.Title = new(string)
+		target = patch.Title
+	case "description":
+		patch.Description = new(string)
+		target = patch.Description
+	case "dueDate":
+		patch.DueDate = new(string)
+		target = patch.DueDate
+	case "status":
+		patch.Status = new(domain.TaskStatus)
+		target = patch.Status
+	default:
+		return domain.FieldValidation(field, fmt.Sprintf("field %q cannot be patched", field))
+	}
+	if err := json.Unmarshal(value, target); err != nil {
+		return domain.FieldValidation(field, fmt.Sprintf("%s must be a string", field))
+	}
+	return nil
+}
+
+// removeTaskField clears an optional task field, required fields cannot be removed
+func removeTaskField(patch *domain.TaskPatch, field string) error {
+	empty := ""
+	switch field {
+	case "description":
+		patch.Description = &empty
+	case "dueDate":
+		patch.DueDate = &empty
+	case "title", "status":
+		return domain.FieldValidation(field, fmt.Sprintf("field %q cannot be removed", field))
+	default:
+		return domain.FieldValidation(field, fmt.Sprintf("field %q cannot be patched", field))
+	}
+	return nil
+}
+
+func isJSONNull(value json.RawMessage) bool {
+	return strings.TrimSpace(string(value)) == "null"
+}
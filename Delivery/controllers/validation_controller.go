@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"net/http"
+
+	usecases "task_management/usecases"
+
+	"github.com/gin-gonic/gin"
+)
+
+//controller to publish the input validation rules so clients can check input before sending it
+func GetValidationRules(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, usecases.ValidationRules())
+}
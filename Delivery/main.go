@@ -1,39 +1,141 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
 	"task_management/Delivery/controllers"
 	"task_management/Delivery/router"
+	"task_management/config"
+	"task_management/db"
 	infrastructure "task_management/infrastructure"
 	repositories "task_management/Repositories"
+	"task_management/Repositories/sqlstore"
 	usecases "task_management/usecases"
 	
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Load the configuration, a bad configuration stops the server before it starts
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
 	// Initialize Gin router
 	r := gin.Default()
 	
 	// Initialize dependencies
-	userRepo := repositories.NewUserRepository()
-	taskRepo := repositories.NewTaskRepository()
+	repos, err := newRepositories(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 	passwordService := infrastructure.NewPasswordService()
-	jwtService := infrastructure.NewJWTService("key")
-	authService:=infrastructure.NewAuthService("key")
+	jwtService := infrastructure.NewJWTService(cfg.JWT.Secret, cfg.JWT.TTL, cfg.JWT.RefreshTTL)
+	authService:=infrastructure.NewAuthService(cfg.JWT.Secret, repos.users, repos.revocations, repos.sessions, repos.personalTokens, infrastructure.TokenPrecedence(cfg.JWT.TokenPrecedence))
 	
 	// Create use cases
-	userUseCase := usecases.NewUserUseCase(userRepo, passwordService, jwtService)
-	taskUseCase := usecases.NewTaskUseCase(taskRepo)
+	userUseCase := usecases.NewUserUseCase(repos.users, passwordService, jwtService, repos.audit, repos.tasks, repos.refreshTokens, repos.revocations, repos.sessions, repos.personalTokens)
+	taskUseCase := usecases.NewTaskUseCase(repos.tasks, repos.users, repos.audit)
+	taskUseCase.TrashRetention = cfg.Tasks.TrashRetention
 	
+	// Permanently remove tasks that outlived the trash retention window
+	go purgeExpiredTrash(taskUseCase, cfg.Tasks.PurgeInterval)
+
 	// Create controllers
-	userController := controllers.NewUserController(userUseCase)
+	userController := controllers.NewUserController(userUseCase, authService)
 	taskController := controllers.NewTaskController(taskUseCase)
 	
+	// Tag every request with an id and render the errors added by the handlers
+	r.Use(infrastructure.RequestID(), infrastructure.ErrorHandler())
+
 	// Setup routes
 	if err := router.SetUpRoutes(r, userController, taskController,authService); err != nil {
 		panic(err) 
 	}
 	
 	// Start server
-	r.Run(":8080")
-}
\ No newline at end of file
+	if err := r.Run(cfg.Server.Addr()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// repositorySet holds the repositories of one storage backend
+type repositorySet struct {
+	users          usecases.IUserRepository
+	tasks          usecases.ITaskRepo
+	audit          usecases.IAuditRepo
+	refreshTokens  usecases.IRefreshTokenRepo
+	revocations    usecases.ITokenRevocationRepo
+	sessions       usecases.ISessionRepo
+	personalTokens usecases.IPersonalTokenRepo
+}
+
+// newRepositories builds the repositories of the configured storage backend
+func newRepositories(cfg *config.Config) (*repositorySet, error) {
+	switch cfg.Storage.Backend {
+	case config.BackendMemory:
+		log.Print("using the in-memory storage, data is lost on restart")
+		return &repositorySet{
+			users:          repositories.NewMemoryUserRepository(),
+			tasks:          repositories.NewMemoryTaskRepository(),
+			audit:          repositories.NewMemoryAuditRepository(),
+			refreshTokens:  repositories.NewMemoryRefreshTokenRepository(),
+			revocations:    repositories.NewMemoryTokenRevocationRepository(),
+			sessions:       repositories.NewMemorySessionRepository(),
+			personalTokens: repositories.NewMemoryPersonalTokenRepository(),
+		}, nil
+	case config.BackendMongo:
+		if err := db.Connect(cfg.Mongo); err != nil {
+			return nil, fmt.Errorf("connecting to mongo: %w", err)
+		}
+		timeout := cfg.Mongo.OperationTimeout
+		return &repositorySet{
+			users:          repositories.NewUserRepository(timeout),
+			tasks:          repositories.NewTaskRepository(timeout),
+			audit:          repositories.NewAuditRepository(timeout),
+			refreshTokens:  repositories.NewRefreshTokenRepository(timeout),
+			revocations:    repositories.NewTokenRevocationRepository(timeout),
+			sessions:       repositories.NewSessionRepository(timeout),
+			personalTokens: repositories.NewPersonalTokenRepository(timeout),
+		}, nil
+	case config.BackendSQLite, config.BackendPostgres:
+		//the pending migrations are applied before the server accepts requests
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		store, err := sqlstore.Open(ctx, cfg.Storage.Backend, cfg.SQL.DSN)
+		if err != nil {
+			return nil, err
+		}
+		timeout := cfg.SQL.OperationTimeout
+		return &repositorySet{
+			users:          sqlstore.NewUserRepository(store, timeout),
+			tasks:          sqlstore.NewTaskRepository(store, timeout),
+			audit:          sqlstore.NewAuditRepository(store, timeout),
+			refreshTokens:  sqlstore.NewRefreshTokenRepository(store, timeout),
+			revocations:    sqlstore.NewTokenRevocationRepository(store, timeout),
+			sessions:       sqlstore.NewSessionRepository(store, timeout),
+			personalTokens: sqlstore.NewPersonalTokenRepository(store, timeout),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+}
+
+// purgeExpiredTrash empties the expired part of the trash on every tick
+func purgeExpiredTrash(taskUseCase *usecases.TaskUseCase, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		purged, err := taskUseCase.PurgeExpiredTrash(context.Background())
+		if err != nil {
+			log.Printf("purging expired trash: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("purged %d expired tasks from the trash", purged)
+		}
+	}
+}
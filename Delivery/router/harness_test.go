@@ -0,0 +1,172 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"task_management/Delivery/controllers"
+	"task_management/Delivery/router"
+	repositories "task_management/Repositories"
+	infrastructure "task_management/infrastructure"
+	"task_management/usecases"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "router-test-secret-of-32-characters"
+
+// harness serves the real routes, controllers, use cases and middleware
+// in process, the data lives in the memory repositories
+type harness struct {
+	t      *testing.T
+	engine *gin.Engine
+	users  usecases.IUserRepository
+	tasks  usecases.ITaskRepo
+}
+
+func newHarness(t *testing.T) *harness {
+	return newHarnessWithPrecedence(t, infrastructure.HeaderFirst)
+}
+
+// newHarnessWithPrecedence serves the routes with the given token precedence policy
+func newHarnessWithPrecedence(t *testing.T, precedence infrastructure.TokenPrecedence) *harness {
+	gin.SetMode(gin.TestMode)
+	h := &harness{
+		t:      t,
+		engine: gin.New(),
+		users:  repositories.NewMemoryUserRepository(),
+		tasks:  repositories.NewMemoryTaskRepository(),
+	}
+	auditRepo := repositories.NewMemoryAuditRepository()
+	jwtService := infrastructure.NewJWTService(testSecret, time.Hour, 24*time.Hour)
+	revocations := repositories.NewMemoryTokenRevocationRepository()
+	sessions := repositories.NewMemorySessionRepository()
+	personalTokens := repositories.NewMemoryPersonalTokenRepository()
+	userUseCase := usecases.NewUserUseCase(h.users, infrastructure.NewPasswordService(), jwtService, auditRepo, h.tasks, repositories.NewMemoryRefreshTokenRepository(), revocations, sessions, personalTokens)
+	taskUseCase := usecases.NewTaskUseCase(h.tasks, h.users, auditRepo)
+
+	authService := infrastructure.NewAuthService(testSecret, h.users, revocations, sessions, personalTokens, precedence)
+
+	h.engine.Use(infrastructure.RequestID(), infrastructure.ErrorHandler())
+	err := router.SetUpRoutes(h.engine, controllers.NewUserController(userUseCase, authService), controllers.NewTaskController(taskUseCase), authService)
+	require.NoError(t, err)
+	return h
+}
+
+// client sends requests with the cookies and headers it was given, the zero client is anonymous
+type client struct {
+	h       *harness
+	ID      string
+	Role    string
+	cookies []*http.Cookie
+	// headers are name, value pairs sent with every request
+	headers []string
+}
+
+// anonymous returns a client without any cookie
+func (h *harness) anonymous() *client {
+	return &client{h: h}
+}
+
+// register creates an account, the first account of a harness is the admin
+func (h *harness) register(username string) {
+	res := h.anonymous().do(http.MethodPost, "/register", map[string]string{"username": username, "password": username + "-password"})
+	require.Equal(h.t, http.StatusOK, res.Code, res.Body.String())
+}
+
+// login signs in a registered account and returns a client holding its auth cookie
+func (h *harness) login(username string) *client {
+	res := h.anonymous().do(http.MethodPost, "/login", map[string]string{"username": username, "password": username + "-password"})
+	require.Equal(h.t, http.StatusOK, res.Code, res.Body.String())
+
+	var body struct {
+		User struct {
+			ID   string `json:"id"`
+			Role string `json:"role"`
+		} `json:"user"`
+	}
+	res.decode(&body)
+	return &client{h: h, ID: body.User.ID, Role: body.User.Role, cookies: res.Result().Cookies()}
+}
+
+// admin registers and logs in the admin, it must be the first account of the harness
+func (h *harness) admin() *client {
+	h.register("admin")
+	c := h.login("admin")
+	require.Equal(h.t, "Admin", c.Role)
+	return c
+}
+
+// user registers and logs in a regular user
+func (h *harness) user(username string) *client {
+	h.register(username)
+	c := h.login(username)
+	require.Equal(h.t, "User", c.Role)
+	return c
+}
+
+// response is a recorded response with decoding helpers
+type response struct {
+	*httptest.ResponseRecorder
+	t *testing.T
+}
+
+// do sends a request, a non nil body is sent as JSON unless it is a string.
+// headers are name, value pairs
+func (c *client) do(method, path string, body any, headers ...string) *response {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		raw, err := json.Marshal(b)
+		require.NoError(c.h.t, err)
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	headers = append(append([]string{}, c.headers...), headers...)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.h.engine.ServeHTTP(rec, req)
+	return &response{ResponseRecorder: rec, t: c.h.t}
+}
+
+// decode reads the JSON body into v
+func (r *response) decode(v any) {
+	require.NoError(r.t, json.Unmarshal(r.Body.Bytes(), v), r.Body.String())
+}
+
+// cookie returns the cookie set by the response, nil when it was not set
+func (r *response) cookie(name string) *http.Cookie {
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// problem reads an application/problem+json body
+func (r *response) problem() infrastructure.Problem {
+	require.Equal(r.t, infrastructure.ProblemContentType, r.Header().Get("Content-Type"), r.Body.String())
+	var p infrastructure.Problem
+	r.decode(&p)
+	require.Equal(r.t, r.Code, p.Status)
+	return p
+}
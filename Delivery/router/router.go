@@ -2,6 +2,7 @@ package router
 
 import (
 	"task_management/Delivery/controllers"
+	domain "task_management/Domain"
 
 	usecases "task_management/usecases"
 
@@ -18,27 +19,58 @@ func SetUpRoutes(
 	router.POST("/register", userController.Register)
 	router.POST("/login", userController.Login)
 	router.POST("/logout", userController.Logout)
-	router.POST("/promote", userController.PromoteUser)
+	router.POST("/auth/refresh", userController.Refresh)
+	router.GET("/validation-rules", controllers.GetValidationRules)
 
-	userRoutes := router.Group("/tasks")
-	userRoutes.Use(authService.AuthWithRole("Admin","User"))
+	// every user manages their own sessions and personal access tokens
+	meRoutes := router.Group("/me")
+	meRoutes.Use(authService.AuthWithRole("Admin","User"))
 	{
-		userRoutes.GET("/", taskController.GetTasks)
-		userRoutes.GET("/:id", taskController.GetTaskByID)
+		meRoutes.GET("/sessions", userController.ListSessions)
+		meRoutes.DELETE("/sessions/:id", userController.RevokeSession)
+		meRoutes.POST("/tokens", userController.CreatePersonalToken)
+		meRoutes.GET("/tokens", userController.ListPersonalTokens)
+		meRoutes.DELETE("/tokens/:id", userController.RevokePersonalToken)
 	}
-	
+
+	// users manage their own tasks, admins can see and modify every task.
+	// personal access tokens need the tasks scopes, every other route refuses them
+	taskRoutes := router.Group("/tasks")
+	taskRoutes.Use(authService.TokenScopes(domain.ScopeTasksRead, domain.ScopeTasksWrite), authService.AuthWithRole("Admin","User"))
+	{
+		taskRoutes.GET("/", taskController.GetTasks)
+		taskRoutes.GET("/trash", taskController.GetTrash)
+		taskRoutes.GET("/:id", taskController.GetTaskByID)
+		taskRoutes.PUT("/:id", taskController.UpdateTaskByID)
+		taskRoutes.PATCH("/:id", taskController.PatchTaskByID)
+		taskRoutes.DELETE("/:id", taskController.DeleteTaskByID)
+		taskRoutes.POST("/", taskController.AddTask)
+		taskRoutes.GET("/:id/transitions", taskController.GetTaskTransitions)
+		taskRoutes.POST("/:id/transitions", taskController.TransitionTask)
+		taskRoutes.POST("/:id/assignees", taskController.AddAssignee)
+		taskRoutes.DELETE("/:id/assignees/:userId", taskController.RemoveAssignee)
+		taskRoutes.POST("/:id/restore", taskController.RestoreTask)
+		taskRoutes.GET("/:id/history", taskController.GetTaskHistory)
+	}
+
+	// permanently removing trashed tasks is for admins only
 	adminTaskRoutes := router.Group("/tasks")
 	adminTaskRoutes.Use(authService.AuthWithRole("Admin"))
 	{
-		adminTaskRoutes.PUT("/:id", taskController.UpdateTaskByID)
-		adminTaskRoutes.DELETE("/:id", taskController.DeleteTaskByID)
-		adminTaskRoutes.POST("/", taskController.AddTask)
+		adminTaskRoutes.DELETE("/:id/purge", taskController.PurgeTask)
 	}
 	
 	adminUserRoutes := router.Group("/admin")
 	adminUserRoutes.Use(authService.AuthWithRole("Admin"))
 	{
-		adminUserRoutes.POST("/promote/", userController.PromoteUser)
+		adminUserRoutes.GET("/users", userController.ListUsers)
+		adminUserRoutes.GET("/users/:id", userController.GetUser)
+		adminUserRoutes.PUT("/users/:id/role", userController.ChangeRole)
+		adminUserRoutes.POST("/users/:id/deactivate", userController.DeactivateUser)
+		adminUserRoutes.POST("/users/:id/reactivate", userController.ReactivateUser)
+		adminUserRoutes.POST("/users/:id/logout", userController.LogoutEverywhere)
+		adminUserRoutes.DELETE("/users/:id", userController.DeleteUser)
+		adminUserRoutes.GET("/audit", taskController.GetAuditTrail)
 	}
 	
 	return nil
@@ -0,0 +1,1047 @@
+package router_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	domain "task_management/Domain"
+	infrastructure "task_management/infrastructure"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RouterTestSuite struct {
+	suite.Suite
+	h *harness
+}
+
+func (suite *RouterTestSuite) SetupTest() {
+	suite.h = newHarness(suite.T())
+}
+
+func TestRouterSuite(t *testing.T) {
+	suite.Run(t, new(RouterTestSuite))
+}
+
+// createTask adds a task as c and returns it
+func (suite *RouterTestSuite) createTask(c *client, title string) domain.Task {
+	res := c.do(http.MethodPost, "/tasks/", map[string]string{"title": title})
+	suite.Require().Equal(http.StatusCreated, res.Code, res.Body.String())
+	var task domain.Task
+	res.decode(&task)
+	return task
+}
+
+// every route behind the auth middleware, with the role it needs
+var protectedRoutes = []struct {
+	method, path string
+	adminOnly    bool
+}{
+	{http.MethodGet, "/me/sessions", false},
+	{http.MethodDelete, "/me/sessions/000000000000000000000000", false},
+	{http.MethodPost, "/me/tokens", false},
+	{http.MethodGet, "/me/tokens", false},
+	{http.MethodDelete, "/me/tokens/000000000000000000000000", false},
+	{http.MethodGet, "/tasks/", false},
+	{http.MethodPost, "/tasks/", false},
+	{http.MethodGet, "/tasks/trash", false},
+	{http.MethodGet, "/tasks/000000000000000000000000", false},
+	{http.MethodPut, "/tasks/000000000000000000000000", false},
+	{http.MethodPatch, "/tasks/000000000000000000000000", false},
+	{http.MethodDelete, "/tasks/000000000000000000000000", false},
+	{http.MethodGet, "/tasks/000000000000000000000000/transitions", false},
+	{http.MethodPost, "/tasks/000000000000000000000000/transitions", false},
+	{http.MethodPost, "/tasks/000000000000000000000000/assignees", false},
+	{http.MethodDelete, "/tasks/000000000000000000000000/assignees/someone", false},
+	{http.MethodPost, "/tasks/000000000000000000000000/restore", false},
+	{http.MethodGet, "/tasks/000000000000000000000000/history", false},
+	{http.MethodDelete, "/tasks/000000000000000000000000/purge", true},
+	{http.MethodGet, "/admin/users", true},
+	{http.MethodGet, "/admin/users/000000000000000000000000", true},
+	{http.MethodPut, "/admin/users/000000000000000000000000/role", true},
+	{http.MethodPost, "/admin/users/000000000000000000000000/deactivate", true},
+	{http.MethodPost, "/admin/users/000000000000000000000000/reactivate", true},
+	{http.MethodPost, "/admin/users/000000000000000000000000/logout", true},
+	{http.MethodDelete, "/admin/users/000000000000000000000000", true},
+	{http.MethodGet, "/admin/audit", true},
+}
+
+func (suite *RouterTestSuite) TestProtectedRoutes() {
+	suite.h.admin()
+	user := suite.h.user("alice")
+	forged := &client{h: suite.h, cookies: []*http.Cookie{{Name: "auth_token", Value: "not-a-token"}}}
+
+	for _, route := range protectedRoutes {
+		name := route.method + " " + route.path
+
+		res := suite.h.anonymous().do(route.method, route.path, nil)
+		suite.Equal(http.StatusUnauthorized, res.Code, name)
+		suite.Equal("unauthorized", res.problem().Code, name)
+
+		res = forged.do(route.method, route.path, nil)
+		suite.Equal(http.StatusUnauthorized, res.Code, name)
+
+		if route.adminOnly {
+			res = user.do(route.method, route.path, nil)
+			suite.Equal(http.StatusForbidden, res.Code, name)
+			suite.Equal("forbidden", res.problem().Code, name)
+		}
+	}
+}
+
+func (suite *RouterTestSuite) TestRegister() {
+	suite.Run("FirstUserIsAdmin", func() {
+		suite.SetupTest()
+
+		res := suite.h.anonymous().do(http.MethodPost, "/register", map[string]string{"username": "admin", "password": "admin-password"})
+
+		suite.Equal(http.StatusOK, res.Code)
+		var user map[string]any
+		res.decode(&user)
+		suite.Equal("admin", user["username"])
+		suite.Equal("Admin", user["role"])
+		suite.NotEmpty(user["id"])
+		suite.Empty(user["password"])
+	})
+
+	suite.Run("LaterUsersAreUsers", func() {
+		suite.SetupTest()
+		suite.h.register("admin")
+
+		res := suite.h.anonymous().do(http.MethodPost, "/register", map[string]string{"username": "alice", "password": "alice-password"})
+
+		suite.Equal(http.StatusOK, res.Code)
+		var user domain.User
+		res.decode(&user)
+		suite.Equal(domain.RoleUser, user.Role)
+	})
+
+	suite.Run("DuplicateUsername", func() {
+		suite.SetupTest()
+		suite.h.register("alice")
+
+		res := suite.h.anonymous().do(http.MethodPost, "/register", map[string]string{"username": "alice", "password": "other-password"})
+
+		suite.Equal(http.StatusConflict, res.Code)
+		suite.Equal("conflict", res.problem().Code)
+	})
+
+	suite.Run("InvalidInput", func() {
+		suite.SetupTest()
+
+		res := suite.h.anonymous().do(http.MethodPost, "/register", map[string]string{"username": "a", "password": "short"})
+
+		suite.Equal(http.StatusBadRequest, res.Code)
+		problem := res.problem()
+		suite.Equal("validation_failed", problem.Code)
+		suite.Len(problem.Errors, 2)
+	})
+}
+
+func (suite *RouterTestSuite) TestLoginAndLogout() {
+	suite.h.register("alice")
+
+	suite.Run("WrongPassword", func() {
+		res := suite.h.anonymous().do(http.MethodPost, "/login", map[string]string{"username": "alice", "password": "wrong-password"})
+
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Empty(res.Result().Cookies())
+	})
+
+	suite.Run("Success", func() {
+		res := suite.h.anonymous().do(http.MethodPost, "/login", map[string]string{"username": "alice", "password": "alice-password"})
+
+		suite.Equal(http.StatusOK, res.Code)
+		suite.Len(res.Result().Cookies(), 2)
+		for _, name := range []string{"auth_token", "refresh_token"} {
+			cookie := res.cookie(name)
+			suite.Require().NotNil(cookie, name)
+			suite.NotEmpty(cookie.Value)
+			suite.True(cookie.HttpOnly)
+		}
+		//the access token expires long before the refresh token
+		suite.Less(res.cookie("auth_token").MaxAge, res.cookie("refresh_token").MaxAge)
+		var body struct {
+			Message string            `json:"message"`
+			User    map[string]string `json:"user"`
+		}
+		res.decode(&body)
+		suite.Equal("login successful", body.Message)
+		suite.Equal("alice", body.User["username"])
+		suite.NotContains(body.User, "password")
+	})
+
+	suite.Run("Logout", func() {
+		res := suite.h.login("alice").do(http.MethodPost, "/logout", nil)
+
+		suite.Equal(http.StatusOK, res.Code)
+		suite.Len(res.Result().Cookies(), 2)
+		for _, name := range []string{"auth_token", "refresh_token"} {
+			cookie := res.cookie(name)
+			suite.Require().NotNil(cookie, name)
+			suite.Empty(cookie.Value)
+			suite.Negative(cookie.MaxAge)
+		}
+	})
+
+	suite.Run("LogoutRevokesAccessToken", func() {
+		alice := suite.h.login("alice")
+		other := suite.h.login("alice")
+		suite.Equal(http.StatusOK, alice.do(http.MethodPost, "/logout", nil).Code)
+
+		//a copy of the cookie kept after the logout is refused
+		res := alice.do(http.MethodGet, "/tasks/", nil)
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("unauthorized: token was revoked", res.problem().Detail)
+		//the other logins are untouched
+		suite.Equal(http.StatusOK, other.do(http.MethodGet, "/tasks/", nil).Code)
+	})
+
+	suite.Run("LogoutRevokesRefreshToken", func() {
+		alice := suite.h.login("alice")
+		suite.Equal(http.StatusOK, alice.do(http.MethodPost, "/logout", nil).Code)
+
+		res := alice.do(http.MethodPost, "/auth/refresh", nil)
+
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("invalid_refresh_token", res.problem().Code)
+	})
+}
+
+func (suite *RouterTestSuite) TestLogoutEverywhere() {
+	admin := suite.h.admin()
+	suite.h.register("alice")
+	first := suite.h.login("alice")
+	second := suite.h.login("alice")
+
+	res := admin.do(http.MethodPost, "/admin/users/"+first.ID+"/logout", nil)
+
+	suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+	for _, c := range []*client{first, second} {
+		suite.Equal(http.StatusUnauthorized, c.do(http.MethodGet, "/tasks/", nil).Code)
+		suite.Equal(http.StatusUnauthorized, c.do(http.MethodPost, "/auth/refresh", nil).Code)
+	}
+	//the admin is still logged in and alice can log in again
+	suite.Equal(http.StatusOK, admin.do(http.MethodGet, "/tasks/", nil).Code)
+	suite.Equal(http.StatusOK, suite.h.login("alice").do(http.MethodGet, "/tasks/", nil).Code)
+
+	res = admin.do(http.MethodGet, "/admin/audit?user="+first.ID, nil)
+	suite.Contains(res.Body.String(), string(domain.AuditLogoutEverywhere))
+	suite.Equal(http.StatusNotFound, admin.do(http.MethodPost, "/admin/users/"+domain.NewID()+"/logout", nil).Code)
+}
+
+func (suite *RouterTestSuite) TestSessions() {
+	suite.h.register("alice")
+	laptop := suite.h.login("alice")
+	res := suite.h.anonymous().do(http.MethodPost, "/login", map[string]string{"username": "alice", "password": "alice-password"}, "User-Agent", "phone-app/1.0")
+	suite.Require().Equal(http.StatusOK, res.Code)
+	phone := &client{h: suite.h, ID: laptop.ID, cookies: res.Result().Cookies()}
+
+	var sessions []domain.Session
+	res = laptop.do(http.MethodGet, "/me/sessions", nil)
+	suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+	res.decode(&sessions)
+	suite.Require().Len(sessions, 2)
+	var phoneSession domain.Session
+	for _, session := range sessions {
+		suite.Equal(laptop.ID, session.UserID)
+		suite.NotEmpty(session.IP)
+		if session.UserAgent == "phone-app/1.0" {
+			phoneSession = session
+			suite.False(session.Current)
+		} else {
+			suite.True(session.Current)
+		}
+	}
+	suite.Require().NotEmpty(phoneSession.ID)
+
+	suite.Run("OtherUsersSessions", func() {
+		bob := suite.h.user("bob")
+
+		res := bob.do(http.MethodDelete, "/me/sessions/"+phoneSession.ID, nil)
+
+		suite.Equal(http.StatusNotFound, res.Code)
+		res = bob.do(http.MethodGet, "/me/sessions", nil)
+		res.decode(&sessions)
+		suite.Len(sessions, 1)
+	})
+
+	suite.Run("Revoke", func() {
+		res := laptop.do(http.MethodDelete, "/me/sessions/"+phoneSession.ID, nil)
+
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+		//both tokens of the phone stop working, the laptop keeps going
+		res = phone.do(http.MethodGet, "/tasks/", nil)
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("unauthorized: session was revoked", res.problem().Detail)
+		suite.Equal(http.StatusUnauthorized, phone.do(http.MethodPost, "/auth/refresh", nil).Code)
+		suite.Equal(http.StatusOK, laptop.do(http.MethodGet, "/tasks/", nil).Code)
+
+		res = laptop.do(http.MethodGet, "/me/sessions", nil)
+		res.decode(&sessions)
+		suite.Len(sessions, 1)
+		suite.Equal(http.StatusNotFound, laptop.do(http.MethodDelete, "/me/sessions/"+phoneSession.ID, nil).Code)
+		suite.Equal(http.StatusBadRequest, laptop.do(http.MethodDelete, "/me/sessions/not-an-id", nil).Code)
+	})
+
+	suite.Run("RefreshKeepsSession", func() {
+		res := laptop.do(http.MethodPost, "/auth/refresh", nil)
+		suite.Require().Equal(http.StatusOK, res.Code)
+		refreshed := &client{h: suite.h, cookies: res.Result().Cookies()}
+
+		res = refreshed.do(http.MethodGet, "/me/sessions", nil)
+		res.decode(&sessions)
+		suite.Require().Len(sessions, 1)
+		suite.True(sessions[0].Current)
+	})
+
+	suite.Run("LogoutEndsSession", func() {
+		suite.Equal(http.StatusOK, laptop.do(http.MethodPost, "/logout", nil).Code)
+
+		other := suite.h.login("alice")
+		res := other.do(http.MethodGet, "/me/sessions", nil)
+		res.decode(&sessions)
+		suite.Len(sessions, 1)
+		suite.True(sessions[0].Current)
+	})
+}
+
+func (suite *RouterTestSuite) TestRefresh() {
+	suite.h.register("alice")
+
+	suite.Run("Rotates", func() {
+		alice := suite.h.login("alice")
+
+		res := alice.do(http.MethodPost, "/auth/refresh", nil)
+
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		suite.Require().NotNil(res.cookie("refresh_token"))
+		suite.NotEqual(alice.cookies, res.Result().Cookies())
+		//the new tokens work
+		next := &client{h: suite.h, cookies: res.Result().Cookies()}
+		suite.Equal(http.StatusOK, next.do(http.MethodGet, "/tasks/", nil).Code)
+		suite.Equal(http.StatusOK, next.do(http.MethodPost, "/auth/refresh", nil).Code)
+	})
+
+	suite.Run("ReuseRevokesFamily", func() {
+		alice := suite.h.login("alice")
+		res := alice.do(http.MethodPost, "/auth/refresh", nil)
+		suite.Require().Equal(http.StatusOK, res.Code)
+		next := &client{h: suite.h, cookies: res.Result().Cookies()}
+
+		//the first refresh token is replayed
+		res = alice.do(http.MethodPost, "/auth/refresh", nil)
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("refresh_token_reused", res.problem().Code)
+		suite.Negative(res.cookie("refresh_token").MaxAge)
+
+		//the rotated token is revoked with the rest of the family
+		res = next.do(http.MethodPost, "/auth/refresh", nil)
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("invalid_refresh_token", res.problem().Code)
+	})
+
+	suite.Run("OtherFamiliesSurvive", func() {
+		first := suite.h.login("alice")
+		second := suite.h.login("alice")
+		suite.Equal(http.StatusOK, first.do(http.MethodPost, "/auth/refresh", nil).Code)
+		suite.Equal(http.StatusUnauthorized, first.do(http.MethodPost, "/auth/refresh", nil).Code)
+
+		suite.Equal(http.StatusOK, second.do(http.MethodPost, "/auth/refresh", nil).Code)
+	})
+
+	suite.Run("MissingToken", func() {
+		res := suite.h.anonymous().do(http.MethodPost, "/auth/refresh", nil)
+
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("invalid_refresh_token", res.problem().Code)
+	})
+}
+
+// tokenBody is the tokens part of a login or refresh asked to include them
+type tokenBody struct {
+	Tokens struct {
+		TokenType    string `json:"tokenType"`
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+	} `json:"tokens"`
+}
+
+func (suite *RouterTestSuite) TestBearerTokens() {
+	suite.h.register("alice")
+	credentials := map[string]any{"username": "alice", "password": "alice-password", "includeTokens": true}
+	loginTokens := func() tokenBody {
+		res := suite.h.anonymous().do(http.MethodPost, "/login", credentials)
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		var body tokenBody
+		res.decode(&body)
+		suite.Require().NotEmpty(body.Tokens.AccessToken)
+		return body
+	}
+
+	suite.Run("LoginReturnsTokensOnRequest", func() {
+		body := loginTokens()
+		suite.Equal("Bearer", body.Tokens.TokenType)
+		suite.NotEmpty(body.Tokens.RefreshToken)
+
+		res := suite.h.anonymous().do(http.MethodPost, "/login", map[string]string{"username": "alice", "password": "alice-password"})
+		suite.Require().Equal(http.StatusOK, res.Code)
+		suite.NotContains(res.Body.String(), "accessToken")
+	})
+
+	suite.Run("HeaderAuthenticates", func() {
+		body := loginTokens()
+
+		res := suite.h.anonymous().do(http.MethodGet, "/tasks/", nil, "Authorization", "Bearer "+body.Tokens.AccessToken)
+
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	})
+
+	suite.Run("MalformedHeader", func() {
+		body := loginTokens()
+
+		for _, header := range []string{"Basic " + body.Tokens.AccessToken, "Bearer", body.Tokens.AccessToken} {
+			res := suite.h.anonymous().do(http.MethodGet, "/tasks/", nil, "Authorization", header)
+			suite.Equal(http.StatusUnauthorized, res.Code, header)
+			suite.Equal("unauthorized: authorization header must be Bearer <token>", res.problem().Detail)
+		}
+	})
+
+	suite.Run("HeaderWinsByDefault", func() {
+		alice := suite.h.login("alice")
+
+		res := alice.do(http.MethodGet, "/tasks/", nil, "Authorization", "Bearer not-a-token")
+
+		suite.Equal(http.StatusUnauthorized, res.Code)
+	})
+
+	suite.Run("CookieFirst", func() {
+		h := newHarnessWithPrecedence(suite.T(), infrastructure.CookieFirst)
+		h.register("alice")
+		alice := h.login("alice")
+
+		res := alice.do(http.MethodGet, "/tasks/", nil, "Authorization", "Bearer not-a-token")
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+		//without a cookie the header is still read
+		res = h.anonymous().do(http.MethodGet, "/tasks/", nil, "Authorization", "Bearer not-a-token")
+		suite.Equal(http.StatusUnauthorized, res.Code)
+	})
+
+	suite.Run("RefreshWithBody", func() {
+		body := loginTokens()
+
+		res := suite.h.anonymous().do(http.MethodPost, "/auth/refresh", map[string]any{"refreshToken": body.Tokens.RefreshToken, "includeTokens": true})
+
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		var next tokenBody
+		res.decode(&next)
+		suite.NotEqual(body.Tokens.RefreshToken, next.Tokens.RefreshToken)
+		suite.Equal(http.StatusOK, suite.h.anonymous().do(http.MethodGet, "/tasks/", nil, "Authorization", "Bearer "+next.Tokens.AccessToken).Code)
+		//the body token was used up like a cookie would be
+		res = suite.h.anonymous().do(http.MethodPost, "/auth/refresh", map[string]string{"refreshToken": body.Tokens.RefreshToken})
+		suite.Equal("refresh_token_reused", res.problem().Code)
+	})
+
+	suite.Run("LogoutWithHeader", func() {
+		body := loginTokens()
+		header := "Bearer " + body.Tokens.AccessToken
+
+		res := suite.h.anonymous().do(http.MethodPost, "/logout", map[string]string{"refreshToken": body.Tokens.RefreshToken}, "Authorization", header)
+
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		res = suite.h.anonymous().do(http.MethodGet, "/tasks/", nil, "Authorization", header)
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		res = suite.h.anonymous().do(http.MethodPost, "/auth/refresh", map[string]string{"refreshToken": body.Tokens.RefreshToken})
+		suite.Equal("invalid_refresh_token", res.problem().Code)
+	})
+
+	suite.Run("LogoutCookieFirst", func() {
+		h := newHarnessWithPrecedence(suite.T(), infrastructure.CookieFirst)
+		h.register("alice")
+		alice := h.login("alice")
+		res := h.anonymous().do(http.MethodPost, "/login", credentials)
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		var other tokenBody
+		res.decode(&other)
+		header := "Bearer " + other.Tokens.AccessToken
+
+		res = alice.do(http.MethodPost, "/logout", nil, "Authorization", header)
+
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		//the cookie is the token the request authenticated with, the header token stays valid
+		suite.Equal(http.StatusUnauthorized, alice.do(http.MethodGet, "/tasks/", nil).Code)
+		suite.Equal(http.StatusOK, h.anonymous().do(http.MethodGet, "/tasks/", nil, "Authorization", header).Code)
+	})
+}
+
+// createPersonalToken creates a personal access token as c and returns a client sending it
+func (suite *RouterTestSuite) createPersonalToken(c *client, scopes ...domain.Scope) (*client, string) {
+	res := c.do(http.MethodPost, "/me/tokens", map[string]any{"name": "automation", "scopes": scopes})
+	suite.Require().Equal(http.StatusCreated, res.Code, res.Body.String())
+	var body struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	res.decode(&body)
+	return &client{h: suite.h, ID: c.ID, headers: []string{"Authorization", "Bearer " + body.Token}}, body.ID
+}
+
+func (suite *RouterTestSuite) TestPersonalTokens() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+
+	suite.Run("Create", func() {
+		res := alice.do(http.MethodPost, "/me/tokens", map[string]any{"name": "deploy job", "scopes": []string{"tasks:read"}})
+
+		suite.Require().Equal(http.StatusCreated, res.Code, res.Body.String())
+		var body map[string]any
+		res.decode(&body)
+		suite.Equal("deploy job", body["name"])
+		suite.Equal([]any{"tasks:read"}, body["scopes"])
+		suite.NotEmpty(body["expiresAt"])
+		suite.True(strings.HasPrefix(body["token"].(string), domain.PersonalTokenPrefix))
+		suite.NotContains(body, "tokenHash")
+	})
+
+	suite.Run("InvalidInput", func() {
+		res := alice.do(http.MethodPost, "/me/tokens", map[string]any{"name": "ci", "scopes": []string{"users:write"}})
+
+		suite.Equal(http.StatusBadRequest, res.Code)
+		suite.Equal("validation_failed", res.problem().Code)
+	})
+
+	suite.Run("ScopesRestrictRoutes", func() {
+		reader, _ := suite.createPersonalToken(alice, domain.ScopeTasksRead)
+		writer, _ := suite.createPersonalToken(alice, domain.ScopeTasksWrite)
+
+		suite.Equal(http.StatusOK, reader.do(http.MethodGet, "/tasks/", nil).Code)
+		res := reader.do(http.MethodPost, "/tasks/", map[string]string{"title": "from a script"})
+		suite.Equal(http.StatusForbidden, res.Code)
+		suite.Equal("insufficient_scope", res.problem().Code)
+		suite.Equal("the personal access token lacks the tasks:write scope", res.problem().Detail)
+
+		task := suite.createTask(writer, "from a script")
+		suite.Equal(alice.ID, task.OwnerID)
+		suite.Equal(http.StatusForbidden, writer.do(http.MethodGet, "/tasks/", nil).Code)
+	})
+
+	suite.Run("OnlyTaskRoutes", func() {
+		token, _ := suite.createPersonalToken(admin, domain.ScopeTasksRead, domain.ScopeTasksWrite)
+
+		for _, route := range protectedRoutes {
+			if strings.HasPrefix(route.path, "/tasks/") && !strings.HasSuffix(route.path, "/purge") {
+				continue
+			}
+			name := route.method + " " + route.path
+			res := token.do(route.method, route.path, nil)
+			suite.Equal(http.StatusForbidden, res.Code, name)
+			suite.Equal("insufficient_scope", res.problem().Code, name)
+		}
+	})
+
+	suite.Run("OnlyInTheHeader", func() {
+		token, _ := suite.createPersonalToken(alice, domain.ScopeTasksRead)
+		_, value, _ := strings.Cut(token.headers[1], " ")
+		cookie := &client{h: suite.h, cookies: []*http.Cookie{{Name: "auth_token", Value: value}}}
+
+		suite.Equal(http.StatusUnauthorized, cookie.do(http.MethodGet, "/tasks/", nil).Code)
+	})
+
+	suite.Run("ListAndRevoke", func() {
+		bob := suite.h.user("bob")
+		token, id := suite.createPersonalToken(bob, domain.ScopeTasksRead)
+		suite.Equal(http.StatusOK, token.do(http.MethodGet, "/tasks/", nil).Code)
+
+		var tokens []map[string]any
+		res := bob.do(http.MethodGet, "/me/tokens", nil)
+		suite.Require().Equal(http.StatusOK, res.Code)
+		res.decode(&tokens)
+		suite.Require().Len(tokens, 1)
+		suite.Equal(id, tokens[0]["id"])
+		suite.NotEmpty(tokens[0]["lastUsedAt"])
+		suite.NotContains(tokens[0], "token")
+
+		//other users cannot see or revoke it
+		suite.Equal(http.StatusNotFound, alice.do(http.MethodDelete, "/me/tokens/"+id, nil).Code)
+
+		res = bob.do(http.MethodDelete, "/me/tokens/"+id, nil)
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+		res = token.do(http.MethodGet, "/tasks/", nil)
+		suite.Equal(http.StatusUnauthorized, res.Code)
+		suite.Equal("invalid_personal_token", res.problem().Code)
+		res = bob.do(http.MethodGet, "/me/tokens", nil)
+		suite.JSONEq("[]", res.Body.String())
+	})
+
+	suite.Run("LogoutEverywhere", func() {
+		token, _ := suite.createPersonalToken(alice, domain.ScopeTasksRead)
+
+		suite.Equal(http.StatusOK, admin.do(http.MethodPost, "/admin/users/"+alice.ID+"/logout", nil).Code)
+
+		suite.Equal(http.StatusUnauthorized, token.do(http.MethodGet, "/tasks/", nil).Code)
+	})
+
+	suite.Run("DeactivatedUser", func() {
+		carol := suite.h.user("carol")
+		token, _ := suite.createPersonalToken(carol, domain.ScopeTasksRead)
+
+		suite.Equal(http.StatusOK, admin.do(http.MethodPost, "/admin/users/"+carol.ID+"/deactivate", nil).Code)
+
+		res := token.do(http.MethodGet, "/tasks/", nil)
+		suite.Equal(http.StatusForbidden, res.Code)
+		suite.Equal("account_deactivated", res.problem().Code)
+	})
+}
+
+func (suite *RouterTestSuite) TestValidationRules() {
+	res := suite.h.anonymous().do(http.MethodGet, "/validation-rules", nil)
+
+	suite.Equal(http.StatusOK, res.Code)
+	var rules map[string][]domain.FieldRule
+	res.decode(&rules)
+	suite.Contains(rules, "task")
+	suite.Contains(rules, "user")
+}
+
+func (suite *RouterTestSuite) TestChangeRole() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+	rolePath := func(id string) string { return "/admin/users/" + id + "/role" }
+
+	suite.Run("NotPublic", func() {
+		for _, path := range []string{"/promote", "/admin/promote/"} {
+			res := suite.h.anonymous().do(http.MethodPost, path, map[string]string{"userId": alice.ID})
+
+			suite.Equal(http.StatusNotFound, res.Code, path)
+		}
+	})
+
+	suite.Run("Grant", func() {
+		res := admin.do(http.MethodPut, rolePath(alice.ID), map[string]string{"role": "Admin"})
+
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+		var user domain.User
+		res.decode(&user)
+		suite.Equal(domain.RoleAdmin, user.Role)
+		suite.NotContains(res.Body.String(), "password")
+		//the role is read on every request, the cookie issued before the change already has it
+		suite.Equal(http.StatusOK, alice.do(http.MethodGet, "/admin/audit", nil).Code)
+	})
+
+	suite.Run("Revoke", func() {
+		promoted := suite.h.login("alice")
+		suite.Require().Equal("Admin", promoted.Role)
+
+		res := admin.do(http.MethodPut, rolePath(alice.ID), map[string]string{"role": "User"})
+
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+		//the cookie issued while alice was an admin no longer grants admin routes
+		suite.Equal(http.StatusForbidden, promoted.do(http.MethodGet, "/admin/audit", nil).Code)
+		suite.Equal(http.StatusForbidden, suite.h.login("alice").do(http.MethodGet, "/admin/audit", nil).Code)
+	})
+
+	suite.Run("LastAdmin", func() {
+		res := admin.do(http.MethodPut, rolePath(admin.ID), map[string]string{"role": "User"})
+
+		suite.Equal(http.StatusConflict, res.Code)
+		suite.Equal("last_admin", res.problem().Code)
+	})
+
+	suite.Run("InvalidRole", func() {
+		res := admin.do(http.MethodPut, rolePath(alice.ID), map[string]string{"role": "Owner"})
+
+		suite.Equal(http.StatusBadRequest, res.Code)
+		suite.Equal("role", res.problem().Errors[0].Field)
+	})
+
+	suite.Run("UnknownUser", func() {
+		res := admin.do(http.MethodPut, rolePath(domain.NewID()), map[string]string{"role": "Admin"})
+
+		suite.Equal(http.StatusNotFound, res.Code)
+	})
+
+	suite.Run("InvalidID", func() {
+		res := admin.do(http.MethodPut, rolePath("not-an-id"), map[string]string{"role": "Admin"})
+
+		suite.Equal(http.StatusBadRequest, res.Code)
+	})
+
+	suite.Run("Audited", func() {
+		res := admin.do(http.MethodGet, "/admin/audit?user="+alice.ID, nil)
+
+		suite.Equal(http.StatusOK, res.Code)
+		var page domain.AuditPage
+		res.decode(&page)
+		suite.Require().Equal(int64(2), page.Total)
+		suite.Equal(domain.AuditRoleChange, page.Records[0].Action)
+		suite.Equal(admin.ID, page.Records[0].ActorID)
+		suite.Equal([]domain.FieldChange{{Field: "role", From: "Admin", To: "User"}}, page.Records[0].Changes)
+	})
+}
+
+func (suite *RouterTestSuite) TestUserManagement() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+	suite.h.user("alina")
+	suite.h.user("bob")
+
+	suite.Run("List", func() {
+		res := admin.do(http.MethodGet, "/admin/users?search=ALI&limit=1", nil)
+
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+		var page domain.UserPage
+		res.decode(&page)
+		suite.Equal(int64(2), page.Total)
+		suite.Require().Len(page.Users, 1)
+		suite.Equal(alice.ID, page.Users[0].ID)
+		suite.NotEmpty(page.NextCursor)
+		suite.NotContains(res.Body.String(), "password")
+
+		res = admin.do(http.MethodGet, "/admin/users?role=Admin", nil)
+		res.decode(&page)
+		suite.Equal(int64(1), page.Total)
+
+		suite.Equal(http.StatusBadRequest, admin.do(http.MethodGet, "/admin/users?limit=many", nil).Code)
+		suite.Equal(http.StatusBadRequest, admin.do(http.MethodGet, "/admin/users?role=Owner", nil).Code)
+	})
+
+	suite.Run("Get", func() {
+		res := admin.do(http.MethodGet, "/admin/users/"+alice.ID, nil)
+
+		suite.Equal(http.StatusOK, res.Code)
+		var user domain.User
+		res.decode(&user)
+		suite.Equal("alice", user.Username)
+		suite.True(user.IsActive())
+
+		suite.Equal(http.StatusNotFound, admin.do(http.MethodGet, "/admin/users/"+domain.NewID(), nil).Code)
+		suite.Equal(http.StatusBadRequest, admin.do(http.MethodGet, "/admin/users/not-an-id", nil).Code)
+	})
+
+	suite.Run("Deactivate", func() {
+		res := admin.do(http.MethodPost, "/admin/users/"+alice.ID+"/deactivate", nil)
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+
+		//the token alice already holds stops working
+		res = alice.do(http.MethodGet, "/tasks/", nil)
+		suite.Equal(http.StatusForbidden, res.Code)
+		suite.Equal("account_deactivated", res.problem().Code)
+
+		res = suite.h.anonymous().do(http.MethodPost, "/login", map[string]string{"username": "alice", "password": "alice-password"})
+		suite.Equal(http.StatusForbidden, res.Code)
+		suite.Equal("account_deactivated", res.problem().Code)
+
+		res = admin.do(http.MethodPost, "/admin/users/"+alice.ID+"/reactivate", nil)
+		suite.Equal(http.StatusOK, res.Code)
+		suite.Equal(http.StatusOK, alice.do(http.MethodGet, "/tasks/", nil).Code)
+	})
+
+	suite.Run("LastAdmin", func() {
+		res := admin.do(http.MethodPost, "/admin/users/"+admin.ID+"/deactivate", nil)
+		suite.Equal(http.StatusConflict, res.Code)
+		suite.Equal("last_admin", res.problem().Code)
+
+		res = admin.do(http.MethodDelete, "/admin/users/"+admin.ID+"?tasks=trash", nil)
+		suite.Equal(http.StatusConflict, res.Code)
+	})
+
+	suite.Run("Audited", func() {
+		res := admin.do(http.MethodGet, "/admin/audit?user="+alice.ID, nil)
+
+		var page domain.AuditPage
+		res.decode(&page)
+		suite.Require().Equal(int64(2), page.Total)
+		suite.Equal(domain.AuditReactivate, page.Records[0].Action)
+		suite.Equal(domain.AuditDeactivate, page.Records[1].Action)
+	})
+}
+
+func (suite *RouterTestSuite) TestDeleteUser() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+	bob := suite.h.user("bob")
+
+	suite.Run("TaskPolicyRequired", func() {
+		res := admin.do(http.MethodDelete, "/admin/users/"+alice.ID, nil)
+
+		suite.Equal(http.StatusBadRequest, res.Code)
+		suite.Equal("tasks", res.problem().Errors[0].Field)
+	})
+
+	suite.Run("Transfer", func() {
+		task := suite.createTask(alice, "Handed over")
+		shared := suite.createTask(bob, "Shared")
+		res := bob.do(http.MethodPost, "/tasks/"+shared.ID+"/assignees", map[string]string{"userId": alice.ID})
+		suite.Require().Equal(http.StatusOK, res.Code, res.Body.String())
+
+		res = admin.do(http.MethodDelete, "/admin/users/"+alice.ID+"?tasks=transfer&to="+bob.ID, nil)
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+
+		var got domain.Task
+		res = bob.do(http.MethodGet, "/tasks/"+task.ID, nil)
+		suite.Require().Equal(http.StatusOK, res.Code)
+		res.decode(&got)
+		suite.Equal(bob.ID, got.OwnerID)
+		bob.do(http.MethodGet, "/tasks/"+shared.ID, nil).decode(&got)
+		suite.Empty(got.Assignees)
+
+		//the deleted user is gone and so is their token
+		suite.Equal(http.StatusNotFound, admin.do(http.MethodGet, "/admin/users/"+alice.ID, nil).Code)
+		suite.Equal(http.StatusUnauthorized, alice.do(http.MethodGet, "/tasks/", nil).Code)
+	})
+
+	suite.Run("Trash", func() {
+		task := suite.createTask(bob, "Trashed with its owner")
+
+		res := admin.do(http.MethodDelete, "/admin/users/"+bob.ID+"?tasks=trash", nil)
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+
+		res = admin.do(http.MethodGet, "/tasks/trash", nil)
+		var page domain.TaskPage
+		res.decode(&page)
+		suite.Contains(taskIDs(page.Tasks), task.ID)
+	})
+
+	suite.Run("UnknownTarget", func() {
+		carol := suite.h.user("carol")
+
+		res := admin.do(http.MethodDelete, "/admin/users/"+carol.ID+"?tasks=transfer&to="+domain.NewID(), nil)
+
+		suite.Equal(http.StatusBadRequest, res.Code)
+		suite.Equal("to", res.problem().Errors[0].Field)
+	})
+}
+
+// taskIDs lists the ids of the tasks in order
+func taskIDs(tasks []domain.Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func (suite *RouterTestSuite) TestTaskLifecycle() {
+	suite.h.admin()
+	alice := suite.h.user("alice")
+
+	//create
+	res := alice.do(http.MethodPost, "/tasks/", map[string]string{"title": "Write docs", "description": "the api"})
+	suite.Require().Equal(http.StatusCreated, res.Code, res.Body.String())
+	var task domain.Task
+	res.decode(&task)
+	suite.True(domain.IsValidID(task.ID))
+	suite.Equal(alice.ID, task.OwnerID)
+	suite.Equal(domain.StatusNotStarted, task.Status)
+	suite.Equal(int64(1), task.Version)
+
+	//read
+	res = alice.do(http.MethodGet, "/tasks/"+task.ID, nil)
+	suite.Equal(http.StatusOK, res.Code)
+	suite.Equal(`"1"`, res.Header().Get("ETag"))
+
+	res = alice.do(http.MethodGet, "/tasks/", nil)
+	suite.Equal(http.StatusOK, res.Code)
+	var page domain.TaskPage
+	res.decode(&page)
+	suite.Equal(int64(1), page.Total)
+
+	//replace, If-Match is required and must be current
+	update := map[string]string{"title": "Write more docs"}
+	res = alice.do(http.MethodPut, "/tasks/"+task.ID, update)
+	suite.Equal(http.StatusPreconditionRequired, res.Code)
+	suite.Equal("precondition_required", res.problem().Code)
+	res = alice.do(http.MethodPut, "/tasks/"+task.ID, update, "If-Match", `"7"`)
+	suite.Equal(http.StatusPreconditionFailed, res.Code)
+	suite.Equal("version_mismatch", res.problem().Code)
+	res = alice.do(http.MethodPut, "/tasks/"+task.ID, update, "If-Match", `"1"`)
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	suite.Equal(`"2"`, res.Header().Get("ETag"))
+
+	//patch
+	res = alice.do(http.MethodPatch, "/tasks/"+task.ID, `{"description":"patched"}`, "If-Match", `"2"`, "Content-Type", "application/merge-patch+json")
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	res.decode(&task)
+	suite.Equal("patched", task.Description)
+	res = alice.do(http.MethodPatch, "/tasks/"+task.ID, `description=x`, "If-Match", `"3"`, "Content-Type", "text/plain")
+	suite.Equal(http.StatusUnsupportedMediaType, res.Code)
+
+	//transitions
+	for _, to := range []string{"in-progress", "completed"} {
+		res = alice.do(http.MethodPost, "/tasks/"+task.ID+"/transitions", map[string]string{"to": to})
+		suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	}
+	res = alice.do(http.MethodPost, "/tasks/"+task.ID+"/transitions", map[string]string{"to": "not-started"})
+	suite.Equal(http.StatusConflict, res.Code)
+	res = alice.do(http.MethodGet, "/tasks/"+task.ID+"/transitions", nil)
+	suite.Equal(http.StatusOK, res.Code)
+	var transitions []domain.StatusTransition
+	res.decode(&transitions)
+	suite.Len(transitions, 3)
+
+	//history
+	res = alice.do(http.MethodGet, "/tasks/"+task.ID+"/history", nil)
+	suite.Equal(http.StatusOK, res.Code)
+	var history domain.AuditPage
+	res.decode(&history)
+	suite.Equal(int64(5), history.Total)
+	suite.Equal(domain.AuditTransition, history.Records[0].Action)
+}
+
+func (suite *RouterTestSuite) TestStatusUpdate() {
+	suite.h.admin()
+	alice := suite.h.user("alice")
+	task := suite.createTask(alice, "Write docs")
+
+	//a status and a field change are one write, the version moves by one
+	res := alice.do(http.MethodPatch, "/tasks/"+task.ID, `{"title":"Write more docs","status":"in-progress"}`, "If-Match", `"1"`, "Content-Type", "application/merge-patch+json")
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	suite.Equal(`"2"`, res.Header().Get("ETag"))
+
+	res = alice.do(http.MethodPut, "/tasks/"+task.ID, map[string]string{"title": "Docs", "status": "completed"}, "If-Match", `"2"`)
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	suite.Equal(`"3"`, res.Header().Get("ETag"))
+
+	//a move outside the transition graph changes nothing
+	res = alice.do(http.MethodPut, "/tasks/"+task.ID, map[string]string{"title": "Other", "status": "not-started"}, "If-Match", `"3"`)
+	suite.Equal(http.StatusConflict, res.Code)
+	res = alice.do(http.MethodGet, "/tasks/"+task.ID, nil)
+	res.decode(&task)
+	suite.Equal("Docs", task.Title)
+	suite.Equal(`"3"`, res.Header().Get("ETag"))
+
+	res = alice.do(http.MethodGet, "/tasks/"+task.ID+"/transitions", nil)
+	var transitions []domain.StatusTransition
+	res.decode(&transitions)
+	suite.Require().Len(transitions, 3)
+	suite.Equal(domain.StatusCompleted, transitions[2].To)
+
+	//each update is a single audit record holding the status change
+	res = alice.do(http.MethodGet, "/tasks/"+task.ID+"/history", nil)
+	var history domain.AuditPage
+	res.decode(&history)
+	suite.Require().Equal(int64(3), history.Total)
+	suite.Equal(domain.AuditUpdate, history.Records[0].Action)
+	suite.Contains(history.Records[0].Changes, domain.FieldChange{Field: "status", From: "in-progress", To: "completed"})
+}
+
+func (suite *RouterTestSuite) TestAssignees() {
+	suite.h.admin()
+	alice := suite.h.user("alice")
+	bob := suite.h.user("bob")
+	task := suite.createTask(alice, "Shared work")
+
+	//bob cannot see the task before he is assigned
+	suite.Equal(http.StatusNotFound, bob.do(http.MethodGet, "/tasks/"+task.ID, nil).Code)
+
+	res := alice.do(http.MethodPost, "/tasks/"+task.ID+"/assignees", map[string]string{"userId": bob.ID})
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	res.decode(&task)
+	suite.Equal([]string{bob.ID}, task.Assignees)
+
+	res = alice.do(http.MethodPost, "/tasks/"+task.ID+"/assignees", map[string]string{})
+	suite.Equal(http.StatusBadRequest, res.Code)
+	suite.Equal("userId", res.problem().Errors[0].Field)
+
+	suite.Equal(http.StatusOK, bob.do(http.MethodGet, "/tasks/"+task.ID, nil).Code)
+	res = bob.do(http.MethodGet, "/tasks/?assignee=me", nil)
+	var page domain.TaskPage
+	res.decode(&page)
+	suite.Equal(int64(1), page.Total)
+
+	//assignees can see and update a task but only the owner deletes it
+	suite.Equal(http.StatusForbidden, bob.do(http.MethodDelete, "/tasks/"+task.ID, nil, "If-Match", `"2"`).Code)
+
+	res = alice.do(http.MethodDelete, "/tasks/"+task.ID+"/assignees/"+bob.ID, nil)
+	suite.Equal(http.StatusOK, res.Code)
+	suite.Equal(http.StatusNotFound, bob.do(http.MethodGet, "/tasks/"+task.ID, nil).Code)
+}
+
+func (suite *RouterTestSuite) TestTrash() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+	task := suite.createTask(alice, "Old work")
+
+	res := alice.do(http.MethodDelete, "/tasks/"+task.ID, nil, "If-Match", `"1"`)
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	suite.Equal(http.StatusNotFound, alice.do(http.MethodGet, "/tasks/"+task.ID, nil).Code)
+
+	res = alice.do(http.MethodGet, "/tasks/trash", nil)
+	suite.Equal(http.StatusOK, res.Code)
+	var trash domain.TaskPage
+	res.decode(&trash)
+	suite.Equal(int64(1), trash.Total)
+
+	res = alice.do(http.MethodPost, "/tasks/"+task.ID+"/restore", nil)
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	suite.Equal(http.StatusOK, alice.do(http.MethodGet, "/tasks/"+task.ID, nil).Code)
+	suite.Equal(http.StatusConflict, alice.do(http.MethodPost, "/tasks/"+task.ID+"/restore", nil).Code)
+
+	//only trashed tasks are purged
+	suite.Equal(http.StatusConflict, admin.do(http.MethodDelete, "/tasks/"+task.ID+"/purge", nil).Code)
+	suite.Equal(http.StatusOK, alice.do(http.MethodDelete, "/tasks/"+task.ID, nil, "If-Match", `"3"`).Code)
+	res = admin.do(http.MethodDelete, "/tasks/"+task.ID+"/purge", nil)
+	suite.Equal(http.StatusOK, res.Code, res.Body.String())
+	suite.Equal(http.StatusNotFound, admin.do(http.MethodPost, "/tasks/"+task.ID+"/restore", nil).Code)
+}
+
+func (suite *RouterTestSuite) TestVisibility() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+	bob := suite.h.user("bob")
+	suite.createTask(alice, "Alice's")
+	suite.createTask(bob, "Bob's")
+
+	var page domain.TaskPage
+	alice.do(http.MethodGet, "/tasks/", nil).decode(&page)
+	suite.Equal(int64(1), page.Total)
+	suite.Equal("Alice's", page.Tasks[0].Title)
+
+	admin.do(http.MethodGet, "/tasks/", nil).decode(&page)
+	suite.Equal(int64(2), page.Total)
+}
+
+func (suite *RouterTestSuite) TestBadRequests() {
+	suite.h.admin()
+	alice := suite.h.user("alice")
+
+	cases := []struct {
+		name, method, path string
+		body               any
+		status             int
+		code               string
+	}{
+		{"InvalidTaskID", http.MethodGet, "/tasks/not-an-id", nil, http.StatusBadRequest, "validation_failed"},
+		{"UnknownTask", http.MethodGet, "/tasks/" + domain.NewID(), nil, http.StatusNotFound, "not_found"},
+		{"MissingTitle", http.MethodPost, "/tasks/", map[string]string{"description": "no title"}, http.StatusBadRequest, "validation_failed"},
+		{"BrokenJSON", http.MethodPost, "/tasks/", "{", http.StatusBadRequest, "validation_failed"},
+		{"BadOrder", http.MethodGet, "/tasks/?order=sideways", nil, http.StatusBadRequest, "validation_failed"},
+		{"BadCursor", http.MethodGet, "/tasks/?cursor=nope", nil, http.StatusBadRequest, "validation_failed"},
+	}
+	for _, tc := range cases {
+		suite.Run(tc.name, func() {
+			res := alice.do(tc.method, tc.path, tc.body)
+
+			suite.Equal(tc.status, res.Code, res.Body.String())
+			problem := res.problem()
+			suite.Equal(tc.code, problem.Code)
+			suite.NotEmpty(res.Header().Get("X-Request-ID"))
+		})
+	}
+}
+
+func (suite *RouterTestSuite) TestAuditTrail() {
+	admin := suite.h.admin()
+	alice := suite.h.user("alice")
+	task := suite.createTask(alice, "Audited")
+
+	res := admin.do(http.MethodGet, "/admin/audit?task="+task.ID, nil)
+
+	suite.Equal(http.StatusOK, res.Code)
+	var page domain.AuditPage
+	res.decode(&page)
+	suite.Equal(int64(1), page.Total)
+	suite.Equal(domain.AuditCreate, page.Records[0].Action)
+	suite.Equal(alice.ID, page.Records[0].ActorID)
+}
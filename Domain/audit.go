@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuditAction is what was done to a task or a user
+type AuditAction string
+
+const (
+	AuditCreate     AuditAction = "create"
+	AuditUpdate     AuditAction = "update"
+	AuditTransition AuditAction = "transition"
+	AuditAssign     AuditAction = "assign"
+	AuditUnassign   AuditAction = "unassign"
+	AuditDelete     AuditAction = "delete"
+	AuditRestore    AuditAction = "restore"
+	AuditPurge      AuditAction = "purge"
+	// AuditRoleChange is a user getting another role, the record has a UserID instead of a TaskID
+	AuditRoleChange AuditAction = "role_change"
+	AuditDeactivate AuditAction = "deactivate"
+	AuditReactivate AuditAction = "reactivate"
+	// AuditLogoutEverywhere is an admin revoking every token of a user
+	AuditLogoutEverywhere AuditAction = "logout_everywhere"
+)
+
+// FieldChange is the old and new value of one task field, an empty value means the field was not set
+type FieldChange struct {
+	Field string `bson:"field" json:"field"`
+	From  string `bson:"from" json:"from"`
+	To    string `bson:"to" json:"to"`
+}
+
+// AuditRecord describes one change to a task or a user. records are only ever added, never changed
+type AuditRecord struct {
+	ID     string `bson:"-" json:"id"`
+	TaskID string `bson:"taskId" json:"taskId"`
+	// UserID is the user a user management record is about, empty on task records
+	UserID  string        `bson:"userId,omitempty" json:"userId,omitempty"`
+	Action  AuditAction   `bson:"action" json:"action"`
+	ActorID string        `bson:"actorId" json:"actorId"`
+	At      time.Time     `bson:"at" json:"at"`
+	Changes []FieldChange `bson:"changes" json:"changes"`
+}
+
+// AuditFilter narrows down and pages the records returned by the audit repository
+type AuditFilter struct {
+	TaskID  string
+	UserID  string
+	ActorID string
+	// From and To bound the time of the change, both ends included
+	From  *time.Time
+	To    *time.Time
+	Limit int64
+	// Cursor is the opaque NextCursor of the previous page, empty for the first page
+	Cursor string
+}
+
+// AuditPage is one page of audit records, newest first
+type AuditPage struct {
+	Records    []AuditRecord `json:"records"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+	Total      int64         `json:"total"`
+}
+
+// DiffTasks lists the fields that differ between two versions of a task.
+// a nil task stands for a task that does not exist, e.g. before it was created
+func DiffTasks(before, after *Task) []FieldChange {
+	old, updated := auditFields(before), auditFields(after)
+
+	changes := []FieldChange{}
+	for _, field := range auditedFields {
+		if old[field] != updated[field] {
+			changes = append(changes, FieldChange{Field: field, From: old[field], To: updated[field]})
+		}
+	}
+	return changes
+}
+
+// task fields tracked by the audit trail, in the order they are reported
+var auditedFields = []string{"title", "description", "dueDate", "status", "ownerId", "assignees", "deletedAt", "deletedBy"}
+
+func auditFields(task *Task) map[string]string {
+	fields := map[string]string{}
+	if task == nil {
+		return fields
+	}
+	fields["title"] = task.Title
+	fields["description"] = task.Description
+	fields["dueDate"] = formatAuditTime(task.DueDate)
+	fields["status"] = string(task.Status)
+	fields["ownerId"] = task.OwnerID
+	//assignees are compared as a set
+	assignees := append([]string(nil), task.Assignees...)
+	sort.Strings(assignees)
+	fields["assignees"] = strings.Join(assignees, ",")
+	fields["deletedAt"] = formatAuditTime(task.DeletedAt)
+	fields["deletedBy"] = task.DeletedBy
+	return fields
+}
+
+func formatAuditTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -1,9 +1,9 @@
 package domain
 
 import (
+	"encoding/base64"
+	"strconv"
 	"time"
-
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // define taks and user struct
@@ -16,19 +16,167 @@ const (
 	StatusCompleted  TaskStatus = "completed"
 )
 
+// IsValid reports whether the status is one of the known task statuses
+func (s TaskStatus) IsValid() bool {
+	switch s {
+	case StatusNotStarted, StatusInProgress, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// TransitionGraph lists, for every status, the statuses a task may move to next
+type TransitionGraph map[TaskStatus][]TaskStatus
+
+// DefaultTransitionGraph lets work start, finish, pause and reopen but never
+// jump straight from completed back to not-started
+func DefaultTransitionGraph() TransitionGraph {
+	return TransitionGraph{
+		StatusNotStarted: {StatusInProgress},
+		StatusInProgress: {StatusNotStarted, StatusCompleted},
+		StatusCompleted:  {StatusInProgress},
+	}
+}
+
+// Allows reports whether a task may move from one status to the other
+func (g TransitionGraph) Allows(from, to TaskStatus) bool {
+	for _, next := range g[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransition records who moved a task from one status to another and when.
+// the transition recorded at creation has an empty From
+type StatusTransition struct {
+	From    TaskStatus `bson:"from" json:"from"`
+	To      TaskStatus `bson:"to" json:"to"`
+	ActorID string     `bson:"actorId" json:"actorId"`
+	At      time.Time  `bson:"at" json:"at"`
+}
+
 type Task struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Title       string             `bson:"title" json:"title"`
-	Description string             `bson:"description" json:"description"`
-	DueDate     time.Time          `bson:"dueDate" json:"dueDate"`
-	Status      TaskStatus         `bson:"status" json:"status"`
-	
-}
-type InputTask struct{
-	Title       string             `bson:"title" json:"title"`
-	Description string             `bson:"description" json:"description"`
-	DueDate     time.Time          `bson:"dueDate" json:"dueDate"`
-	Status      TaskStatus         `bson:"status" json:"status"`
+	// ID is made by NewID, the storage backends map it to their own key type
+	ID          string     `bson:"-" json:"id"`
+	Title       string     `bson:"title" json:"title"`
+	Description string     `bson:"description" json:"description"`
+	DueDate     *time.Time `bson:"dueDate,omitempty" json:"dueDate,omitempty"`
+	Status      TaskStatus `bson:"status" json:"status"`
+	OwnerID     string     `bson:"ownerId" json:"ownerId"`
+	Assignees   []string   `bson:"assignees" json:"assignees"`
+	// DeletedAt is set while the task sits in the trash
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy string     `bson:"deletedBy,omitempty" json:"deletedBy,omitempty"`
+	// Version is bumped on every write and served as the task ETag
+	Version int64 `bson:"version" json:"version"`
+	// StatusHistory is served by GET /tasks/:id/transitions
+	StatusHistory []StatusTransition `bson:"statusHistory" json:"-"`
+}
+
+// IsTrashed reports whether the task was soft deleted
+func (t *Task) IsTrashed() bool {
+	return t.DeletedAt != nil
+}
+
+// IsAssignee reports whether the user is one of the task assignees
+func (t *Task) IsAssignee(userID string) bool {
+	for _, id := range t.Assignees {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// LastTransition returns the last entry of the status history, false when the
+// history is empty or does not lead to the current status
+func (t *Task) LastTransition() (StatusTransition, bool) {
+	if len(t.StatusHistory) == 0 {
+		return StatusTransition{}, false
+	}
+	last := t.StatusHistory[len(t.StatusHistory)-1]
+	return last, last.To == t.Status
+}
+
+// TaskPatch holds the task fields a partial update changes, nil fields are left untouched
+type TaskPatch struct {
+	Title       *string
+	Description *string
+	Status      *TaskStatus
+	// DueDate is an RFC3339 date, an empty string removes the due date
+	DueDate *string
+}
+
+// TaskFilter narrows down, sorts and pages the tasks returned by the task repository
+type TaskFilter struct {
+	OwnerID    string
+	AssigneeID string
+	// VisibleTo keeps only the tasks the user owns or is assigned to
+	VisibleTo     string
+	Status        TaskStatus
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	TitleContains string
+	// ExcludeStatus drops the tasks in that status, e.g. completed tasks are never overdue
+	ExcludeStatus TaskStatus
+	// Trashed lists the tasks in the trash instead of the live ones
+	Trashed bool
+	// Overdue and DueWithinDays are resolved into a due date range by the task use case
+	Overdue       bool
+	DueWithinDays int
+	SortBy        string
+	SortDesc      bool
+	Limit         int64
+	// Cursor is the opaque NextCursor of the previous page, empty for the first page
+	Cursor string
+}
+
+// task fields the task list can be sorted by
+const (
+	SortByID      = "id"
+	SortByTitle   = "title"
+	SortByDueDate = "dueDate"
+	SortByStatus  = "status"
+)
+
+// TaskPage is one page of tasks together with the total number of matching tasks
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+// EncodeCursor turns the offset of the next page into an opaque cursor
+func EncodeCursor(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(offset, 10)))
+}
+
+// DecodeCursor returns the offset stored in a cursor, an empty cursor is the first page
+func DecodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, FieldValidation("cursor", "invalid cursor")
+	}
+	offset, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, FieldValidation("cursor", "invalid cursor")
+	}
+	return offset, nil
+}
+
+type InputTask struct {
+	Title       string `bson:"title" json:"title"`
+	Description string `bson:"description" json:"description"`
+	// DueDate is an RFC3339 date, empty for tasks without a due date
+	DueDate string     `bson:"dueDate" json:"dueDate"`
+	Status  TaskStatus `bson:"status" json:"status"`
+	// AllowPastDueDate lets a new task be created already past its due date
+	AllowPastDueDate bool `bson:"-" json:"allowPastDueDate"`
 }
 type Role string
 
@@ -37,16 +185,70 @@ const (
 	RoleUser  Role = "User"
 )
 
+// IsValid reports whether the role is one of the known roles
+func (r Role) IsValid() bool {
+	return r == RoleAdmin || r == RoleUser
+}
+
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username string             `bson:"username" json:"username"`
-	Password string             `bson:"password,omitempty" json:"-"` 
-	Role     Role               `bson:"role" json:"role"`
+	ID       string `bson:"-" json:"id"`
+	Username string `bson:"username" json:"username"`
+	Password string `bson:"password,omitempty" json:"-"`
+	Role     Role   `bson:"role" json:"role"`
+	// DeactivatedAt is set while an admin keeps the user from logging in
+	DeactivatedAt *time.Time `bson:"deactivatedAt,omitempty" json:"deactivatedAt,omitempty"`
 }
-type RegisterUserInput struct{
-	Username string
-	Password string
+
+// IsActive reports whether the user may log in and use their token
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
 }
 
+// UserFilter narrows down and pages the users returned by the user repository,
+// users are listed by username
+type UserFilter struct {
+	// Search keeps the users whose username contains it, ignoring case
+	Search string
+	Role   Role
+	Limit  int64
+	// Cursor is the opaque NextCursor of the previous page, empty for the first page
+	Cursor string
+}
 
+// UserPage is one page of users together with the total number of matching users
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      int64  `json:"total"`
+}
 
+// OrphanedTasks is what happens to the tasks of a deleted user
+type OrphanedTasks string
+
+const (
+	// OrphanedTasksTransfer hands the tasks over to another user
+	OrphanedTasksTransfer OrphanedTasks = "transfer"
+	// OrphanedTasksTrash moves the live tasks to the trash
+	OrphanedTasksTrash OrphanedTasks = "trash"
+)
+
+// Actor is the authenticated user on whose behalf a use case runs
+type Actor struct {
+	UserID string
+	Role   Role
+	// SessionID is the session of the token the request came with
+	SessionID string
+}
+
+// SystemActor makes the changes the server does on its own, like the trash retention purge
+var SystemActor = Actor{UserID: "system"}
+
+// IsAdmin reports whether the actor has global visibility
+func (a Actor) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+type RegisterUserInput struct {
+	Username string
+	Password string
+}
@@ -0,0 +1,133 @@
+package domain
+
+import "errors"
+
+// error kinds, every error returned by the use cases is one of these kinds.
+// check the kind with errors.Is(err, domain.ErrNotFound)
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrInternal     = errors.New("internal error")
+	// ErrPreconditionFailed is a write made against an outdated version
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error is an error of a known kind. Message is safe to show to clients,
+// Err keeps the underlying cause for the logs
+type Error struct {
+	Kind    error
+	Message string
+	// Code is a machine readable name of the error, the kind's code when empty
+	Code string
+	// Fields lists what is wrong with each invalid input field
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError is the problem with one input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// machine readable codes of the error kinds
+var kindCodes = map[error]string{
+	ErrNotFound:           "not_found",
+	ErrConflict:           "conflict",
+	ErrValidation:         "validation_failed",
+	ErrUnauthorized:       "unauthorized",
+	ErrForbidden:          "forbidden",
+	ErrInternal:           "internal",
+	ErrPreconditionFailed: "precondition_failed",
+}
+
+// ErrorCode returns the machine readable code of any error, unknown errors are internal
+func ErrorCode(err error) string {
+	var domainErr *Error
+	if !errors.As(err, &domainErr) {
+		return kindCodes[ErrInternal]
+	}
+	if domainErr.Code != "" {
+		return domainErr.Code
+	}
+	return kindCodes[domainErr.Kind]
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap lets errors.Is match both the kind and the underlying cause
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// ErrVersionMismatch is returned when a task changed since the version the client last read
+var ErrVersionMismatch error = &Error{Kind: ErrPreconditionFailed, Code: "version_mismatch", Message: "task was modified by someone else, reload it and try again"}
+
+// ErrLastAdmin is returned when a change would leave no active admin at all
+var ErrLastAdmin error = &Error{Kind: ErrConflict, Code: "last_admin", Message: "the last admin cannot lose the admin role"}
+
+// ErrAccountDeactivated is returned when a deactivated user logs in or uses their token
+var ErrAccountDeactivated error = &Error{Kind: ErrForbidden, Code: "account_deactivated", Message: "the account is deactivated"}
+
+// ErrInvalidRefreshToken is returned for unknown, expired and revoked refresh tokens
+var ErrInvalidRefreshToken error = &Error{Kind: ErrUnauthorized, Code: "invalid_refresh_token", Message: "the refresh token is invalid or expired, log in again"}
+
+// ErrRefreshTokenReused is returned when a refresh token is used twice. the token
+// probably leaked, so every token of its family was revoked
+var ErrRefreshTokenReused error = &Error{Kind: ErrUnauthorized, Code: "refresh_token_reused", Message: "the refresh token was already used, log in again"}
+
+// ErrInvalidPersonalToken is returned for unknown, expired and revoked personal access tokens
+var ErrInvalidPersonalToken error = &Error{Kind: ErrUnauthorized, Code: "invalid_personal_token", Message: "the personal access token is invalid, expired or revoked"}
+
+// InsufficientScope is returned when a personal access token is used on a route its
+// scopes do not cover, an empty scope means the route takes no personal access tokens
+func InsufficientScope(scope Scope) error {
+	message := "personal access tokens cannot be used on this route"
+	if scope != "" {
+		message = "the personal access token lacks the " + string(scope) + " scope"
+	}
+	return &Error{Kind: ErrForbidden, Code: "insufficient_scope", Message: message}
+}
+
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+// FieldValidation is a validation error caused by a single input field
+func FieldValidation(field, message string) error {
+	return &Error{Kind: ErrValidation, Message: message, Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// InvalidFields is a validation error listing every invalid input field
+func InvalidFields(message string, fields []FieldError) error {
+	return &Error{Kind: ErrValidation, Message: message, Fields: fields}
+}
+
+func Unauthorized(message string) error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// Internal hides the cause of a failure behind a message that is safe to show
+func Internal(message string, cause error) error {
+	return &Error{Kind: ErrInternal, Message: message, Err: cause}
+}
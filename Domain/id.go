@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// ids are 12 bytes written as 24 hex characters: the creation time in seconds, 5 random
+// bytes picked once per process and a counter. they sort by creation time and have the
+// layout of mongo object ids, so every storage backend can keep them as they are
+const idLength = 24
+
+var (
+	idProcess [5]byte
+	idCounter atomic.Uint32
+)
+
+func init() {
+	var seed [4]byte
+	_, _ = rand.Read(idProcess[:])
+	_, _ = rand.Read(seed[:])
+	idCounter.Store(binary.BigEndian.Uint32(seed[:]))
+}
+
+// NewID returns a new unique id for a task, user or audit record
+func NewID() string {
+	var id [12]byte
+	binary.BigEndian.PutUint32(id[0:4], uint32(time.Now().Unix()))
+	copy(id[4:9], idProcess[:])
+	counter := idCounter.Add(1)
+	id[9], id[10], id[11] = byte(counter>>16), byte(counter>>8), byte(counter)
+	return hex.EncodeToString(id[:])
+}
+
+// IsValidID reports whether s has the form of an id made by NewID
+func IsValidID(s string) bool {
+	if len(s) != idLength {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,122 @@
+package domain
+
+import "time"
+
+// RefreshToken is the server side record of a refresh token. only the hash of the
+// token is kept, the client holds the token itself
+type RefreshToken struct {
+	ID     string `bson:"-" json:"id"`
+	UserID string `bson:"userId" json:"userId"`
+	// FamilyID is shared by every token rotated out of the same login
+	FamilyID  string    `bson:"familyId" json:"familyId"`
+	TokenHash string    `bson:"tokenHash" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+	// UsedAt is set once the token was exchanged for a new one, it cannot be used again
+	UsedAt *time.Time `bson:"usedAt,omitempty" json:"usedAt,omitempty"`
+	// RevokedAt is set on logout and when a used token comes back
+	RevokedAt *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// IsExpired reports whether the token is past its expiry time
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// AccessClaims is what an access token says about its holder
+type AccessClaims struct {
+	// TokenID is the jti claim, unique to every access token
+	TokenID string
+	UserID  string
+	Role    Role
+	// SessionID is the sid claim, the login the token was issued for
+	SessionID string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Session is one login of a user, it lives as long as the refresh tokens rotated
+// out of that login. the session ID is the family ID of those refresh tokens
+type Session struct {
+	ID         string     `bson:"-" json:"id"`
+	UserID     string     `bson:"userId" json:"userId"`
+	UserAgent  string     `bson:"userAgent" json:"userAgent"`
+	IP         string     `bson:"ip" json:"ip"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	LastSeenAt time.Time  `bson:"lastSeenAt" json:"lastSeenAt"`
+	ExpiresAt  time.Time  `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	// Current marks the session of the request listing the sessions, it is not stored
+	Current bool `bson:"-" json:"current"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ClientInfo describes where a login comes from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// TokenPair is what a login or a refresh hands to the client
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Scope is a permission of a personal access token
+type Scope string
+
+const (
+	ScopeTasksRead  Scope = "tasks:read"
+	ScopeTasksWrite Scope = "tasks:write"
+)
+
+// Scopes lists every scope a personal access token can be given
+var Scopes = []Scope{ScopeTasksRead, ScopeTasksWrite}
+
+// PersonalTokenPrefix starts every personal access token, it tells them apart from
+// the signed access tokens
+const PersonalTokenPrefix = "pat_"
+
+// PersonalToken is a named, scoped and expiring token a user creates for scripts and
+// automation. only the hash of the token is kept, the user sees the token once
+type PersonalToken struct {
+	ID         string     `bson:"-" json:"id"`
+	UserID     string     `bson:"userId" json:"userId"`
+	Name       string     `bson:"name" json:"name"`
+	Scopes     []Scope    `bson:"scopes" json:"scopes"`
+	TokenHash  string     `bson:"tokenHash" json:"-"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  time.Time  `bson:"expiresAt" json:"expiresAt"`
+	LastUsedAt *time.Time `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
+
+// IsActive reports whether the token can still be used
+func (t *PersonalToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// HasScope reports whether the token was given the scope
+func (t *PersonalToken) HasScope(scope Scope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PersonalTokenInput is what a user asks for when creating a personal access token,
+// a nil ExpiresAt gets the default lifetime
+type PersonalTokenInput struct {
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "regexp"
+
+// FieldRule describes what a valid value of one input field looks like.
+// the rules are enforced by the use cases and published so clients can mirror them
+type FieldRule struct {
+	Field    string `json:"field"`
+	Required bool   `json:"required"`
+	// MinLength and MaxLength count characters, not bytes
+	MinLength int `json:"minLength,omitempty"`
+	MaxLength int `json:"maxLength,omitempty"`
+	// MaxBytes bounds the UTF-8 encoded size of the value
+	MaxBytes int `json:"maxBytes,omitempty"`
+	// Pattern is a regular expression the whole value must match
+	Pattern        string   `json:"pattern,omitempty"`
+	PatternMessage string   `json:"patternMessage,omitempty"`
+	OneOf          []string `json:"oneOf,omitempty"`
+	// Format names a well known format, "date-time" is an RFC3339 date
+	Format string `json:"format,omitempty"`
+	// Regexp is Pattern compiled by CompileRules
+	Regexp *regexp.Regexp `json:"-"`
+}
+
+// CompileRules compiles the pattern of every rule once and returns the rules,
+// it panics on a bad pattern so a broken rule table stops the server at startup
+func CompileRules(rules []FieldRule) []FieldRule {
+	for i := range rules {
+		if rules[i].Pattern != "" {
+			rules[i].Regexp = regexp.MustCompile(rules[i].Pattern)
+		}
+	}
+	return rules
+}
+
+// formats a FieldRule can ask for
+const FormatDateTime = "date-time"
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// MemoryAuditRepository keeps the audit trail in memory, it is safe for concurrent use
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	records []domain.AuditRecord
+}
+
+func NewMemoryAuditRepository() usecases.IAuditRepo {
+	return &MemoryAuditRepository{}
+}
+
+// function to append a record to the audit trail
+func (r *MemoryAuditRepository) AddRecord(ctx context.Context, record *domain.AuditRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if record.ID == "" {
+		record.ID = domain.NewID()
+	}
+	stored := *record
+	stored.Changes = append([]domain.FieldChange{}, record.Changes...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, stored)
+	return nil
+}
+
+// function to get one page of the audit records matching the filter, newest first
+func (r *MemoryAuditRepository) GetRecords(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	matching := make([]domain.AuditRecord, 0)
+	for _, record := range r.records {
+		if matchesAuditFilter(record, filter) {
+			matching = append(matching, record)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matching, func(i, j int) bool {
+		if !matching[i].At.Equal(matching[j].At) {
+			return matching[i].At.After(matching[j].At)
+		}
+		return matching[i].ID > matching[j].ID
+	})
+
+	total := int64(len(matching))
+	records := make([]domain.AuditRecord, 0)
+	for i := offset; i < total && (filter.Limit <= 0 || int64(len(records)) < filter.Limit); i++ {
+		records = append(records, matching[i])
+	}
+
+	page := &domain.AuditPage{Records: records, Total: total}
+	if next := offset + int64(len(records)); len(records) > 0 && next < total {
+		page.NextCursor = domain.EncodeCursor(next)
+	}
+	return page, nil
+}
+
+// matchesAuditFilter applies the same conditions as the mongo query of buildAuditFilter
+func matchesAuditFilter(record domain.AuditRecord, filter domain.AuditFilter) bool {
+	if filter.TaskID != "" && record.TaskID != filter.TaskID {
+		return false
+	}
+	if filter.UserID != "" && record.UserID != filter.UserID {
+		return false
+	}
+	if filter.ActorID != "" && record.ActorID != filter.ActorID {
+		return false
+	}
+	if filter.From != nil && record.At.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && record.At.After(*filter.To) {
+		return false
+	}
+	return true
+}
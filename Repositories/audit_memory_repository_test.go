@@ -0,0 +1,43 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	repositories "task_management/Repositories"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MemoryAuditRepositoryTestSuite struct {
+	suite.Suite
+}
+
+func TestMemoryAuditRepositorySuite(t *testing.T) {
+	suite.Run(t, new(MemoryAuditRepositoryTestSuite))
+}
+
+func (suite *MemoryAuditRepositoryTestSuite) TestGetRecords() {
+	ctx := context.Background()
+	repo := repositories.NewMemoryAuditRepository()
+	at := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	for i, taskID := range []string{"task", "other", "task", "task"} {
+		record := &domain.AuditRecord{TaskID: taskID, Action: domain.AuditUpdate, ActorID: "actor", At: at.Add(time.Duration(i) * time.Hour)}
+		suite.Require().NoError(repo.AddRecord(ctx, record))
+		suite.NotEmpty(record.ID)
+	}
+
+	from := at.Add(time.Hour)
+	first, err := repo.GetRecords(ctx, domain.AuditFilter{TaskID: "task", From: &from, Limit: 1})
+	suite.NoError(err)
+	suite.Equal(int64(2), first.Total)
+	suite.Equal(at.Add(3*time.Hour), first.Records[0].At)
+	suite.NotEmpty(first.NextCursor)
+
+	second, err := repo.GetRecords(ctx, domain.AuditFilter{TaskID: "task", From: &from, Limit: 1, Cursor: first.NextCursor})
+	suite.NoError(err)
+	suite.Equal(at.Add(2*time.Hour), second.Records[0].At)
+	suite.Empty(second.NextCursor)
+}
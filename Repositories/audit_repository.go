@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/db"
+	"task_management/usecases"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// the audit collection is only ever inserted into and read from
+type IAuditMongoCollection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
+}
+
+// auditDocument is an audit record as stored in mongo, the id is kept as an object id
+type auditDocument struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	domain.AuditRecord `bson:",inline"`
+}
+
+type AuditRepository struct {
+	Collection IAuditMongoCollection
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewAuditRepository(timeout time.Duration) usecases.IAuditRepo {
+	return &AuditRepository{
+		Collection: db.GetAuditCollection(),
+		Timeout:    timeout,
+	}
+}
+
+// function to append a record to the audit trail
+func (r *AuditRepository) AddRecord(ctx context.Context, record *domain.AuditRecord) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	if record.ID == "" {
+		record.ID = domain.NewID()
+	}
+	objID, err := primitive.ObjectIDFromHex(record.ID)
+	if err != nil {
+		return domain.Validation("invalid audit record ID")
+	}
+	_, err = r.Collection.InsertOne(ctx, &auditDocument{ID: objID, AuditRecord: *record})
+	return err
+}
+
+// function to get one page of the audit records matching the filter, newest first
+func (r *AuditRepository) GetRecords(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	query := buildAuditFilter(filter)
+
+	total, err := r.Collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count audit records: %v", err)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(offset)
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	cur, err := r.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit records: %v", err)
+	}
+	defer cur.Close(ctx)
+
+	var docs []auditDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode audit records: %v", err)
+	}
+	records := make([]domain.AuditRecord, 0, len(docs))
+	for _, doc := range docs {
+		record := doc.AuditRecord
+		record.ID = doc.ID.Hex()
+		records = append(records, record)
+	}
+
+	page := &domain.AuditPage{Records: records, Total: total}
+	if next := offset + int64(len(records)); len(records) > 0 && next < total {
+		page.NextCursor = domain.EncodeCursor(next)
+	}
+	return page, nil
+}
+
+// translates the audit filter into a mongo query
+func buildAuditFilter(filter domain.AuditFilter) bson.M {
+	query := bson.M{}
+	if filter.TaskID != "" {
+		query["taskId"] = filter.TaskID
+	}
+	if filter.UserID != "" {
+		query["userId"] = filter.UserID
+	}
+	if filter.ActorID != "" {
+		query["actorId"] = filter.ActorID
+	}
+	if filter.From != nil || filter.To != nil {
+		at := bson.M{}
+		if filter.From != nil {
+			at["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			at["$lte"] = *filter.To
+		}
+		query["at"] = at
+	}
+	return query
+}
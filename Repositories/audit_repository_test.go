@@ -0,0 +1,92 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	repositories "task_management/Repositories"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AuditRepositoryTestSuite struct {
+	suite.Suite
+	repo        *repositories.AuditRepository
+	mockCol     *MockTaskCollection
+	mockContext context.Context
+}
+
+func (suite *AuditRepositoryTestSuite) SetupTest() {
+	suite.mockCol = new(MockTaskCollection)
+	suite.mockContext = context.Background()
+	suite.repo = &repositories.AuditRepository{
+		Collection: suite.mockCol,
+	}
+}
+
+func TestAuditRepositorySuite(t *testing.T) {
+	suite.Run(t, new(AuditRepositoryTestSuite))
+}
+
+func (suite *AuditRepositoryTestSuite) TestAddRecord() {
+	// Test Case 1  Records get an id before they are inserted
+	suite.Run("Success", func() {
+		suite.SetupTest()
+
+		record := &domain.AuditRecord{TaskID: "task", Action: domain.AuditCreate, ActorID: "actor", At: time.Now()}
+		suite.mockCol.On("InsertOne", suite.mockContext, mock.AnythingOfType("*repositories.auditDocument")).Return(&mongo.InsertOneResult{}, nil).Run(func(args mock.Arguments) {
+			inserted := insertedDocument(args)
+			suite.IsType(primitive.ObjectID{}, inserted["_id"])
+			suite.Equal("task", inserted["taskId"])
+		}).Once()
+
+		err := suite.repo.AddRecord(suite.mockContext, record)
+		suite.NoError(err)
+		suite.True(domain.IsValidID(record.ID))
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+}
+
+func (suite *AuditRepositoryTestSuite) TestGetRecords() {
+	// Test Case 1  Records are filtered by task, actor and time range
+	suite.Run("Filters", func() {
+		suite.SetupTest()
+
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 1, 0)
+		query := bson.M{
+			"taskId":  "task",
+			"actorId": "actor",
+			"at":      bson.M{"$gte": from, "$lte": to},
+		}
+		stored := domain.AuditRecord{TaskID: "task", Action: domain.AuditUpdate, ActorID: "actor", At: from}
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{stored}, nil, nil)
+		suite.Require().NoError(err)
+		suite.mockCol.On("CountDocuments", suite.mockContext, query).Return(int64(3), nil).Once()
+		suite.mockCol.On("Find", suite.mockContext, query).Return(cursor, nil).Once()
+
+		page, err := suite.repo.GetRecords(suite.mockContext, domain.AuditFilter{TaskID: "task", ActorID: "actor", From: &from, To: &to, Limit: 1})
+		suite.NoError(err)
+		suite.Len(page.Records, 1)
+		suite.Equal(domain.AuditUpdate, page.Records[0].Action)
+		suite.Equal(int64(3), page.Total)
+		suite.Equal(domain.EncodeCursor(1), page.NextCursor)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 2  Invalid cursor never reaches the database
+	suite.Run("Invalid cursor", func() {
+		suite.SetupTest()
+
+		page, err := suite.repo.GetRecords(suite.mockContext, domain.AuditFilter{Cursor: "not a cursor"})
+		suite.Nil(page)
+		suite.EqualError(err, "invalid cursor")
+		suite.mockCol.AssertNotCalled(suite.T(), "CountDocuments", mock.Anything, mock.Anything)
+	})
+}
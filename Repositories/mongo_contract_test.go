@@ -0,0 +1,105 @@
+package repositories_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	domain "task_management/Domain"
+	repositories "task_management/Repositories"
+	"task_management/Repositories/repotest"
+	"task_management/config"
+	"task_management/db"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// the mongo contract tests only run when TEST_MONGO_URL points at a server,
+// every test gets its own database which is dropped afterwards
+const mongoURLEnv = "TEST_MONGO_URL"
+
+// mongoURL skips the test when no mongo server is configured
+func mongoURL(t *testing.T) string {
+	url := os.Getenv(mongoURLEnv)
+	if url == "" {
+		t.Skip(mongoURLEnv + " is not set")
+	}
+	return url
+}
+
+// mongoDatabase connects the db package to a new database and returns it
+func mongoDatabase(t *testing.T, url string) *mongo.Database {
+	name := "task_management_test_" + domain.NewID()
+	require.NoError(t, db.Connect(config.MongoConfig{URL: url, Database: name}))
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(url))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Database(name).Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+		_ = db.Disconnect(context.Background())
+	})
+	return client.Database(name)
+}
+
+func TestMongoTaskRepositoryContract(t *testing.T) {
+	url := mongoURL(t)
+	suite.Run(t, &repotest.TaskRepoContract{
+		NewRepo: func(t *testing.T) usecases.ITaskRepo {
+			return &repositories.TaskRepository{Collection: mongoDatabase(t, url).Collection("tasks")}
+		},
+	})
+}
+
+func TestMongoUserRepositoryContract(t *testing.T) {
+	url := mongoURL(t)
+	suite.Run(t, &repotest.UserRepoContract{
+		NewRepo: func(t *testing.T) usecases.IUserRepository {
+			return &repositories.UserRepository{Collection: mongoDatabase(t, url).Collection("users")}
+		},
+	})
+}
+
+func TestMongoRefreshTokenRepositoryContract(t *testing.T) {
+	url := mongoURL(t)
+	suite.Run(t, &repotest.RefreshTokenRepoContract{
+		NewRepo: func(t *testing.T) usecases.IRefreshTokenRepo {
+			return &repositories.RefreshTokenRepository{Collection: mongoDatabase(t, url).Collection("refresh_tokens")}
+		},
+	})
+}
+
+func TestMongoTokenRevocationRepositoryContract(t *testing.T) {
+	url := mongoURL(t)
+	suite.Run(t, &repotest.TokenRevocationRepoContract{
+		NewRepo: func(t *testing.T) usecases.ITokenRevocationRepo {
+			database := mongoDatabase(t, url)
+			return &repositories.TokenRevocationRepository{
+				Tokens: database.Collection("revoked_tokens"),
+				Users:  database.Collection("user_revocations"),
+			}
+		},
+	})
+}
+
+func TestMongoSessionRepositoryContract(t *testing.T) {
+	url := mongoURL(t)
+	suite.Run(t, &repotest.SessionRepoContract{
+		NewRepo: func(t *testing.T) usecases.ISessionRepo {
+			return &repositories.SessionRepository{Collection: mongoDatabase(t, url).Collection("sessions")}
+		},
+	})
+}
+
+func TestMongoPersonalTokenRepositoryContract(t *testing.T) {
+	url := mongoURL(t)
+	suite.Run(t, &repotest.PersonalTokenRepoContract{
+		NewRepo: func(t *testing.T) usecases.IPersonalTokenRepo {
+			return &repositories.PersonalTokenRepository{Collection: mongoDatabase(t, url).Collection("personal_tokens")}
+		},
+	})
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// MemoryPersonalTokenRepository keeps the personal access tokens in memory, token
+// hashes are unique like in the mongo collection. it is safe for concurrent use
+type MemoryPersonalTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]*domain.PersonalToken
+}
+
+func NewMemoryPersonalTokenRepository() usecases.IPersonalTokenRepo {
+	return &MemoryPersonalTokenRepository{tokens: map[string]*domain.PersonalToken{}}
+}
+
+// stores a new personal access token, an empty ID is filled in
+func (r *MemoryPersonalTokenRepository) CreatePersonalToken(ctx context.Context, token *domain.PersonalToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if token.ID == "" {
+		token.ID = domain.NewID()
+	} else if !domain.IsValidID(token.ID) {
+		return domain.Validation("invalid personal token ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return domain.Conflict("personal token already exists")
+		}
+	}
+	r.tokens[token.ID] = clonePersonalToken(token)
+	return nil
+}
+
+// retrieves a personal access token by its ID
+func (r *MemoryPersonalTokenRepository) FindPersonalToken(ctx context.Context, tokenID string) (*domain.PersonalToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !domain.IsValidID(tokenID) {
+		return nil, domain.Validation("invalid personal token ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenID]
+	if !ok {
+		return nil, domain.NotFound("personal token not found")
+	}
+	return clonePersonalToken(token), nil
+}
+
+// retrieves a personal access token by the hash of the token
+func (r *MemoryPersonalTokenRepository) FindPersonalTokenByHash(ctx context.Context, hash string) (*domain.PersonalToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return clonePersonalToken(token), nil
+		}
+	}
+	return nil, domain.NotFound("personal token not found")
+}
+
+// lists the tokens of the user that are not revoked, newest first
+func (r *MemoryPersonalTokenRepository) ListPersonalTokens(ctx context.Context, userID string) ([]domain.PersonalToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := []domain.PersonalToken{}
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			tokens = append(tokens, *clonePersonalToken(token))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+// moves the last used time of the token forward
+func (r *MemoryPersonalTokenRepository) TouchPersonalToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	return r.update(ctx, tokenID, func(token *domain.PersonalToken) {
+		if token.LastUsedAt == nil || usedAt.After(*token.LastUsedAt) {
+			token.LastUsedAt = &usedAt
+		}
+	})
+}
+
+// revokes the token, a revoked token keeps its first revocation time
+func (r *MemoryPersonalTokenRepository) RevokePersonalToken(ctx context.Context, tokenID string, at time.Time) error {
+	return r.update(ctx, tokenID, func(token *domain.PersonalToken) {
+		if token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	})
+}
+
+// revokes every token of the user that is not revoked yet
+func (r *MemoryPersonalTokenRepository) RevokeUserPersonalTokens(ctx context.Context, userID string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			revokedAt := at
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPersonalTokenRepository) update(ctx context.Context, tokenID string, change func(token *domain.PersonalToken)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(tokenID) {
+		return domain.Validation("invalid personal token ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenID]
+	if !ok {
+		return domain.NotFound("personal token not found")
+	}
+	change(token)
+	return nil
+}
+
+// clonePersonalToken copies a token so callers never share memory with the stored one
+func clonePersonalToken(token *domain.PersonalToken) *domain.PersonalToken {
+	c := *token
+	c.Scopes = append([]domain.Scope(nil), token.Scopes...)
+	c.LastUsedAt = copyTime(token.LastUsedAt)
+	c.RevokedAt = copyTime(token.RevokedAt)
+	return &c
+}
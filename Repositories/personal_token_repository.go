@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/db"
+	"task_management/usecases"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IPersonalTokenMongoCollection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+}
+
+// personalTokenDocument is a personal access token as stored in mongo, the id is kept as an object id
+type personalTokenDocument struct {
+	ID                   primitive.ObjectID `bson:"_id"`
+	domain.PersonalToken `bson:",inline"`
+}
+
+func (d *personalTokenDocument) personalToken() domain.PersonalToken {
+	token := d.PersonalToken
+	token.ID = d.ID.Hex()
+	return token
+}
+
+// PersonalTokenRepository keeps the personal access tokens in mongo, expired tokens are
+// removed by the TTL index db.Connect creates
+type PersonalTokenRepository struct {
+	Collection IPersonalTokenMongoCollection
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewPersonalTokenRepository(timeout time.Duration) usecases.IPersonalTokenRepo {
+	return &PersonalTokenRepository{
+		Collection: db.GetPersonalTokensCollection(),
+		Timeout:    timeout,
+	}
+}
+
+// stores a new personal access token, an empty ID is filled in
+func (r *PersonalTokenRepository) CreatePersonalToken(ctx context.Context, token *domain.PersonalToken) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	if token.ID == "" {
+		token.ID = domain.NewID()
+	}
+	objID, err := primitive.ObjectIDFromHex(token.ID)
+	if err != nil {
+		return domain.Validation("invalid personal token ID")
+	}
+	_, err = r.Collection.InsertOne(ctx, &personalTokenDocument{ID: objID, PersonalToken: *token})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Conflict("personal token already exists")
+	}
+	return err
+}
+
+// retrieves a personal access token by its ID
+func (r *PersonalTokenRepository) FindPersonalToken(ctx context.Context, tokenID string) (*domain.PersonalToken, error) {
+	objID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return nil, domain.Validation("invalid personal token ID")
+	}
+	return r.findOne(ctx, bson.M{"_id": objID})
+}
+
+// retrieves a personal access token by the hash of the token
+func (r *PersonalTokenRepository) FindPersonalTokenByHash(ctx context.Context, hash string) (*domain.PersonalToken, error) {
+	return r.findOne(ctx, bson.M{"tokenHash": hash})
+}
+
+func (r *PersonalTokenRepository) findOne(ctx context.Context, filter bson.M) (*domain.PersonalToken, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	var doc personalTokenDocument
+	err := r.Collection.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.NotFound("personal token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := doc.personalToken()
+	return &token, nil
+}
+
+// lists the tokens of the user that are not revoked, newest first
+func (r *PersonalTokenRepository) ListPersonalTokens(ctx context.Context, userID string) ([]domain.PersonalToken, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: 1}})
+	cur, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var docs []personalTokenDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	tokens := make([]domain.PersonalToken, 0, len(docs))
+	for i := range docs {
+		tokens = append(tokens, docs[i].personalToken())
+	}
+	return tokens, nil
+}
+
+// moves the last used time of the token forward
+func (r *PersonalTokenRepository) TouchPersonalToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	return r.update(ctx, tokenID, bson.M{"$max": bson.M{"lastUsedAt": usedAt}})
+}
+
+// revokes the token, a revoked token keeps its first revocation time
+func (r *PersonalTokenRepository) RevokePersonalToken(ctx context.Context, tokenID string, at time.Time) error {
+	return r.update(ctx, tokenID, bson.M{"$min": bson.M{"revokedAt": at}})
+}
+
+// revokes every token of the user that is not revoked yet
+func (r *PersonalTokenRepository) RevokeUserPersonalTokens(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	_, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": at}})
+	return err
+}
+
+func (r *PersonalTokenRepository) update(ctx context.Context, tokenID string, update bson.M) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return domain.Validation("invalid personal token ID")
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.NotFound("personal token not found")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// MemoryRefreshTokenRepository keeps the refresh tokens in memory, token hashes are
+// unique like in the mongo collection. it is safe for concurrent use
+type MemoryRefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]*domain.RefreshToken
+}
+
+func NewMemoryRefreshTokenRepository() usecases.IRefreshTokenRepo {
+	return &MemoryRefreshTokenRepository{tokens: map[string]*domain.RefreshToken{}}
+}
+
+// stores a new refresh token, an empty ID is filled in
+func (r *MemoryRefreshTokenRepository) CreateToken(ctx context.Context, token *domain.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if token.ID == "" {
+		token.ID = domain.NewID()
+	} else if !domain.IsValidID(token.ID) {
+		return domain.Validation("invalid refresh token ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return domain.Conflict("refresh token already exists")
+		}
+	}
+	r.tokens[token.ID] = cloneRefreshToken(token)
+	return nil
+}
+
+// retrieves a refresh token by the hash of the token
+func (r *MemoryRefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return cloneRefreshToken(token), nil
+		}
+	}
+	return nil, domain.NotFound("refresh token not found")
+}
+
+// marks a refresh token as exchanged, a token can only be used once
+func (r *MemoryRefreshTokenRepository) MarkUsed(ctx context.Context, tokenID string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(tokenID) {
+		return domain.Validation("invalid refresh token ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenID]
+	if !ok {
+		return domain.NotFound("refresh token not found")
+	}
+	if token.UsedAt != nil {
+		return domain.Conflict("refresh token was already used")
+	}
+	token.UsedAt = &at
+	return nil
+}
+
+// revokes every token of a family that is not revoked yet
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.revoke(ctx, at, func(token *domain.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+// revokes every token of the user that is not revoked yet
+func (r *MemoryRefreshTokenRepository) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	return r.revoke(ctx, at, func(token *domain.RefreshToken) bool { return token.UserID == userID })
+}
+
+func (r *MemoryRefreshTokenRepository) revoke(ctx context.Context, at time.Time, match func(token *domain.RefreshToken) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if match(token) && token.RevokedAt == nil {
+			revokedAt := at
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+// cloneRefreshToken copies a token so callers never share memory with the stored one
+func cloneRefreshToken(token *domain.RefreshToken) *domain.RefreshToken {
+	c := *token
+	c.UsedAt = copyTime(token.UsedAt)
+	c.RevokedAt = copyTime(token.RevokedAt)
+	return &c
+}
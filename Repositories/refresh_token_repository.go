@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/db"
+	"task_management/usecases"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IRefreshTokenMongoCollection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+}
+
+// refreshTokenDocument is a refresh token as stored in mongo, the id is kept as an object id
+type refreshTokenDocument struct {
+	ID                  primitive.ObjectID `bson:"_id"`
+	domain.RefreshToken `bson:",inline"`
+}
+
+// RefreshTokenRepository keeps the refresh tokens in mongo, expired tokens are
+// removed by the TTL index db.Connect creates
+type RefreshTokenRepository struct {
+	Collection IRefreshTokenMongoCollection
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewRefreshTokenRepository(timeout time.Duration) usecases.IRefreshTokenRepo {
+	return &RefreshTokenRepository{
+		Collection: db.GetRefreshTokensCollection(),
+		Timeout:    timeout,
+	}
+}
+
+// stores a new refresh token, an empty ID is filled in
+func (r *RefreshTokenRepository) CreateToken(ctx context.Context, token *domain.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	if token.ID == "" {
+		token.ID = domain.NewID()
+	}
+	objID, err := primitive.ObjectIDFromHex(token.ID)
+	if err != nil {
+		return domain.Validation("invalid refresh token ID")
+	}
+	_, err = r.Collection.InsertOne(ctx, &refreshTokenDocument{ID: objID, RefreshToken: *token})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Conflict("refresh token already exists")
+	}
+	return err
+}
+
+// retrieves a refresh token by the hash of the token
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	var doc refreshTokenDocument
+	err := r.Collection.FindOne(ctx, bson.M{"tokenHash": hash}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.NotFound("refresh token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := doc.RefreshToken
+	token.ID = doc.ID.Hex()
+	return &token, nil
+}
+
+// marks a refresh token as exchanged, a token can only be used once
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, tokenID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return domain.Validation("invalid refresh token ID")
+	}
+
+	//only a token that was never used matches, the update is the check
+	filter := bson.M{"_id": objID, "usedAt": bson.M{"$exists": false}}
+	result, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"usedAt": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	err = r.Collection.FindOne(ctx, bson.M{"_id": objID}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.NotFound("refresh token not found")
+	}
+	if err != nil {
+		return err
+	}
+	return domain.Conflict("refresh token was already used")
+}
+
+// revokes every token of a family that is not revoked yet
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	filter := bson.M{"familyId": familyID, "revokedAt": bson.M{"$exists": false}}
+	_, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": at}})
+	return err
+}
+
+// revokes every token of the user that is not revoked yet
+func (r *RefreshTokenRepository) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	_, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": at}})
+	return err
+}
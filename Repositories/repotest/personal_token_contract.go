@@ -0,0 +1,177 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// PersonalTokenRepoContract is the behaviour expected from an IPersonalTokenRepo
+type PersonalTokenRepoContract struct {
+	suite.Suite
+	// NewRepo returns an empty repository, it is called before every test
+	NewRepo func(t *testing.T) usecases.IPersonalTokenRepo
+
+	repo usecases.IPersonalTokenRepo
+	ctx  context.Context
+	now  time.Time
+}
+
+func (suite *PersonalTokenRepoContract) SetupTest() {
+	suite.repo = suite.NewRepo(suite.T())
+	suite.ctx = context.Background()
+	//the mongo TTL index removes expired tokens, keep them in the future
+	suite.now = time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// addToken stores a token of the user created at createdAt and returns it
+func (suite *PersonalTokenRepoContract) addToken(userID string, createdAt time.Time) *domain.PersonalToken {
+	token := &domain.PersonalToken{
+		UserID:    userID,
+		Name:      "deploy job",
+		Scopes:    []domain.Scope{domain.ScopeTasksRead, domain.ScopeTasksWrite},
+		TokenHash: "hash-" + domain.NewID(),
+		CreatedAt: createdAt,
+		ExpiresAt: suite.now.Add(time.Hour),
+	}
+	suite.Require().NoError(suite.repo.CreatePersonalToken(suite.ctx, token))
+	return token
+}
+
+// find reads a token back and fails the test when it is missing
+func (suite *PersonalTokenRepoContract) find(tokenID string) *domain.PersonalToken {
+	token, err := suite.repo.FindPersonalToken(suite.ctx, tokenID)
+	suite.Require().NoError(err)
+	return token
+}
+
+func (suite *PersonalTokenRepoContract) TestCreatePersonalToken() {
+	suite.Run("Success", func() {
+		suite.SetupTest()
+		token := suite.addToken(domain.NewID(), suite.now)
+
+		suite.True(domain.IsValidID(token.ID))
+		found := suite.find(token.ID)
+		suite.Equal(token.UserID, found.UserID)
+		suite.Equal("deploy job", found.Name)
+		suite.Equal(token.Scopes, found.Scopes)
+		suite.Equal(token.TokenHash, found.TokenHash)
+		suite.True(token.CreatedAt.Equal(found.CreatedAt))
+		suite.True(token.ExpiresAt.Equal(found.ExpiresAt))
+		suite.Nil(found.LastUsedAt)
+		suite.Nil(found.RevokedAt)
+	})
+
+	suite.Run("DuplicateHash", func() {
+		suite.SetupTest()
+		token := suite.addToken(domain.NewID(), suite.now)
+
+		duplicate := &domain.PersonalToken{UserID: domain.NewID(), TokenHash: token.TokenHash, CreatedAt: suite.now, ExpiresAt: suite.now.Add(time.Hour)}
+
+		suite.ErrorIs(suite.repo.CreatePersonalToken(suite.ctx, duplicate), domain.ErrConflict)
+	})
+
+	suite.Run("Missing", func() {
+		suite.SetupTest()
+
+		_, err := suite.repo.FindPersonalToken(suite.ctx, domain.NewID())
+		suite.ErrorIs(err, domain.ErrNotFound)
+		_, err = suite.repo.FindPersonalToken(suite.ctx, "not-an-id")
+		suite.ErrorIs(err, domain.ErrValidation)
+	})
+}
+
+func (suite *PersonalTokenRepoContract) TestFindPersonalTokenByHash() {
+	token := suite.addToken(domain.NewID(), suite.now)
+
+	found, err := suite.repo.FindPersonalTokenByHash(suite.ctx, token.TokenHash)
+
+	suite.NoError(err)
+	suite.Equal(token.ID, found.ID)
+	_, err = suite.repo.FindPersonalTokenByHash(suite.ctx, "unknown-hash")
+	suite.ErrorIs(err, domain.ErrNotFound)
+}
+
+func (suite *PersonalTokenRepoContract) TestListPersonalTokens() {
+	userID := domain.NewID()
+	older := suite.addToken(userID, suite.now.Add(-time.Hour))
+	newer := suite.addToken(userID, suite.now)
+	revoked := suite.addToken(userID, suite.now)
+	suite.addToken(domain.NewID(), suite.now)
+	suite.Require().NoError(suite.repo.RevokePersonalToken(suite.ctx, revoked.ID, suite.now))
+
+	tokens, err := suite.repo.ListPersonalTokens(suite.ctx, userID)
+
+	suite.NoError(err)
+	suite.Require().Len(tokens, 2)
+	suite.Equal(newer.ID, tokens[0].ID)
+	suite.Equal(older.ID, tokens[1].ID)
+
+	tokens, err = suite.repo.ListPersonalTokens(suite.ctx, domain.NewID())
+	suite.NoError(err)
+	suite.NotNil(tokens)
+	suite.Empty(tokens)
+}
+
+func (suite *PersonalTokenRepoContract) TestTouchPersonalToken() {
+	token := suite.addToken(domain.NewID(), suite.now)
+
+	suite.NoError(suite.repo.TouchPersonalToken(suite.ctx, token.ID, suite.now.Add(time.Minute)))
+	suite.Require().NotNil(suite.find(token.ID).LastUsedAt)
+	suite.True(suite.now.Add(time.Minute).Equal(*suite.find(token.ID).LastUsedAt))
+
+	//a late update never moves the time back
+	suite.NoError(suite.repo.TouchPersonalToken(suite.ctx, token.ID, suite.now))
+	suite.True(suite.now.Add(time.Minute).Equal(*suite.find(token.ID).LastUsedAt))
+
+	suite.ErrorIs(suite.repo.TouchPersonalToken(suite.ctx, domain.NewID(), suite.now), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.TouchPersonalToken(suite.ctx, "not-an-id", suite.now), domain.ErrValidation)
+}
+
+func (suite *PersonalTokenRepoContract) TestRevokePersonalToken() {
+	token := suite.addToken(domain.NewID(), suite.now)
+
+	suite.NoError(suite.repo.RevokePersonalToken(suite.ctx, token.ID, suite.now))
+
+	found := suite.find(token.ID)
+	suite.Require().NotNil(found.RevokedAt)
+	suite.True(suite.now.Equal(*found.RevokedAt))
+	suite.False(found.IsActive(suite.now))
+
+	//revoking again keeps the first revocation time
+	suite.NoError(suite.repo.RevokePersonalToken(suite.ctx, token.ID, suite.now.Add(time.Minute)))
+	suite.True(suite.now.Equal(*suite.find(token.ID).RevokedAt))
+
+	suite.ErrorIs(suite.repo.RevokePersonalToken(suite.ctx, domain.NewID(), suite.now), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.RevokePersonalToken(suite.ctx, "not-an-id", suite.now), domain.ErrValidation)
+}
+
+func (suite *PersonalTokenRepoContract) TestRevokeUserPersonalTokens() {
+	userID := domain.NewID()
+	first := suite.addToken(userID, suite.now)
+	second := suite.addToken(userID, suite.now)
+	other := suite.addToken(domain.NewID(), suite.now)
+
+	suite.NoError(suite.repo.RevokeUserPersonalTokens(suite.ctx, userID, suite.now))
+
+	suite.NotNil(suite.find(first.ID).RevokedAt)
+	suite.NotNil(suite.find(second.ID).RevokedAt)
+	suite.Nil(suite.find(other.ID).RevokedAt)
+	tokens, _ := suite.repo.ListPersonalTokens(suite.ctx, userID)
+	suite.Empty(tokens)
+}
+
+func (suite *PersonalTokenRepoContract) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	err := suite.repo.CreatePersonalToken(ctx, &domain.PersonalToken{UserID: domain.NewID(), TokenHash: "hash", ExpiresAt: suite.now.Add(time.Hour)})
+	suite.ErrorIs(err, context.Canceled)
+	_, err = suite.repo.ListPersonalTokens(ctx, domain.NewID())
+	suite.ErrorIs(err, context.Canceled)
+}
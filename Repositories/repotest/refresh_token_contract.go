@@ -0,0 +1,172 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// RefreshTokenRepoContract is the behaviour expected from an IRefreshTokenRepo
+type RefreshTokenRepoContract struct {
+	suite.Suite
+	// NewRepo returns an empty repository, it is called before every test
+	NewRepo func(t *testing.T) usecases.IRefreshTokenRepo
+
+	repo usecases.IRefreshTokenRepo
+	ctx  context.Context
+	now  time.Time
+}
+
+func (suite *RefreshTokenRepoContract) SetupTest() {
+	suite.repo = suite.NewRepo(suite.T())
+	suite.ctx = context.Background()
+	suite.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+}
+
+// addToken stores a token of the family and returns it
+func (suite *RefreshTokenRepoContract) addToken(familyID, hash string) *domain.RefreshToken {
+	token := &domain.RefreshToken{
+		UserID:    domain.NewID(),
+		FamilyID:  familyID,
+		TokenHash: hash,
+		CreatedAt: suite.now,
+		ExpiresAt: suite.now.Add(24 * time.Hour),
+	}
+	suite.Require().NoError(suite.repo.CreateToken(suite.ctx, token))
+	return token
+}
+
+func (suite *RefreshTokenRepoContract) TestCreateToken() {
+	suite.Run("Success", func() {
+		suite.SetupTest()
+		token := suite.addToken(domain.NewID(), "hash")
+
+		suite.True(domain.IsValidID(token.ID))
+		found, err := suite.repo.FindByHash(suite.ctx, "hash")
+		suite.NoError(err)
+		suite.Equal(token.ID, found.ID)
+		suite.Equal(token.UserID, found.UserID)
+		suite.Equal(token.FamilyID, found.FamilyID)
+		suite.True(token.CreatedAt.Equal(found.CreatedAt))
+		suite.True(token.ExpiresAt.Equal(found.ExpiresAt))
+		suite.Nil(found.UsedAt)
+		suite.Nil(found.RevokedAt)
+	})
+
+	suite.Run("DuplicateHash", func() {
+		suite.SetupTest()
+		suite.addToken(domain.NewID(), "hash")
+
+		err := suite.repo.CreateToken(suite.ctx, &domain.RefreshToken{FamilyID: domain.NewID(), TokenHash: "hash", ExpiresAt: suite.now})
+
+		suite.ErrorIs(err, domain.ErrConflict)
+	})
+
+	suite.Run("NotFound", func() {
+		suite.SetupTest()
+
+		_, err := suite.repo.FindByHash(suite.ctx, "unknown")
+
+		suite.ErrorIs(err, domain.ErrNotFound)
+	})
+}
+
+func (suite *RefreshTokenRepoContract) TestMarkUsed() {
+	suite.Run("Once", func() {
+		suite.SetupTest()
+		token := suite.addToken(domain.NewID(), "hash")
+
+		suite.NoError(suite.repo.MarkUsed(suite.ctx, token.ID, suite.now))
+
+		found, _ := suite.repo.FindByHash(suite.ctx, "hash")
+		suite.Require().NotNil(found.UsedAt)
+		suite.True(suite.now.Equal(*found.UsedAt))
+		err := suite.repo.MarkUsed(suite.ctx, token.ID, suite.now.Add(time.Minute))
+		suite.ErrorIs(err, domain.ErrConflict)
+		found, _ = suite.repo.FindByHash(suite.ctx, "hash")
+		suite.True(suite.now.Equal(*found.UsedAt))
+	})
+
+	suite.Run("Concurrent", func() {
+		suite.SetupTest()
+		token := suite.addToken(domain.NewID(), "hash")
+
+		//only one of the refreshes racing with the same token may win
+		errs := concurrently(10, func(int) error {
+			return suite.repo.MarkUsed(suite.ctx, token.ID, suite.now)
+		})
+
+		used := 0
+		for _, err := range errs {
+			if err == nil {
+				used++
+			} else {
+				suite.ErrorIs(err, domain.ErrConflict)
+			}
+		}
+		suite.Equal(1, used)
+	})
+
+	suite.Run("Missing", func() {
+		suite.SetupTest()
+
+		suite.ErrorIs(suite.repo.MarkUsed(suite.ctx, domain.NewID(), suite.now), domain.ErrNotFound)
+		suite.ErrorIs(suite.repo.MarkUsed(suite.ctx, "not-an-id", suite.now), domain.ErrValidation)
+	})
+}
+
+func (suite *RefreshTokenRepoContract) TestRevokeFamily() {
+	familyID := domain.NewID()
+	first := suite.addToken(familyID, "first")
+	suite.addToken(familyID, "second")
+	suite.addToken(domain.NewID(), "other")
+	suite.Require().NoError(suite.repo.MarkUsed(suite.ctx, first.ID, suite.now))
+
+	suite.NoError(suite.repo.RevokeFamily(suite.ctx, familyID, suite.now))
+
+	for _, hash := range []string{"first", "second"} {
+		found, err := suite.repo.FindByHash(suite.ctx, hash)
+		suite.NoError(err)
+		suite.Require().NotNil(found.RevokedAt, hash)
+		suite.True(suite.now.Equal(*found.RevokedAt))
+	}
+	other, _ := suite.repo.FindByHash(suite.ctx, "other")
+	suite.Nil(other.RevokedAt)
+
+	//revoking again keeps the first revocation time
+	suite.NoError(suite.repo.RevokeFamily(suite.ctx, familyID, suite.now.Add(time.Hour)))
+	found, _ := suite.repo.FindByHash(suite.ctx, "second")
+	suite.True(suite.now.Equal(*found.RevokedAt))
+	suite.NoError(suite.repo.RevokeFamily(suite.ctx, domain.NewID(), suite.now))
+}
+
+func (suite *RefreshTokenRepoContract) TestRevokeUserTokens() {
+	alice := suite.addToken(domain.NewID(), "alice-1")
+	second := &domain.RefreshToken{UserID: alice.UserID, FamilyID: domain.NewID(), TokenHash: "alice-2", CreatedAt: suite.now, ExpiresAt: suite.now.Add(time.Hour)}
+	suite.Require().NoError(suite.repo.CreateToken(suite.ctx, second))
+	suite.addToken(domain.NewID(), "bob")
+
+	suite.NoError(suite.repo.RevokeUserTokens(suite.ctx, alice.UserID, suite.now))
+
+	for _, hash := range []string{"alice-1", "alice-2"} {
+		found, _ := suite.repo.FindByHash(suite.ctx, hash)
+		suite.NotNil(found.RevokedAt, hash)
+	}
+	bob, _ := suite.repo.FindByHash(suite.ctx, "bob")
+	suite.Nil(bob.RevokedAt)
+}
+
+func (suite *RefreshTokenRepoContract) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	err := suite.repo.CreateToken(ctx, &domain.RefreshToken{FamilyID: domain.NewID(), TokenHash: "hash", ExpiresAt: suite.now})
+	suite.ErrorIs(err, context.Canceled)
+	_, err = suite.repo.FindByHash(ctx, "hash")
+	suite.ErrorIs(err, context.Canceled)
+}
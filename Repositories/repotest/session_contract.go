@@ -0,0 +1,178 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// SessionRepoContract is the behaviour expected from an ISessionRepo
+type SessionRepoContract struct {
+	suite.Suite
+	// NewRepo returns an empty repository, it is called before every test
+	NewRepo func(t *testing.T) usecases.ISessionRepo
+
+	repo usecases.ISessionRepo
+	ctx  context.Context
+	now  time.Time
+}
+
+func (suite *SessionRepoContract) SetupTest() {
+	suite.repo = suite.NewRepo(suite.T())
+	suite.ctx = context.Background()
+	//the mongo TTL index removes expired sessions, keep them in the future
+	suite.now = time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// addSession stores a session of the user last seen at seenAt and returns it
+func (suite *SessionRepoContract) addSession(userID string, seenAt time.Time) *domain.Session {
+	session := &domain.Session{
+		UserID:     userID,
+		UserAgent:  "curl/8.0",
+		IP:         "192.0.2.1",
+		CreatedAt:  seenAt,
+		LastSeenAt: seenAt,
+		ExpiresAt:  suite.now.Add(time.Hour),
+	}
+	suite.Require().NoError(suite.repo.CreateSession(suite.ctx, session))
+	return session
+}
+
+// find reads a session back and fails the test when it is missing
+func (suite *SessionRepoContract) find(sessionID string) *domain.Session {
+	session, err := suite.repo.FindSession(suite.ctx, sessionID)
+	suite.Require().NoError(err)
+	return session
+}
+
+func (suite *SessionRepoContract) TestCreateSession() {
+	suite.Run("Success", func() {
+		suite.SetupTest()
+		session := suite.addSession(domain.NewID(), suite.now)
+
+		suite.True(domain.IsValidID(session.ID))
+		found := suite.find(session.ID)
+		suite.Equal(session.UserID, found.UserID)
+		suite.Equal("curl/8.0", found.UserAgent)
+		suite.Equal("192.0.2.1", found.IP)
+		suite.True(session.CreatedAt.Equal(found.CreatedAt))
+		suite.True(session.LastSeenAt.Equal(found.LastSeenAt))
+		suite.True(session.ExpiresAt.Equal(found.ExpiresAt))
+		suite.Nil(found.RevokedAt)
+	})
+
+	suite.Run("GivenID", func() {
+		suite.SetupTest()
+		session := &domain.Session{ID: domain.NewID(), UserID: domain.NewID(), CreatedAt: suite.now, LastSeenAt: suite.now, ExpiresAt: suite.now.Add(time.Hour)}
+
+		suite.NoError(suite.repo.CreateSession(suite.ctx, session))
+
+		suite.Equal(session.UserID, suite.find(session.ID).UserID)
+		duplicate := *session
+		suite.ErrorIs(suite.repo.CreateSession(suite.ctx, &duplicate), domain.ErrConflict)
+	})
+
+	suite.Run("Missing", func() {
+		suite.SetupTest()
+
+		_, err := suite.repo.FindSession(suite.ctx, domain.NewID())
+		suite.ErrorIs(err, domain.ErrNotFound)
+		_, err = suite.repo.FindSession(suite.ctx, "not-an-id")
+		suite.ErrorIs(err, domain.ErrValidation)
+	})
+}
+
+func (suite *SessionRepoContract) TestListSessions() {
+	userID := domain.NewID()
+	older := suite.addSession(userID, suite.now.Add(-time.Hour))
+	newer := suite.addSession(userID, suite.now)
+	revoked := suite.addSession(userID, suite.now)
+	suite.addSession(domain.NewID(), suite.now)
+	suite.Require().NoError(suite.repo.RevokeSession(suite.ctx, revoked.ID, suite.now))
+
+	sessions, err := suite.repo.ListSessions(suite.ctx, userID)
+
+	suite.NoError(err)
+	suite.Require().Len(sessions, 2)
+	suite.Equal(newer.ID, sessions[0].ID)
+	suite.Equal(older.ID, sessions[1].ID)
+
+	sessions, err = suite.repo.ListSessions(suite.ctx, domain.NewID())
+	suite.NoError(err)
+	suite.NotNil(sessions)
+	suite.Empty(sessions)
+}
+
+func (suite *SessionRepoContract) TestTouchSession() {
+	session := suite.addSession(domain.NewID(), suite.now)
+
+	suite.NoError(suite.repo.TouchSession(suite.ctx, session.ID, suite.now.Add(time.Minute)))
+	suite.True(suite.now.Add(time.Minute).Equal(suite.find(session.ID).LastSeenAt))
+
+	//a late update never moves the time back
+	suite.NoError(suite.repo.TouchSession(suite.ctx, session.ID, suite.now))
+	suite.True(suite.now.Add(time.Minute).Equal(suite.find(session.ID).LastSeenAt))
+
+	suite.ErrorIs(suite.repo.TouchSession(suite.ctx, domain.NewID(), suite.now), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.TouchSession(suite.ctx, "not-an-id", suite.now), domain.ErrValidation)
+}
+
+func (suite *SessionRepoContract) TestExtendSession() {
+	session := suite.addSession(domain.NewID(), suite.now)
+	expiresAt := suite.now.Add(48 * time.Hour)
+
+	suite.NoError(suite.repo.ExtendSession(suite.ctx, session.ID, suite.now.Add(time.Minute), expiresAt))
+
+	found := suite.find(session.ID)
+	suite.True(suite.now.Add(time.Minute).Equal(found.LastSeenAt))
+	suite.True(expiresAt.Equal(found.ExpiresAt))
+	suite.ErrorIs(suite.repo.ExtendSession(suite.ctx, domain.NewID(), suite.now, expiresAt), domain.ErrNotFound)
+}
+
+func (suite *SessionRepoContract) TestRevokeSession() {
+	session := suite.addSession(domain.NewID(), suite.now)
+
+	suite.NoError(suite.repo.RevokeSession(suite.ctx, session.ID, suite.now))
+
+	found := suite.find(session.ID)
+	suite.Require().NotNil(found.RevokedAt)
+	suite.True(suite.now.Equal(*found.RevokedAt))
+	suite.False(found.IsActive(suite.now))
+
+	//revoking again keeps the first revocation time
+	suite.NoError(suite.repo.RevokeSession(suite.ctx, session.ID, suite.now.Add(time.Minute)))
+	suite.True(suite.now.Equal(*suite.find(session.ID).RevokedAt))
+
+	suite.ErrorIs(suite.repo.RevokeSession(suite.ctx, domain.NewID(), suite.now), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.RevokeSession(suite.ctx, "not-an-id", suite.now), domain.ErrValidation)
+}
+
+func (suite *SessionRepoContract) TestRevokeUserSessions() {
+	userID := domain.NewID()
+	first := suite.addSession(userID, suite.now)
+	second := suite.addSession(userID, suite.now)
+	other := suite.addSession(domain.NewID(), suite.now)
+
+	suite.NoError(suite.repo.RevokeUserSessions(suite.ctx, userID, suite.now))
+
+	suite.NotNil(suite.find(first.ID).RevokedAt)
+	suite.NotNil(suite.find(second.ID).RevokedAt)
+	suite.Nil(suite.find(other.ID).RevokedAt)
+	sessions, _ := suite.repo.ListSessions(suite.ctx, userID)
+	suite.Empty(sessions)
+}
+
+func (suite *SessionRepoContract) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	err := suite.repo.CreateSession(ctx, &domain.Session{UserID: domain.NewID(), ExpiresAt: suite.now.Add(time.Hour)})
+	suite.ErrorIs(err, context.Canceled)
+	_, err = suite.repo.ListSessions(ctx, domain.NewID())
+	suite.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,495 @@
+// Package repotest holds the behaviour every repository implementation must have,
+// written as testify suites. each storage backend runs the suites against its own
+// repositories, so all of them are held to the same contract
+package repotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TaskRepoContract is the behaviour expected from an ITaskRepo
+type TaskRepoContract struct {
+	suite.Suite
+	// NewRepo returns an empty repository, it is called before every test
+	NewRepo func(t *testing.T) usecases.ITaskRepo
+
+	repo usecases.ITaskRepo
+	ctx  context.Context
+}
+
+func (suite *TaskRepoContract) SetupTest() {
+	suite.repo = suite.NewRepo(suite.T())
+	suite.ctx = context.Background()
+}
+
+// times are whole milliseconds in UTC, the precision every backend keeps
+var (
+	now      = time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	tomorrow = now.AddDate(0, 0, 1)
+	nextWeek = now.AddDate(0, 0, 7)
+)
+
+// addTask stores a task at version 1 and returns it
+func (suite *TaskRepoContract) addTask(title string, ownerID string, status domain.TaskStatus, dueDate *time.Time) *domain.Task {
+	task := &domain.Task{
+		ID:        domain.NewID(),
+		Title:     title,
+		OwnerID:   ownerID,
+		Status:    status,
+		DueDate:   dueDate,
+		Assignees: []string{},
+		Version:   1,
+	}
+	suite.Require().NoError(suite.repo.CreateTask(suite.ctx, task))
+	return task
+}
+
+func (suite *TaskRepoContract) get(taskID string) *domain.Task {
+	task, err := suite.repo.GetTaskByID(suite.ctx, taskID)
+	suite.Require().NoError(err)
+	return task
+}
+
+func (suite *TaskRepoContract) TestCreateAndGet() {
+	suite.Run("Success", func() {
+		suite.SetupTest()
+		task := &domain.Task{
+			ID:          domain.NewID(),
+			Title:       "Write docs",
+			Description: "the whole api",
+			DueDate:     &tomorrow,
+			Status:      domain.StatusInProgress,
+			OwnerID:     "owner",
+			Assignees:   []string{"bob", "alice"},
+			StatusHistory: []domain.StatusTransition{
+				{From: domain.StatusNotStarted, To: domain.StatusInProgress, ActorID: "owner", At: now},
+			},
+			Version: 1,
+		}
+		suite.Require().NoError(suite.repo.CreateTask(suite.ctx, task))
+
+		suite.Equal(task, suite.get(task.ID))
+	})
+
+	suite.Run("GeneratesID", func() {
+		suite.SetupTest()
+		task := &domain.Task{Title: "Write docs", OwnerID: "owner", Status: domain.StatusNotStarted, Assignees: []string{}, Version: 1}
+
+		suite.Require().NoError(suite.repo.CreateTask(suite.ctx, task))
+
+		suite.True(domain.IsValidID(task.ID))
+		suite.Equal("Write docs", suite.get(task.ID).Title)
+	})
+
+	suite.Run("Duplicate", func() {
+		suite.SetupTest()
+		task := suite.addTask("Write docs", "owner", domain.StatusNotStarted, nil)
+
+		err := suite.repo.CreateTask(suite.ctx, task)
+
+		suite.ErrorIs(err, domain.ErrConflict)
+	})
+
+	suite.Run("NotFound", func() {
+		suite.SetupTest()
+
+		_, err := suite.repo.GetTaskByID(suite.ctx, domain.NewID())
+
+		suite.ErrorIs(err, domain.ErrNotFound)
+		suite.EqualError(err, "task not found")
+	})
+
+	suite.Run("ReturnsCopies", func() {
+		suite.SetupTest()
+		task := suite.addTask("Write docs", "owner", domain.StatusNotStarted, nil)
+		task.Title = "changed outside"
+		stored := suite.get(task.ID)
+		stored.Assignees = append(stored.Assignees, "changed outside")
+
+		again := suite.get(task.ID)
+
+		suite.Equal("Write docs", again.Title)
+		suite.Empty(again.Assignees)
+	})
+}
+
+func (suite *TaskRepoContract) TestInvalidIDs() {
+	const invalid = "not-an-id"
+	transition := domain.StatusTransition{From: domain.StatusNotStarted, To: domain.StatusInProgress}
+
+	_, err := suite.repo.GetTaskByID(suite.ctx, invalid)
+	suite.ErrorIs(err, domain.ErrValidation)
+	suite.ErrorIs(suite.repo.CreateTask(suite.ctx, &domain.Task{ID: invalid}), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.UpdateTaskFields(suite.ctx, invalid, &domain.Task{}, []string{"title"}, 1), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.DeleteTaskByID(suite.ctx, invalid, 1), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.TrashTaskByID(suite.ctx, invalid, "me", now, 1), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.RestoreTaskByID(suite.ctx, invalid, 1), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.TransitionTaskStatus(suite.ctx, invalid, transition, 1), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.AddAssignee(suite.ctx, invalid, "bob"), domain.ErrValidation)
+	suite.ErrorIs(suite.repo.RemoveAssignee(suite.ctx, invalid, "bob"), domain.ErrValidation)
+}
+
+func (suite *TaskRepoContract) TestGetAllTasks() {
+	suite.Run("Filters", func() {
+		suite.SetupTest()
+		mine := suite.addTask("Buy milk", "me", domain.StatusNotStarted, &tomorrow)
+		suite.addTask("Buy bread", "other", domain.StatusCompleted, &tomorrow)
+		suite.addTask("Plan trip", "me", domain.StatusInProgress, &nextWeek)
+		suite.addTask("Buy a car", "me", domain.StatusNotStarted, nil)
+
+		page, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{
+			VisibleTo:     "me",
+			TitleContains: "BUY",
+			DueBefore:     &tomorrow,
+			ExcludeStatus: domain.StatusCompleted,
+		})
+
+		suite.NoError(err)
+		suite.Equal(int64(1), page.Total)
+		suite.Equal([]domain.Task{*mine}, page.Tasks)
+	})
+
+	suite.Run("Visibility", func() {
+		suite.SetupTest()
+		suite.addTask("Owned", "me", domain.StatusNotStarted, nil)
+		assigned := suite.addTask("Assigned", "other", domain.StatusNotStarted, nil)
+		suite.addTask("Hidden", "other", domain.StatusNotStarted, nil)
+		suite.Require().NoError(suite.repo.AddAssignee(suite.ctx, assigned.ID, "me"))
+
+		visible, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{VisibleTo: "me", SortBy: domain.SortByTitle})
+		suite.NoError(err)
+		suite.Equal([]string{"Assigned", "Owned"}, Titles(visible.Tasks))
+
+		byAssignee, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{AssigneeID: "me"})
+		suite.NoError(err)
+		suite.Equal([]string{"Assigned"}, Titles(byAssignee.Tasks))
+
+		byOwner, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{OwnerID: "other", Status: domain.StatusNotStarted, SortBy: domain.SortByTitle})
+		suite.NoError(err)
+		suite.Equal([]string{"Assigned", "Hidden"}, Titles(byOwner.Tasks))
+	})
+
+	suite.Run("TitleIsNotAPattern", func() {
+		suite.SetupTest()
+		suite.addTask("100% done", "me", domain.StatusNotStarted, nil)
+		suite.addTask("1000 things", "me", domain.StatusNotStarted, nil)
+
+		page, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{TitleContains: "0%"})
+
+		suite.NoError(err)
+		suite.Equal([]string{"100% done"}, Titles(page.Tasks))
+	})
+
+	suite.Run("Trash", func() {
+		suite.SetupTest()
+		trashed := suite.addTask("Old", "me", domain.StatusNotStarted, nil)
+		suite.addTask("Live", "me", domain.StatusNotStarted, nil)
+		suite.Require().NoError(suite.repo.TrashTaskByID(suite.ctx, trashed.ID, "me", now, 1))
+
+		live, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{})
+		suite.NoError(err)
+		suite.Equal(int64(1), live.Total)
+		suite.Equal("Live", live.Tasks[0].Title)
+
+		trash, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{Trashed: true})
+		suite.NoError(err)
+		suite.Equal(int64(1), trash.Total)
+		suite.Equal("Old", trash.Tasks[0].Title)
+	})
+
+	suite.Run("SortAndPages", func() {
+		suite.SetupTest()
+		suite.addTask("c", "me", domain.StatusNotStarted, &nextWeek)
+		suite.addTask("a", "me", domain.StatusNotStarted, nil)
+		suite.addTask("b", "me", domain.StatusNotStarted, &tomorrow)
+
+		first, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{SortBy: domain.SortByDueDate, Limit: 2})
+		suite.NoError(err)
+		suite.Equal(int64(3), first.Total)
+		suite.Equal([]string{"a", "b"}, Titles(first.Tasks))
+		suite.NotEmpty(first.NextCursor)
+
+		second, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{SortBy: domain.SortByDueDate, Limit: 2, Cursor: first.NextCursor})
+		suite.NoError(err)
+		suite.Equal([]string{"c"}, Titles(second.Tasks))
+		suite.Empty(second.NextCursor)
+
+		desc, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{SortBy: domain.SortByTitle, SortDesc: true})
+		suite.NoError(err)
+		suite.Equal([]string{"c", "b", "a"}, Titles(desc.Tasks))
+	})
+
+	suite.Run("Empty", func() {
+		suite.SetupTest()
+
+		page, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{})
+
+		suite.NoError(err)
+		suite.Equal(int64(0), page.Total)
+		suite.NotNil(page.Tasks)
+		suite.Empty(page.Tasks)
+	})
+
+	suite.Run("InvalidCursor", func() {
+		suite.SetupTest()
+
+		_, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{Cursor: "not a cursor"})
+
+		suite.ErrorIs(err, domain.ErrValidation)
+	})
+}
+
+func (suite *TaskRepoContract) TestVersionedWrites() {
+	suite.Run("UpdateFields", func() {
+		suite.SetupTest()
+		task := suite.addTask("Old", "me", domain.StatusNotStarted, &tomorrow)
+
+		err := suite.repo.UpdateTaskFields(suite.ctx, task.ID, &domain.Task{Title: "New", Description: "ignored"}, []string{"title", "dueDate"}, 1)
+		suite.NoError(err)
+
+		stored := suite.get(task.ID)
+		suite.Equal("New", stored.Title)
+		suite.Empty(stored.Description)
+		suite.Nil(stored.DueDate)
+		suite.Equal(int64(2), stored.Version)
+	})
+
+	suite.Run("StaleVersion", func() {
+		suite.SetupTest()
+		task := suite.addTask("Old", "me", domain.StatusNotStarted, nil)
+
+		suite.ErrorIs(suite.repo.UpdateTaskFields(suite.ctx, task.ID, &domain.Task{Title: "New"}, []string{"title"}, 3), domain.ErrVersionMismatch)
+		suite.ErrorIs(suite.repo.TrashTaskByID(suite.ctx, task.ID, "me", now, 3), domain.ErrVersionMismatch)
+
+		stored := suite.get(task.ID)
+		suite.Equal("Old", stored.Title)
+		suite.Equal(int64(1), stored.Version)
+	})
+
+	suite.Run("MissingTask", func() {
+		suite.SetupTest()
+
+		err := suite.repo.UpdateTaskFields(suite.ctx, domain.NewID(), &domain.Task{Title: "New"}, []string{"title"}, 1)
+
+		suite.ErrorIs(err, domain.ErrVersionMismatch)
+	})
+
+	suite.Run("UnknownField", func() {
+		suite.SetupTest()
+		task := suite.addTask("Old", "me", domain.StatusNotStarted, nil)
+
+		err := suite.repo.UpdateTaskFields(suite.ctx, task.ID, task, []string{"assignees"}, 1)
+
+		suite.ErrorIs(err, domain.ErrValidation)
+	})
+
+	suite.Run("UpdateFieldsWithStatus", func() {
+		suite.SetupTest()
+		task := suite.addTask("Old", "me", domain.StatusNotStarted, nil)
+		start := domain.StatusTransition{From: domain.StatusNotStarted, To: domain.StatusInProgress, ActorID: "me", At: now}
+		moved := &domain.Task{Title: "New", Status: domain.StatusInProgress, StatusHistory: []domain.StatusTransition{start}}
+
+		suite.NoError(suite.repo.UpdateTaskFields(suite.ctx, task.ID, moved, []string{"title", "status"}, 1))
+
+		stored := suite.get(task.ID)
+		suite.Equal("New", stored.Title)
+		suite.Equal(domain.StatusInProgress, stored.Status)
+		suite.Equal([]domain.StatusTransition{start}, stored.StatusHistory)
+		suite.Equal(int64(2), stored.Version)
+
+		//a stale write leaves the status and its history alone
+		finish := domain.StatusTransition{From: domain.StatusInProgress, To: domain.StatusCompleted, ActorID: "me", At: tomorrow}
+		moved.Status, moved.StatusHistory = domain.StatusCompleted, []domain.StatusTransition{start, finish}
+		suite.ErrorIs(suite.repo.UpdateTaskFields(suite.ctx, task.ID, moved, []string{"status"}, 1), domain.ErrVersionMismatch)
+		suite.Equal([]domain.StatusTransition{start}, suite.get(task.ID).StatusHistory)
+
+		//a status without the transition leading to it is refused
+		err := suite.repo.UpdateTaskFields(suite.ctx, task.ID, &domain.Task{Status: domain.StatusCompleted}, []string{"status"}, 2)
+		suite.ErrorIs(err, domain.ErrValidation)
+	})
+
+	suite.Run("UpdateOwner", func() {
+		suite.SetupTest()
+		task := suite.addTask("Old", "me", domain.StatusNotStarted, nil)
+
+		err := suite.repo.UpdateTaskFields(suite.ctx, task.ID, &domain.Task{OwnerID: "you"}, []string{"ownerId"}, 1)
+		suite.NoError(err)
+
+		stored := suite.get(task.ID)
+		suite.Equal("you", stored.OwnerID)
+		suite.Equal("Old", stored.Title)
+		suite.Equal(int64(2), stored.Version)
+	})
+
+	suite.Run("Transition", func() {
+		suite.SetupTest()
+		task := suite.addTask("Work", "me", domain.StatusNotStarted, nil)
+		start := domain.StatusTransition{From: domain.StatusNotStarted, To: domain.StatusInProgress, ActorID: "me", At: now}
+		finish := domain.StatusTransition{From: domain.StatusInProgress, To: domain.StatusCompleted, ActorID: "me", At: tomorrow}
+
+		suite.NoError(suite.repo.TransitionTaskStatus(suite.ctx, task.ID, start, 1))
+		//the task already left not-started
+		suite.ErrorIs(suite.repo.TransitionTaskStatus(suite.ctx, task.ID, start, 2), domain.ErrVersionMismatch)
+		suite.NoError(suite.repo.TransitionTaskStatus(suite.ctx, task.ID, finish, 2))
+
+		stored := suite.get(task.ID)
+		suite.Equal(domain.StatusCompleted, stored.Status)
+		suite.Equal([]domain.StatusTransition{start, finish}, stored.StatusHistory)
+		suite.Equal(int64(3), stored.Version)
+	})
+
+	suite.Run("TrashAndRestore", func() {
+		suite.SetupTest()
+		task := suite.addTask("Work", "me", domain.StatusNotStarted, nil)
+
+		suite.NoError(suite.repo.TrashTaskByID(suite.ctx, task.ID, "me", now, 1))
+		trashed := suite.get(task.ID)
+		suite.Equal(&now, trashed.DeletedAt)
+		suite.Equal("me", trashed.DeletedBy)
+
+		suite.NoError(suite.repo.RestoreTaskByID(suite.ctx, task.ID, 2))
+		restored := suite.get(task.ID)
+		suite.Nil(restored.DeletedAt)
+		suite.Empty(restored.DeletedBy)
+		suite.Equal(int64(3), restored.Version)
+	})
+
+	suite.Run("Delete", func() {
+		suite.SetupTest()
+		task := suite.addTask("Work", "me", domain.StatusNotStarted, nil)
+		suite.Require().NoError(suite.repo.AddAssignee(suite.ctx, task.ID, "bob"))
+
+		suite.ErrorIs(suite.repo.DeleteTaskByID(suite.ctx, task.ID, 1), domain.ErrVersionMismatch)
+		suite.NoError(suite.repo.DeleteTaskByID(suite.ctx, task.ID, 2))
+
+		_, err := suite.repo.GetTaskByID(suite.ctx, task.ID)
+		suite.ErrorIs(err, domain.ErrNotFound)
+		page, err := suite.repo.GetAllTasks(suite.ctx, domain.TaskFilter{AssigneeID: "bob"})
+		suite.NoError(err)
+		suite.Empty(page.Tasks)
+	})
+}
+
+func (suite *TaskRepoContract) TestAssignees() {
+	task := suite.addTask("Work", "me", domain.StatusNotStarted, nil)
+
+	suite.NoError(suite.repo.AddAssignee(suite.ctx, task.ID, "bob"))
+	suite.NoError(suite.repo.AddAssignee(suite.ctx, task.ID, "alice"))
+	suite.NoError(suite.repo.AddAssignee(suite.ctx, task.ID, "bob"))
+	stored := suite.get(task.ID)
+	suite.Equal([]string{"bob", "alice"}, stored.Assignees)
+	//every change bumps the version, so stale writers notice it
+	suite.Equal(int64(4), stored.Version)
+
+	suite.NoError(suite.repo.RemoveAssignee(suite.ctx, task.ID, "bob"))
+	suite.NoError(suite.repo.RemoveAssignee(suite.ctx, task.ID, "nobody"))
+	suite.Equal([]string{"alice"}, suite.get(task.ID).Assignees)
+
+	suite.ErrorIs(suite.repo.AddAssignee(suite.ctx, domain.NewID(), "bob"), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.RemoveAssignee(suite.ctx, domain.NewID(), "bob"), domain.ErrNotFound)
+}
+
+func (suite *TaskRepoContract) TestPurgeTrashedBefore() {
+	cutoff := now
+	expired := suite.addTask("Expired", "me", domain.StatusNotStarted, nil)
+	recent := suite.addTask("Recent", "me", domain.StatusNotStarted, nil)
+	live := suite.addTask("Live", "me", domain.StatusNotStarted, nil)
+	suite.Require().NoError(suite.repo.TrashTaskByID(suite.ctx, expired.ID, "me", cutoff.Add(-time.Hour), 1))
+	suite.Require().NoError(suite.repo.TrashTaskByID(suite.ctx, recent.ID, "me", cutoff.Add(time.Hour), 1))
+
+	purged, err := suite.repo.PurgeTrashedBefore(suite.ctx, cutoff)
+
+	suite.NoError(err)
+	suite.Require().Len(purged, 1)
+	suite.Equal(expired.ID, purged[0].ID)
+	suite.Equal("Expired", purged[0].Title)
+	suite.NotNil(purged[0].DeletedAt)
+	_, err = suite.repo.GetTaskByID(suite.ctx, expired.ID)
+	suite.ErrorIs(err, domain.ErrNotFound)
+	suite.Equal("Recent", suite.get(recent.ID).Title)
+	suite.Equal("Live", suite.get(live.ID).Title)
+}
+
+func (suite *TaskRepoContract) TestConcurrentWrites() {
+	suite.Run("SameVersion", func() {
+		suite.SetupTest()
+		task := suite.addTask("Work", "me", domain.StatusNotStarted, nil)
+
+		//every writer read version 1, only one of them may win
+		errs := concurrently(10, func(int) error {
+			return suite.repo.UpdateTaskFields(suite.ctx, task.ID, &domain.Task{Title: "New"}, []string{"title"}, 1)
+		})
+
+		succeeded := 0
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+			} else {
+				suite.ErrorIs(err, domain.ErrVersionMismatch)
+			}
+		}
+		suite.Equal(1, succeeded)
+		suite.Equal(int64(2), suite.get(task.ID).Version)
+	})
+
+	suite.Run("Assignees", func() {
+		suite.SetupTest()
+		task := suite.addTask("Work", "me", domain.StatusNotStarted, nil)
+		users := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+		//assigning does not take a version, no assignment may get lost
+		errs := concurrently(len(users), func(i int) error {
+			return suite.repo.AddAssignee(suite.ctx, task.ID, users[i])
+		})
+
+		for _, err := range errs {
+			suite.NoError(err)
+		}
+		stored := suite.get(task.ID)
+		suite.ElementsMatch(users, stored.Assignees)
+		suite.Equal(int64(1+len(users)), stored.Version)
+	})
+}
+
+func (suite *TaskRepoContract) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	_, err := suite.repo.GetAllTasks(ctx, domain.TaskFilter{})
+	suite.ErrorIs(err, context.Canceled)
+
+	err = suite.repo.CreateTask(ctx, &domain.Task{Title: "Work", Assignees: []string{}, Version: 1})
+	suite.ErrorIs(err, context.Canceled)
+}
+
+// Titles lists the titles of the tasks in order
+func Titles(tasks []domain.Task) []string {
+	result := []string{}
+	for _, task := range tasks {
+		result = append(result, task.Title)
+	}
+	return result
+}
+
+// concurrently runs n calls of fn at once and returns their errors
+func concurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,84 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TokenRevocationRepoContract is the behaviour expected from an ITokenRevocationRepo
+type TokenRevocationRepoContract struct {
+	suite.Suite
+	// NewRepo returns an empty repository, it is called before every test
+	NewRepo func(t *testing.T) usecases.ITokenRevocationRepo
+
+	repo usecases.ITokenRevocationRepo
+	ctx  context.Context
+	// now is the real time, stores may drop the entries that expired
+	now time.Time
+}
+
+func (suite *TokenRevocationRepoContract) SetupTest() {
+	suite.repo = suite.NewRepo(suite.T())
+	suite.ctx = context.Background()
+	suite.now = time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// isRevoked asks the repository and fails the test on errors
+func (suite *TokenRevocationRepoContract) isRevoked(tokenID, userID string, issuedAt time.Time) bool {
+	revoked, err := suite.repo.IsRevoked(suite.ctx, tokenID, userID, issuedAt)
+	suite.Require().NoError(err)
+	return revoked
+}
+
+func (suite *TokenRevocationRepoContract) TestRevokeToken() {
+	tokenID, userID := domain.NewID(), domain.NewID()
+	suite.False(suite.isRevoked(tokenID, userID, suite.now))
+
+	suite.NoError(suite.repo.RevokeToken(suite.ctx, tokenID, suite.now.Add(time.Hour)))
+
+	suite.True(suite.isRevoked(tokenID, userID, suite.now))
+	suite.False(suite.isRevoked(domain.NewID(), userID, suite.now))
+	//revoking twice is fine
+	suite.NoError(suite.repo.RevokeToken(suite.ctx, tokenID, suite.now.Add(time.Minute)))
+	suite.True(suite.isRevoked(tokenID, userID, suite.now))
+
+	suite.ErrorIs(suite.repo.RevokeToken(suite.ctx, "not-an-id", suite.now), domain.ErrValidation)
+}
+
+func (suite *TokenRevocationRepoContract) TestRevokeUserTokens() {
+	userID, otherID := domain.NewID(), domain.NewID()
+	cutoff := suite.now
+
+	suite.NoError(suite.repo.RevokeUserTokens(suite.ctx, userID, cutoff, cutoff.Add(time.Hour)))
+
+	suite.True(suite.isRevoked(domain.NewID(), userID, cutoff.Add(-time.Millisecond)))
+	suite.True(suite.isRevoked(domain.NewID(), userID, cutoff))
+	//tokens issued after the cutoff are valid
+	suite.False(suite.isRevoked(domain.NewID(), userID, cutoff.Add(time.Millisecond)))
+	suite.False(suite.isRevoked(domain.NewID(), userID, cutoff.Add(time.Second)))
+	suite.False(suite.isRevoked(domain.NewID(), otherID, cutoff.Add(-time.Second)))
+
+	//a later cutoff moves it forward, an earlier one is ignored
+	later := cutoff.Add(time.Minute)
+	suite.NoError(suite.repo.RevokeUserTokens(suite.ctx, userID, later, later.Add(time.Hour)))
+	suite.True(suite.isRevoked(domain.NewID(), userID, cutoff.Add(time.Second)))
+	suite.NoError(suite.repo.RevokeUserTokens(suite.ctx, userID, cutoff, cutoff.Add(time.Hour)))
+	suite.True(suite.isRevoked(domain.NewID(), userID, cutoff.Add(time.Second)))
+
+	suite.ErrorIs(suite.repo.RevokeUserTokens(suite.ctx, "not-an-id", cutoff, cutoff), domain.ErrValidation)
+}
+
+func (suite *TokenRevocationRepoContract) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	suite.ErrorIs(suite.repo.RevokeToken(ctx, domain.NewID(), suite.now.Add(time.Hour)), context.Canceled)
+	_, err := suite.repo.IsRevoked(ctx, domain.NewID(), domain.NewID(), suite.now)
+	suite.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,347 @@
+package repotest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// UserRepoContract is the behaviour expected from an IUserRepository
+type UserRepoContract struct {
+	suite.Suite
+	// NewRepo returns an empty repository, it is called before every test
+	NewRepo func(t *testing.T) usecases.IUserRepository
+
+	repo usecases.IUserRepository
+	ctx  context.Context
+}
+
+func (suite *UserRepoContract) SetupTest() {
+	suite.repo = suite.NewRepo(suite.T())
+	suite.ctx = context.Background()
+}
+
+func newUser(username string) *domain.User {
+	return &domain.User{Username: username, Password: "hash", Role: domain.RoleUser}
+}
+
+// addUser stores a user and returns it
+func (suite *UserRepoContract) addUser(username string) *domain.User {
+	user := newUser(username)
+	suite.Require().NoError(suite.repo.CreateUser(suite.ctx, user))
+	return user
+}
+
+func (suite *UserRepoContract) TestCreateUser() {
+	suite.Run("Success", func() {
+		suite.SetupTest()
+		user := newUser("alice")
+
+		err := suite.repo.CreateUser(suite.ctx, user)
+
+		suite.NoError(err)
+		suite.True(domain.IsValidID(user.ID))
+		found, err := suite.repo.FindByUsername(suite.ctx, "alice")
+		suite.NoError(err)
+		suite.Equal(user, found)
+	})
+
+	suite.Run("DuplicateUsername", func() {
+		suite.SetupTest()
+		suite.addUser("alice")
+		duplicate := newUser("alice")
+
+		err := suite.repo.CreateUser(suite.ctx, duplicate)
+
+		suite.ErrorIs(err, domain.ErrConflict)
+		suite.EqualError(err, "username already exists")
+		suite.Empty(duplicate.ID)
+		count, _ := suite.repo.CountAll(suite.ctx)
+		suite.Equal(int64(1), count)
+	})
+
+	suite.Run("ConcurrentDuplicates", func() {
+		suite.SetupTest()
+
+		//the username check must hold even when nobody checked first
+		errs := concurrently(10, func(int) error {
+			return suite.repo.CreateUser(suite.ctx, newUser("alice"))
+		})
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+			} else {
+				suite.ErrorIs(err, domain.ErrConflict)
+			}
+		}
+		suite.Equal(1, created)
+		count, _ := suite.repo.CountByUsername(suite.ctx, "alice")
+		suite.Equal(int64(1), count)
+	})
+}
+
+func (suite *UserRepoContract) TestFind() {
+	alice := suite.addUser("alice")
+	suite.addUser("bob")
+
+	found, err := suite.repo.FindByID(suite.ctx, alice.ID)
+	suite.NoError(err)
+	suite.Equal(alice, found)
+
+	found, err = suite.repo.FindByUsername(suite.ctx, "alice")
+	suite.NoError(err)
+	suite.Equal(alice, found)
+
+	_, err = suite.repo.FindByID(suite.ctx, domain.NewID())
+	suite.ErrorIs(err, domain.ErrNotFound)
+	suite.EqualError(err, "user not found")
+
+	_, err = suite.repo.FindByID(suite.ctx, "not-an-id")
+	suite.ErrorIs(err, domain.ErrValidation)
+
+	_, err = suite.repo.FindByUsername(suite.ctx, "carol")
+	suite.ErrorIs(err, domain.ErrNotFound)
+
+	//usernames are case sensitive
+	_, err = suite.repo.FindByUsername(suite.ctx, "Alice")
+	suite.ErrorIs(err, domain.ErrNotFound)
+}
+
+func (suite *UserRepoContract) TestCount() {
+	count, err := suite.repo.CountAll(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+
+	suite.addUser("alice")
+	suite.addUser("bob")
+
+	count, err = suite.repo.CountAll(suite.ctx)
+	suite.NoError(err)
+	suite.Equal(int64(2), count)
+
+	count, err = suite.repo.CountByUsername(suite.ctx, "alice")
+	suite.NoError(err)
+	suite.Equal(int64(1), count)
+
+	count, err = suite.repo.CountByUsername(suite.ctx, "carol")
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+}
+
+func (suite *UserRepoContract) TestUpdateRole() {
+	alice := suite.addUser("alice")
+	bob := suite.addUser("bob")
+
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleAdmin))
+
+	found, _ := suite.repo.FindByID(suite.ctx, alice.ID)
+	suite.Equal(domain.RoleAdmin, found.Role)
+	found, _ = suite.repo.FindByID(suite.ctx, bob.ID)
+	suite.Equal(domain.RoleUser, found.Role)
+
+	//another admin is left once alice is demoted
+	carol := suite.addUser("carol")
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, carol.ID, domain.RoleAdmin))
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleUser))
+	found, _ = suite.repo.FindByID(suite.ctx, alice.ID)
+	suite.Equal(domain.RoleUser, found.Role)
+
+	suite.ErrorIs(suite.repo.UpdateRole(suite.ctx, domain.NewID(), domain.RoleAdmin), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.UpdateRole(suite.ctx, "not-an-id", domain.RoleAdmin), domain.ErrValidation)
+}
+
+func (suite *UserRepoContract) TestCountByRole() {
+	alice := suite.addUser("alice")
+	bob := suite.addUser("bob")
+	suite.addUser("carol")
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleAdmin))
+
+	count, err := suite.repo.CountByRole(suite.ctx, domain.RoleAdmin)
+	suite.NoError(err)
+	suite.Equal(int64(1), count)
+
+	count, err = suite.repo.CountByRole(suite.ctx, domain.RoleUser)
+	suite.NoError(err)
+	suite.Equal(int64(2), count)
+
+	//deactivated users are not counted
+	now := time.Now()
+	suite.NoError(suite.repo.UpdateDeactivatedAt(suite.ctx, bob.ID, &now))
+	count, err = suite.repo.CountByRole(suite.ctx, domain.RoleUser)
+	suite.NoError(err)
+	suite.Equal(int64(1), count)
+}
+
+func (suite *UserRepoContract) TestListUsers() {
+	for _, name := range []string{"carol", "Alice", "bob", "alina"} {
+		suite.addUser(name)
+	}
+	bob, _ := suite.repo.FindByUsername(suite.ctx, "bob")
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, bob.ID, domain.RoleAdmin))
+
+	suite.Run("Paging", func() {
+		first, err := suite.repo.ListUsers(suite.ctx, domain.UserFilter{Limit: 3})
+		suite.NoError(err)
+		suite.Equal(int64(4), first.Total)
+		suite.Equal([]string{"Alice", "alina", "bob"}, usernames(first.Users))
+		suite.NotEmpty(first.NextCursor)
+
+		second, err := suite.repo.ListUsers(suite.ctx, domain.UserFilter{Limit: 3, Cursor: first.NextCursor})
+		suite.NoError(err)
+		suite.Equal([]string{"carol"}, usernames(second.Users))
+		suite.Empty(second.NextCursor)
+	})
+
+	suite.Run("Search", func() {
+		page, err := suite.repo.ListUsers(suite.ctx, domain.UserFilter{Search: "AL"})
+		suite.NoError(err)
+		suite.Equal(int64(2), page.Total)
+		suite.Equal([]string{"Alice", "alina"}, usernames(page.Users))
+
+		//wildcards match themselves
+		page, err = suite.repo.ListUsers(suite.ctx, domain.UserFilter{Search: "a%"})
+		suite.NoError(err)
+		suite.Empty(page.Users)
+	})
+
+	suite.Run("Role", func() {
+		page, err := suite.repo.ListUsers(suite.ctx, domain.UserFilter{Role: domain.RoleAdmin})
+		suite.NoError(err)
+		suite.Equal([]string{"bob"}, usernames(page.Users))
+	})
+
+	suite.Run("NoPassword", func() {
+		page, err := suite.repo.ListUsers(suite.ctx, domain.UserFilter{Search: "bob"})
+		suite.NoError(err)
+		suite.Require().Len(page.Users, 1)
+		suite.Equal(bob.ID, page.Users[0].ID)
+		suite.Equal(domain.RoleAdmin, page.Users[0].Role)
+	})
+}
+
+// usernames lists the usernames of the users in order
+func usernames(users []domain.User) []string {
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+func (suite *UserRepoContract) TestUpdateDeactivatedAt() {
+	alice := suite.addUser("alice")
+	at := time.Date(2025, 1, 10, 9, 30, 0, 0, time.UTC)
+
+	suite.NoError(suite.repo.UpdateDeactivatedAt(suite.ctx, alice.ID, &at))
+
+	found, err := suite.repo.FindByID(suite.ctx, alice.ID)
+	suite.NoError(err)
+	suite.False(found.IsActive())
+	suite.True(at.Equal(*found.DeactivatedAt))
+	page, _ := suite.repo.ListUsers(suite.ctx, domain.UserFilter{})
+	suite.False(page.Users[0].IsActive())
+
+	suite.NoError(suite.repo.UpdateDeactivatedAt(suite.ctx, alice.ID, nil))
+	found, _ = suite.repo.FindByUsername(suite.ctx, "alice")
+	suite.True(found.IsActive())
+
+	suite.ErrorIs(suite.repo.UpdateDeactivatedAt(suite.ctx, domain.NewID(), &at), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.UpdateDeactivatedAt(suite.ctx, "not-an-id", &at), domain.ErrValidation)
+}
+
+func (suite *UserRepoContract) TestDeleteUser() {
+	alice := suite.addUser("alice")
+	bob := suite.addUser("bob")
+
+	suite.NoError(suite.repo.DeleteUser(suite.ctx, alice.ID))
+
+	_, err := suite.repo.FindByID(suite.ctx, alice.ID)
+	suite.ErrorIs(err, domain.ErrNotFound)
+	_, err = suite.repo.FindByID(suite.ctx, bob.ID)
+	suite.NoError(err)
+	//the username is free again
+	suite.NoError(suite.repo.CreateUser(suite.ctx, newUser("alice")))
+
+	suite.ErrorIs(suite.repo.DeleteUser(suite.ctx, alice.ID), domain.ErrNotFound)
+	suite.ErrorIs(suite.repo.DeleteUser(suite.ctx, "not-an-id"), domain.ErrValidation)
+}
+
+func (suite *UserRepoContract) TestLastAdmin() {
+	alice := suite.addUser("alice")
+	bob := suite.addUser("bob")
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleAdmin))
+	now := time.Now()
+
+	//the only active admin keeps the role and the account
+	suite.ErrorIs(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleUser), domain.ErrLastAdmin)
+	suite.ErrorIs(suite.repo.UpdateDeactivatedAt(suite.ctx, alice.ID, &now), domain.ErrLastAdmin)
+	suite.ErrorIs(suite.repo.DeleteUser(suite.ctx, alice.ID), domain.ErrLastAdmin)
+	found, err := suite.repo.FindByID(suite.ctx, alice.ID)
+	suite.Require().NoError(err)
+	suite.Equal(domain.RoleAdmin, found.Role)
+	suite.True(found.IsActive())
+
+	//promoting the admin again is not a demotion
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleAdmin))
+
+	//a deactivated admin does not count
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, bob.ID, domain.RoleAdmin))
+	suite.NoError(suite.repo.UpdateDeactivatedAt(suite.ctx, bob.ID, &now))
+	suite.ErrorIs(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleUser), domain.ErrLastAdmin)
+	suite.NoError(suite.repo.DeleteUser(suite.ctx, bob.ID))
+
+	//with another active admin the change goes through
+	carol := suite.addUser("carol")
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, carol.ID, domain.RoleAdmin))
+	suite.NoError(suite.repo.UpdateDeactivatedAt(suite.ctx, alice.ID, &now))
+	suite.ErrorIs(suite.repo.DeleteUser(suite.ctx, carol.ID), domain.ErrLastAdmin)
+}
+
+func (suite *UserRepoContract) TestLastAdminConcurrentDemotions() {
+	alice := suite.addUser("alice")
+	bob := suite.addUser("bob")
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, alice.ID, domain.RoleAdmin))
+	suite.NoError(suite.repo.UpdateRole(suite.ctx, bob.ID, domain.RoleAdmin))
+
+	//both admins are demoted at once, only one of the demotions may go through
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for _, id := range []string{alice.ID, bob.ID} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- suite.repo.UpdateRole(suite.ctx, id, domain.RoleUser)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	refused := 0
+	for err := range errs {
+		if err != nil {
+			suite.ErrorIs(err, domain.ErrLastAdmin)
+			refused++
+		}
+	}
+	suite.Equal(1, refused)
+	count, err := suite.repo.CountByRole(suite.ctx, domain.RoleAdmin)
+	suite.NoError(err)
+	suite.Equal(int64(1), count)
+}
+
+func (suite *UserRepoContract) TestCancelledContext() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	suite.ErrorIs(suite.repo.CreateUser(ctx, newUser("alice")), context.Canceled)
+	_, err := suite.repo.FindByUsername(ctx, "alice")
+	suite.ErrorIs(err, context.Canceled)
+}
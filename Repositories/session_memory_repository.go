@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// MemorySessionRepository keeps the sessions in memory, it is safe for concurrent use
+type MemorySessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.Session
+}
+
+func NewMemorySessionRepository() usecases.ISessionRepo {
+	return &MemorySessionRepository{sessions: map[string]*domain.Session{}}
+}
+
+// stores a new session, an empty ID is filled in
+func (r *MemorySessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if session.ID == "" {
+		session.ID = domain.NewID()
+	} else if !domain.IsValidID(session.ID) {
+		return domain.Validation("invalid session ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[session.ID]; ok {
+		return domain.Conflict("session already exists")
+	}
+	r.sessions[session.ID] = cloneSession(session)
+	return nil
+}
+
+// retrieves a session by its ID
+func (r *MemorySessionRepository) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !domain.IsValidID(sessionID) {
+		return nil, domain.Validation("invalid session ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return nil, domain.NotFound("session not found")
+	}
+	return cloneSession(session), nil
+}
+
+// lists the sessions of the user that are not revoked, last seen first
+func (r *MemorySessionRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions := []domain.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, *cloneSession(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// moves the last seen time of the session forward
+func (r *MemorySessionRepository) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	return r.update(ctx, sessionID, func(session *domain.Session) {
+		if seenAt.After(session.LastSeenAt) {
+			session.LastSeenAt = seenAt
+		}
+	})
+}
+
+// touches the session and moves its expiry
+func (r *MemorySessionRepository) ExtendSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	return r.update(ctx, sessionID, func(session *domain.Session) {
+		if seenAt.After(session.LastSeenAt) {
+			session.LastSeenAt = seenAt
+		}
+		session.ExpiresAt = expiresAt
+	})
+}
+
+// revokes the session, a revoked session keeps its first revocation time
+func (r *MemorySessionRepository) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	return r.update(ctx, sessionID, func(session *domain.Session) {
+		if session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	})
+}
+
+// revokes every session of the user that is not revoked yet
+func (r *MemorySessionRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			revokedAt := at
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) update(ctx context.Context, sessionID string, change func(session *domain.Session)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(sessionID) {
+		return domain.Validation("invalid session ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return domain.NotFound("session not found")
+	}
+	change(session)
+	return nil
+}
+
+// cloneSession copies a session so callers never share memory with the stored one
+func cloneSession(session *domain.Session) *domain.Session {
+	c := *session
+	c.RevokedAt = copyTime(session.RevokedAt)
+	c.Current = false
+	return &c
+}
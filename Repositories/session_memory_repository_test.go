@@ -0,0 +1,17 @@
+package repositories_test
+
+import (
+	"testing"
+
+	repositories "task_management/Repositories"
+	"task_management/Repositories/repotest"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMemorySessionRepositorySuite(t *testing.T) {
+	suite.Run(t, &repotest.SessionRepoContract{
+		NewRepo: func(*testing.T) usecases.ISessionRepo { return repositories.NewMemorySessionRepository() },
+	})
+}
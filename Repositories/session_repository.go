@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/db"
+	"task_management/usecases"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ISessionMongoCollection interface {
+	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+}
+
+// sessionDocument is a session as stored in mongo, the id is kept as an object id
+type sessionDocument struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	domain.Session `bson:",inline"`
+}
+
+func (d *sessionDocument) session() domain.Session {
+	session := d.Session
+	session.ID = d.ID.Hex()
+	return session
+}
+
+// SessionRepository keeps the sessions in mongo, expired sessions are removed by
+// the TTL index db.Connect creates
+type SessionRepository struct {
+	Collection ISessionMongoCollection
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewSessionRepository(timeout time.Duration) usecases.ISessionRepo {
+	return &SessionRepository{
+		Collection: db.GetSessionsCollection(),
+		Timeout:    timeout,
+	}
+}
+
+// stores a new session, an empty ID is filled in
+func (r *SessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	if session.ID == "" {
+		session.ID = domain.NewID()
+	}
+	objID, err := primitive.ObjectIDFromHex(session.ID)
+	if err != nil {
+		return domain.Validation("invalid session ID")
+	}
+	_, err = r.Collection.InsertOne(ctx, &sessionDocument{ID: objID, Session: *session})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Conflict("session already exists")
+	}
+	return err
+}
+
+// retrieves a session by its ID
+func (r *SessionRepository) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, domain.Validation("invalid session ID")
+	}
+
+	var doc sessionDocument
+	err = r.Collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.NotFound("session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	session := doc.session()
+	return &session, nil
+}
+
+// lists the sessions of the user that are not revoked, last seen first
+func (r *SessionRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}, {Key: "_id", Value: 1}})
+	cur, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var docs []sessionDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	sessions := make([]domain.Session, 0, len(docs))
+	for i := range docs {
+		sessions = append(sessions, docs[i].session())
+	}
+	return sessions, nil
+}
+
+// moves the last seen time of the session forward
+func (r *SessionRepository) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	return r.update(ctx, sessionID, bson.M{"$max": bson.M{"lastSeenAt": seenAt}})
+}
+
+// touches the session and moves its expiry
+func (r *SessionRepository) ExtendSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	return r.update(ctx, sessionID, bson.M{"$max": bson.M{"lastSeenAt": seenAt}, "$set": bson.M{"expiresAt": expiresAt}})
+}
+
+// revokes the session, a revoked session keeps its first revocation time
+func (r *SessionRepository) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	return r.update(ctx, sessionID, bson.M{"$min": bson.M{"revokedAt": at}})
+}
+
+// revokes every session of the user that is not revoked yet
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	_, err := r.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": at}})
+	return err
+}
+
+func (r *SessionRepository) update(ctx context.Context, sessionID string, update bson.M) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return domain.Validation("invalid session ID")
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.NotFound("session not found")
+	}
+	return nil
+}
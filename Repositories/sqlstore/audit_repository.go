@@ -0,0 +1,129 @@
+package sqlstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// AuditRepository keeps the audit trail in the audit_records table, the
+// changes of a record are stored as one JSON array
+type AuditRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewAuditRepository(db *DB, timeout time.Duration) usecases.IAuditRepo {
+	return &AuditRepository{DB: db, Timeout: timeout}
+}
+
+// function to append a record to the audit trail
+func (r *AuditRepository) AddRecord(ctx context.Context, record *domain.AuditRecord) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if record.ID == "" {
+		record.ID = domain.NewID()
+	}
+	changes := record.Changes
+	if changes == nil {
+		changes = []domain.FieldChange{}
+	}
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	d := r.DB.dialect
+	_, err = r.DB.sql.ExecContext(ctx, d.rebind("INSERT INTO audit_records (id, task_id, user_id, action, actor_id, at, changes) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		record.ID, record.TaskID, record.UserID, string(record.Action), record.ActorID, d.timeValue(record.At), string(encoded))
+	return err
+}
+
+// function to get one page of the audit records matching the filter, newest first
+func (r *AuditRepository) GetRecords(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	d := r.DB.dialect
+	where, args := buildAuditWhere(d, filter)
+
+	var total int64
+	if err := r.DB.sql.QueryRowContext(ctx, d.rebind("SELECT COUNT(*) FROM audit_records"+where), args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count audit records: %w", err)
+	}
+
+	page, pageArgs := d.limitOffset(filter.Limit, offset)
+	query := "SELECT id, task_id, user_id, action, actor_id, at, changes FROM audit_records" + where + " ORDER BY at DESC, id DESC" + page
+	rows, err := r.DB.sql.QueryContext(ctx, d.rebind(query), append(args, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]domain.AuditRecord, 0)
+	for rows.Next() {
+		var record domain.AuditRecord
+		var action, changes string
+		var at nullTime
+		if err := rows.Scan(&record.ID, &record.TaskID, &record.UserID, &action, &record.ActorID, &at, &changes); err != nil {
+			return nil, err
+		}
+		record.Action = domain.AuditAction(action)
+		if at.Time != nil {
+			record.At = *at.Time
+		}
+		if err := json.Unmarshal([]byte(changes), &record.Changes); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &domain.AuditPage{Records: records, Total: total}
+	if next := offset + int64(len(records)); len(records) > 0 && next < total {
+		result.NextCursor = domain.EncodeCursor(next)
+	}
+	return result, nil
+}
+
+// buildAuditWhere applies the same conditions as the mongo query of the audit repository
+func buildAuditWhere(d dialect, filter domain.AuditFilter) (string, []any) {
+	var conditions []string
+	var args []any
+	if filter.TaskID != "" {
+		conditions = append(conditions, "task_id = ?")
+		args = append(args, filter.TaskID)
+	}
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.ActorID != "" {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "at >= ?")
+		args = append(args, d.timeValue(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "at <= ?")
+		args = append(args, d.timeValue(*filter.To))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,59 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/Repositories/sqlstore"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditRepositoryTestSuite struct {
+	storeSuite
+}
+
+func TestAuditRepositorySuite(t *testing.T) {
+	runDialects(t, func(dialect, dsn string) suite.TestingSuite {
+		return &AuditRepositoryTestSuite{storeSuite: storeSuite{dialect: dialect, dsn: dsn}}
+	})
+}
+
+func (suite *AuditRepositoryTestSuite) TestGetRecords() {
+	repo := sqlstore.NewAuditRepository(suite.db, 0)
+	at := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	changes := []domain.FieldChange{{Field: "title", From: "old", To: "new"}}
+	for i, taskID := range []string{"task", "other", "task", "task"} {
+		record := &domain.AuditRecord{TaskID: taskID, Action: domain.AuditUpdate, ActorID: "actor", At: at.Add(time.Duration(i) * time.Hour), Changes: changes}
+		suite.Require().NoError(repo.AddRecord(suite.ctx, record))
+		suite.NotEmpty(record.ID)
+	}
+
+	from := at.Add(time.Hour)
+	first, err := repo.GetRecords(suite.ctx, domain.AuditFilter{TaskID: "task", From: &from, Limit: 1})
+	suite.NoError(err)
+	suite.Equal(int64(2), first.Total)
+	suite.Equal(at.Add(3*time.Hour), first.Records[0].At)
+	suite.Equal(changes, first.Records[0].Changes)
+	suite.NotEmpty(first.NextCursor)
+
+	second, err := repo.GetRecords(suite.ctx, domain.AuditFilter{TaskID: "task", From: &from, Limit: 1, Cursor: first.NextCursor})
+	suite.NoError(err)
+	suite.Equal(at.Add(2*time.Hour), second.Records[0].At)
+	suite.Empty(second.NextCursor)
+}
+
+func (suite *AuditRepositoryTestSuite) TestUserRecords() {
+	repo := sqlstore.NewAuditRepository(suite.db, 0)
+	change := &domain.AuditRecord{UserID: "user", Action: domain.AuditRoleChange, ActorID: "admin", At: time.Now()}
+	suite.Require().NoError(repo.AddRecord(suite.ctx, change))
+	suite.Require().NoError(repo.AddRecord(suite.ctx, &domain.AuditRecord{TaskID: "task", Action: domain.AuditCreate, ActorID: "user", At: time.Now()}))
+
+	page, err := repo.GetRecords(suite.ctx, domain.AuditFilter{UserID: "user"})
+	suite.NoError(err)
+	suite.Require().Equal(int64(1), page.Total)
+	suite.Equal(change.ID, page.Records[0].ID)
+	suite.Equal("user", page.Records[0].UserID)
+	suite.Empty(page.Records[0].TaskID)
+}
@@ -0,0 +1,50 @@
+-- times are stored as fixed width UTC text so they compare and sort as strings
+CREATE TABLE users (
+    id       TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    role     TEXT NOT NULL
+);
+
+CREATE TABLE tasks (
+    id          TEXT PRIMARY KEY,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL,
+    due_date    TEXT,
+    status      TEXT NOT NULL,
+    owner_id    TEXT NOT NULL,
+    deleted_at  TEXT,
+    deleted_by  TEXT NOT NULL DEFAULT '',
+    version     INTEGER NOT NULL
+);
+CREATE INDEX tasks_owner_id ON tasks (owner_id);
+CREATE INDEX tasks_deleted_at ON tasks (deleted_at);
+
+CREATE TABLE task_assignees (
+    task_id  TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (task_id, user_id)
+);
+CREATE INDEX task_assignees_user_id ON task_assignees (user_id);
+
+CREATE TABLE task_status_history (
+    task_id     TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status   TEXT NOT NULL,
+    actor_id    TEXT NOT NULL,
+    at          TEXT NOT NULL,
+    PRIMARY KEY (task_id, position)
+);
+
+CREATE TABLE audit_records (
+    id       TEXT PRIMARY KEY,
+    task_id  TEXT NOT NULL,
+    action   TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    at       TEXT NOT NULL,
+    changes  TEXT NOT NULL
+);
+CREATE INDEX audit_records_task_id ON audit_records (task_id, at);
+CREATE INDEX audit_records_at ON audit_records (at);
@@ -0,0 +1,2 @@
+ALTER TABLE audit_records ADD COLUMN user_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX audit_records_user_id ON audit_records (user_id, at);
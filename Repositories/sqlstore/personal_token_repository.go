@@ -0,0 +1,165 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// PersonalTokenRepository keeps the personal access tokens in the personal_tokens table,
+// the scopes are stored space separated
+type PersonalTokenRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewPersonalTokenRepository(db *DB, timeout time.Duration) usecases.IPersonalTokenRepo {
+	return &PersonalTokenRepository{DB: db, Timeout: timeout}
+}
+
+// stores a new personal access token, an empty ID is filled in
+func (r *PersonalTokenRepository) CreatePersonalToken(ctx context.Context, token *domain.PersonalToken) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if token.ID == "" {
+		token.ID = domain.NewID()
+	} else if !domain.IsValidID(token.ID) {
+		return domain.Validation("invalid personal token ID")
+	}
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("INSERT INTO personal_tokens ("+personalTokenColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		token.ID, token.UserID, token.Name, joinScopes(token.Scopes), token.TokenHash, d.timeValue(token.CreatedAt), d.timeValue(token.ExpiresAt),
+		d.nullTimeValue(token.LastUsedAt), d.nullTimeValue(token.RevokedAt))
+	if isUniqueViolation(err) {
+		return domain.Conflict("personal token already exists")
+	}
+	return err
+}
+
+const personalTokenColumns = "id, user_id, name, scopes, token_hash, created_at, expires_at, last_used_at, revoked_at"
+
+func scanPersonalToken(row interface{ Scan(dest ...any) error }) (*domain.PersonalToken, error) {
+	var token domain.PersonalToken
+	var scopes string
+	var createdAt, expiresAt, lastUsedAt, revokedAt nullTime
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.TokenHash, &createdAt, &expiresAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	token.Scopes = splitScopes(scopes)
+	token.CreatedAt, token.ExpiresAt = *createdAt.Time, *expiresAt.Time
+	token.LastUsedAt, token.RevokedAt = lastUsedAt.Time, revokedAt.Time
+	return &token, nil
+}
+
+func joinScopes(scopes []domain.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, scope := range scopes {
+		parts[i] = string(scope)
+	}
+	return strings.Join(parts, " ")
+}
+
+func splitScopes(value string) []domain.Scope {
+	scopes := []domain.Scope{}
+	for _, part := range strings.Fields(value) {
+		scopes = append(scopes, domain.Scope(part))
+	}
+	return scopes
+}
+
+// retrieves a personal access token by its ID
+func (r *PersonalTokenRepository) FindPersonalToken(ctx context.Context, tokenID string) (*domain.PersonalToken, error) {
+	if !domain.IsValidID(tokenID) {
+		return nil, domain.Validation("invalid personal token ID")
+	}
+	return r.findOne(ctx, "id", tokenID)
+}
+
+// retrieves a personal access token by the hash of the token
+func (r *PersonalTokenRepository) FindPersonalTokenByHash(ctx context.Context, hash string) (*domain.PersonalToken, error) {
+	return r.findOne(ctx, "token_hash", hash)
+}
+
+func (r *PersonalTokenRepository) findOne(ctx context.Context, column, value string) (*domain.PersonalToken, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	query := "SELECT " + personalTokenColumns + " FROM personal_tokens WHERE " + column + " = ?"
+	token, err := scanPersonalToken(r.DB.sql.QueryRowContext(ctx, r.DB.dialect.rebind(query), value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NotFound("personal token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// lists the tokens of the user that are not revoked, newest first
+func (r *PersonalTokenRepository) ListPersonalTokens(ctx context.Context, userID string) ([]domain.PersonalToken, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	query := "SELECT " + personalTokenColumns + " FROM personal_tokens WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at DESC, " + r.DB.dialect.orderBy("id", false)
+	rows, err := r.DB.sql.QueryContext(ctx, r.DB.dialect.rebind(query), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]domain.PersonalToken, 0)
+	for rows.Next() {
+		token, err := scanPersonalToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// moves the last used time of the token forward
+func (r *PersonalTokenRepository) TouchPersonalToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	d := r.DB.dialect
+	return r.updateOne(ctx, tokenID, "UPDATE personal_tokens SET last_used_at = CASE WHEN last_used_at IS NULL OR last_used_at < ? THEN ? ELSE last_used_at END WHERE id = ?",
+		d.timeValue(usedAt), d.timeValue(usedAt), tokenID)
+}
+
+// revokes the token, a revoked token keeps its first revocation time
+func (r *PersonalTokenRepository) RevokePersonalToken(ctx context.Context, tokenID string, at time.Time) error {
+	return r.updateOne(ctx, tokenID, "UPDATE personal_tokens SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", r.DB.dialect.timeValue(at), tokenID)
+}
+
+// revokes every token of the user that is not revoked yet
+func (r *PersonalTokenRepository) RevokeUserPersonalTokens(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("UPDATE personal_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"), d.timeValue(at), userID)
+	return err
+}
+
+func (r *PersonalTokenRepository) updateOne(ctx context.Context, tokenID, query string, args ...any) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(tokenID) {
+		return domain.Validation("invalid personal token ID")
+	}
+	updated, err := rowsAffected(r.DB.sql.ExecContext(ctx, r.DB.dialect.rebind(query), args...))
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return domain.NotFound("personal token not found")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// RefreshTokenRepository keeps the refresh tokens in the refresh_tokens table, token hashes are unique
+type RefreshTokenRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewRefreshTokenRepository(db *DB, timeout time.Duration) usecases.IRefreshTokenRepo {
+	return &RefreshTokenRepository{DB: db, Timeout: timeout}
+}
+
+// stores a new refresh token, an empty ID is filled in
+func (r *RefreshTokenRepository) CreateToken(ctx context.Context, token *domain.RefreshToken) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if token.ID == "" {
+		token.ID = domain.NewID()
+	} else if !domain.IsValidID(token.ID) {
+		return domain.Validation("invalid refresh token ID")
+	}
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+		token.ID, token.UserID, token.FamilyID, token.TokenHash, d.timeValue(token.CreatedAt), d.timeValue(token.ExpiresAt), d.nullTimeValue(token.UsedAt), d.nullTimeValue(token.RevokedAt))
+	if isUniqueViolation(err) {
+		return domain.Conflict("refresh token already exists")
+	}
+	return err
+}
+
+// retrieves a refresh token by the hash of the token
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	var token domain.RefreshToken
+	var createdAt, expiresAt, usedAt, revokedAt nullTime
+	err := r.DB.sql.QueryRowContext(ctx, r.DB.dialect.rebind("SELECT id, user_id, family_id, token_hash, created_at, expires_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash = ?"), hash).
+		Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &createdAt, &expiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NotFound("refresh token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	token.CreatedAt, token.ExpiresAt = *createdAt.Time, *expiresAt.Time
+	token.UsedAt, token.RevokedAt = usedAt.Time, revokedAt.Time
+	return &token, nil
+}
+
+// marks a refresh token as exchanged, a token can only be used once
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, tokenID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(tokenID) {
+		return domain.Validation("invalid refresh token ID")
+	}
+	d := r.DB.dialect
+	//only a token that was never used matches, the update is the check
+	updated, err := rowsAffected(r.DB.sql.ExecContext(ctx, d.rebind("UPDATE refresh_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL"), d.timeValue(at), tokenID))
+	if err != nil {
+		return err
+	}
+	if updated > 0 {
+		return nil
+	}
+	var exists int
+	err = r.DB.sql.QueryRowContext(ctx, d.rebind("SELECT 1 FROM refresh_tokens WHERE id = ?"), tokenID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotFound("refresh token not found")
+	}
+	if err != nil {
+		return err
+	}
+	return domain.Conflict("refresh token was already used")
+}
+
+// revokes every token of a family that is not revoked yet
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL"), d.timeValue(at), familyID)
+	return err
+}
+
+// revokes every token of the user that is not revoked yet
+func (r *RefreshTokenRepository) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"), d.timeValue(at), userID)
+	return err
+}
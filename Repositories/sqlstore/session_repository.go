@@ -0,0 +1,141 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// SessionRepository keeps the sessions in the sessions table
+type SessionRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewSessionRepository(db *DB, timeout time.Duration) usecases.ISessionRepo {
+	return &SessionRepository{DB: db, Timeout: timeout}
+}
+
+// stores a new session, an empty ID is filled in
+func (r *SessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if session.ID == "" {
+		session.ID = domain.NewID()
+	} else if !domain.IsValidID(session.ID) {
+		return domain.Validation("invalid session ID")
+	}
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("INSERT INTO sessions ("+sessionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+		session.ID, session.UserID, session.UserAgent, session.IP, d.timeValue(session.CreatedAt), d.timeValue(session.LastSeenAt), d.timeValue(session.ExpiresAt), d.nullTimeValue(session.RevokedAt))
+	if isUniqueViolation(err) {
+		return domain.Conflict("session already exists")
+	}
+	return err
+}
+
+const sessionColumns = "id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at"
+
+func scanSession(row interface{ Scan(dest ...any) error }) (*domain.Session, error) {
+	var session domain.Session
+	var createdAt, lastSeenAt, expiresAt, revokedAt nullTime
+	if err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &createdAt, &lastSeenAt, &expiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	session.CreatedAt, session.LastSeenAt, session.ExpiresAt = *createdAt.Time, *lastSeenAt.Time, *expiresAt.Time
+	session.RevokedAt = revokedAt.Time
+	return &session, nil
+}
+
+// retrieves a session by its ID
+func (r *SessionRepository) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(sessionID) {
+		return nil, domain.Validation("invalid session ID")
+	}
+	session, err := scanSession(r.DB.sql.QueryRowContext(ctx, r.DB.dialect.rebind("SELECT "+sessionColumns+" FROM sessions WHERE id = ?"), sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NotFound("session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// lists the sessions of the user that are not revoked, last seen first
+func (r *SessionRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	query := "SELECT " + sessionColumns + " FROM sessions WHERE user_id = ? AND revoked_at IS NULL ORDER BY last_seen_at DESC, " + r.DB.dialect.orderBy("id", false)
+	rows, err := r.DB.sql.QueryContext(ctx, r.DB.dialect.rebind(query), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// moves the last seen time of the session forward
+func (r *SessionRepository) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	d := r.DB.dialect
+	return r.updateOne(ctx, sessionID, "UPDATE sessions SET last_seen_at = CASE WHEN last_seen_at < ? THEN ? ELSE last_seen_at END WHERE id = ?",
+		d.timeValue(seenAt), d.timeValue(seenAt), sessionID)
+}
+
+// touches the session and moves its expiry
+func (r *SessionRepository) ExtendSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	d := r.DB.dialect
+	return r.updateOne(ctx, sessionID, "UPDATE sessions SET last_seen_at = CASE WHEN last_seen_at < ? THEN ? ELSE last_seen_at END, expires_at = ? WHERE id = ?",
+		d.timeValue(seenAt), d.timeValue(seenAt), d.timeValue(expiresAt), sessionID)
+}
+
+// revokes the session, a revoked session keeps its first revocation time
+func (r *SessionRepository) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	return r.updateOne(ctx, sessionID, "UPDATE sessions SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", r.DB.dialect.timeValue(at), sessionID)
+}
+
+// revokes every session of the user that is not revoked yet
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	d := r.DB.dialect
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind("UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"), d.timeValue(at), userID)
+	return err
+}
+
+func (r *SessionRepository) updateOne(ctx context.Context, sessionID, query string, args ...any) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(sessionID) {
+		return domain.Validation("invalid session ID")
+	}
+	updated, err := rowsAffected(r.DB.sql.ExecContext(ctx, r.DB.dialect.rebind(query), args...))
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return domain.NotFound("session not found")
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+// Package sqlstore keeps the tasks, users and audit trail in a SQL database.
+// SQLite is meant for local and embedded use, PostgreSQL for production
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// the supported databases
+const (
+	SQLite   = "sqlite"
+	Postgres = "postgres"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// DB is an open database whose schema is up to date
+type DB struct {
+	sql     *sql.DB
+	dialect dialect
+}
+
+// Open connects to a SQLite or PostgreSQL database and applies the pending migrations.
+// the SQLite dsn is a file name or ":memory:", the PostgreSQL dsn a connection URL
+func Open(ctx context.Context, dialectName string, dsn string) (*DB, error) {
+	var (
+		d      dialect
+		driver string
+	)
+	switch dialectName {
+	case SQLite:
+		d, driver, dsn = dialect{name: SQLite}, "sqlite", sqliteDSN(dsn)
+	case Postgres:
+		d, driver = dialect{name: Postgres}, "pgx"
+	default:
+		return nil, fmt.Errorf("unknown sql dialect %q", dialectName)
+	}
+
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if d.name == SQLite {
+		//sqlite allows one writer at a time, a single connection also keeps ":memory:" in one database
+		conn.SetMaxOpenConns(1)
+	}
+	db := &DB{sql: conn, dialect: d}
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to %s: %w", d.name, err)
+	}
+	if err := db.migrate(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close closes the database
+func (db *DB) Close() error {
+	return db.sql.Close()
+}
+
+// sqliteDSN turns on the foreign keys, which cascade task deletes, and waits on locks instead of failing
+func sqliteDSN(dsn string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+// migrate applies the migrations of the dialect that were not applied yet, in the order of
+// their number. every migration runs in its own transaction
+func (db *DB) migrate(ctx context.Context) error {
+	if _, err := db.sql.ExecContext(ctx, db.dialect.migrationsTable()); err != nil {
+		return fmt.Errorf("creating the migrations table: %w", err)
+	}
+	applied := map[int]bool{}
+	rows, err := db.sql.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("reading the applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	dir := path.Join("migrations", db.dialect.name)
+	files, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	for _, file := range files {
+		version, err := migrationVersion(file.Name())
+		if err != nil {
+			return err
+		}
+		if applied[version] {
+			continue
+		}
+		script, err := migrations.ReadFile(path.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		err = db.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, db.dialect.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+				version, file.Name(), db.dialect.timeValue(time.Now()))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("applying migration %s: %w", file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// migrationVersion reads the number a migration file name starts with, e.g. 1 for 0001_init.sql
+func migrationVersion(name string) (int, error) {
+	prefix, _, ok := strings.Cut(name, "_")
+	if !ok {
+		return 0, fmt.Errorf("migration %s: the name must start with its number", name)
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 0, fmt.Errorf("migration %s: the name must start with its number", name)
+	}
+	return version, nil
+}
+
+// inTx runs fn in a transaction that is committed when fn succeeds and rolled back otherwise
+func (db *DB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// dialect hides the differences between the SQL of the supported databases
+type dialect struct {
+	name string
+}
+
+// sqliteTimeFormat has a fixed width so that stored times sort as text
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+func (d dialect) migrationsTable() string {
+	timeType := "TIMESTAMPTZ"
+	if d.name == SQLite {
+		timeType = "TEXT"
+	}
+	return "CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at " + timeType + " NOT NULL)"
+}
+
+// forUpdate locks the selected rows until the transaction ends, SQLite has a single
+// connection so its transactions never overlap
+func (d dialect) forUpdate() string {
+	if d.name == Postgres {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// rebind turns the ? placeholders of a query into the $1, $2... placeholders of PostgreSQL
+func (d dialect) rebind(query string) string {
+	if d.name != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// timeValue is the query argument of a time, always in UTC
+func (d dialect) timeValue(t time.Time) any {
+	if d.name == SQLite {
+		return t.UTC().Format(sqliteTimeFormat)
+	}
+	return t.UTC()
+}
+
+// nullTimeValue is the query argument of an optional time
+func (d dialect) nullTimeValue(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return d.timeValue(*t)
+}
+
+// orderBy sorts on a column the way mongo does: text compares byte by byte
+// and missing values come before every other value
+func (d dialect) orderBy(column string, desc bool) string {
+	if d.name == Postgres && column != "due_date" {
+		column += ` COLLATE "C"`
+	}
+	if desc {
+		return column + " DESC NULLS LAST"
+	}
+	return column + " ASC NULLS FIRST"
+}
+
+// limitOffset pages a query, a limit of 0 returns every row after the offset
+func (d dialect) limitOffset(limit, offset int64) (string, []any) {
+	if limit > 0 {
+		return " LIMIT ? OFFSET ?", []any{limit, offset}
+	}
+	if d.name == SQLite {
+		return " LIMIT -1 OFFSET ?", []any{offset}
+	}
+	return " OFFSET ?", []any{offset}
+}
+
+// isUniqueViolation reports whether an insert failed on a primary key or unique constraint
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// nullTime scans an optional time stored by either dialect
+type nullTime struct {
+	Time *time.Time
+}
+
+func (n *nullTime) Scan(value any) error {
+	var t time.Time
+	switch v := value.(type) {
+	case nil:
+		n.Time = nil
+		return nil
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(sqliteTimeFormat, v)
+		if err != nil {
+			return err
+		}
+		t = parsed
+	case []byte:
+		parsed, err := time.Parse(sqliteTimeFormat, string(v))
+		if err != nil {
+			return err
+		}
+		t = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into a time", value)
+	}
+	t = t.UTC()
+	n.Time = &t
+	return nil
+}
+
+// placeholders returns n comma separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// withTimeout derives the context of one database operation from the caller's context
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// rowsAffected returns how many rows a statement changed
+func rowsAffected(result sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	domain "task_management/Domain"
+	"task_management/Repositories/sqlstore"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// the PostgreSQL tests only run when TEST_POSTGRES_DSN points at a database they may empty
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// storeSuite opens an empty database of one dialect before every test
+type storeSuite struct {
+	suite.Suite
+	dialect string
+	dsn     string
+	db      *sqlstore.DB
+	ctx     context.Context
+}
+
+func (s *storeSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.db = openTestDB(s.T(), s.dialect, s.dsn)
+}
+
+// runDialects runs a suite against SQLite and, when configured, PostgreSQL
+func runDialects(t *testing.T, newSuite func(dialect, dsn string) suite.TestingSuite) {
+	t.Run(sqlstore.SQLite, func(t *testing.T) {
+		suite.Run(t, newSuite(sqlstore.SQLite, ":memory:"))
+	})
+	t.Run(sqlstore.Postgres, func(t *testing.T) {
+		dsn := os.Getenv(postgresDSNEnv)
+		if dsn == "" {
+			t.Skip(postgresDSNEnv + " is not set")
+		}
+		suite.Run(t, newSuite(sqlstore.Postgres, dsn))
+	})
+}
+
+// openTestDB opens a migrated database without any rows, it is closed when the test ends
+func openTestDB(t *testing.T, dialect, dsn string) *sqlstore.DB {
+	if dialect == sqlstore.Postgres {
+		conn, err := sql.Open("pgx", dsn)
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Exec("DROP TABLE IF EXISTS task_status_history, task_assignees, tasks, users, audit_records, refresh_tokens, revoked_tokens, user_revocations, sessions, personal_tokens, schema_migrations")
+		require.NoError(t, err)
+	}
+	db, err := sqlstore.Open(context.Background(), dialect, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("MigrationsApplyOnce", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "tasks.db")
+		first, err := sqlstore.Open(context.Background(), sqlstore.SQLite, file)
+		require.NoError(t, err)
+		repo := sqlstore.NewUserRepository(first, 0)
+		require.NoError(t, repo.CreateUser(context.Background(), &domain.User{Username: "alice", Password: "hash", Role: domain.RoleUser}))
+		require.NoError(t, first.Close())
+
+		//opening again keeps the schema and the data
+		second, err := sqlstore.Open(context.Background(), sqlstore.SQLite, file)
+		require.NoError(t, err)
+		defer second.Close()
+		count, err := sqlstore.NewUserRepository(second, 0).CountAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("UnknownDialect", func(t *testing.T) {
+		_, err := sqlstore.Open(context.Background(), "oracle", "")
+		require.EqualError(t, err, `unknown sql dialect "oracle"`)
+	})
+}
@@ -0,0 +1,482 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// TaskRepository keeps the tasks in the tasks table, their assignees and status
+// history live in task_assignees and task_status_history
+type TaskRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewTaskRepository(db *DB, timeout time.Duration) usecases.ITaskRepo {
+	return &TaskRepository{DB: db, Timeout: timeout}
+}
+
+const taskColumns = "id, title, description, due_date, status, owner_id, deleted_at, deleted_by, version"
+
+// sortable task fields and their columns
+var taskSortColumns = map[string]string{
+	domain.SortByTitle:   "title",
+	domain.SortByDueDate: "due_date",
+	domain.SortByStatus:  "status",
+}
+
+// function to create a new task together with its assignees and status history
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if task.ID == "" {
+		task.ID = domain.NewID()
+	}
+	if !domain.IsValidID(task.ID) {
+		return domain.Validation("invalid task ID")
+	}
+	d := r.DB.dialect
+	err := r.DB.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, d.rebind("INSERT INTO tasks ("+taskColumns+") VALUES ("+placeholders(9)+")"),
+			task.ID, task.Title, task.Description, d.nullTimeValue(task.DueDate), string(task.Status),
+			task.OwnerID, d.nullTimeValue(task.DeletedAt), task.DeletedBy, task.Version)
+		if err != nil {
+			return err
+		}
+		for i, userID := range task.Assignees {
+			if _, err := tx.ExecContext(ctx, d.rebind("INSERT INTO task_assignees (task_id, user_id, position) VALUES (?, ?, ?)"), task.ID, userID, i+1); err != nil {
+				return err
+			}
+		}
+		for i, transition := range task.StatusHistory {
+			if err := insertTransition(ctx, tx, d, task.ID, i+1, transition); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if isUniqueViolation(err) {
+		return domain.Conflict("task already exists")
+	}
+	return err
+}
+
+// function to get one page of the tasks matching the filter
+func (r *TaskRepository) GetAllTasks(ctx context.Context, filter domain.TaskFilter) (*domain.TaskPage, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	d := r.DB.dialect
+	where, args := buildTaskWhere(d, filter)
+
+	var total int64
+	if err := r.DB.sql.QueryRowContext(ctx, d.rebind("SELECT COUNT(*) FROM tasks"+where), args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	order := []string{}
+	if column, ok := taskSortColumns[filter.SortBy]; ok {
+		order = append(order, d.orderBy(column, filter.SortDesc))
+	}
+	order = append(order, d.orderBy("id", filter.SortDesc))
+	page, pageArgs := d.limitOffset(filter.Limit, offset)
+
+	query := "SELECT " + taskColumns + " FROM tasks" + where + " ORDER BY " + strings.Join(order, ", ") + page
+	tasks, err := r.queryTasks(ctx, r.DB.sql, query, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tasks: %w", err)
+	}
+
+	result := &domain.TaskPage{Tasks: tasks, Total: total}
+	if next := offset + int64(len(tasks)); len(tasks) > 0 && next < total {
+		result.NextCursor = domain.EncodeCursor(next)
+	}
+	return result, nil
+}
+
+// buildTaskWhere translates the domain filter into a where clause and its arguments
+func buildTaskWhere(d dialect, filter domain.TaskFilter) (string, []any) {
+	//trashed tasks only show up when the trash is listed
+	conditions := []string{"deleted_at IS NULL"}
+	if filter.Trashed {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+	var args []any
+	const assigned = "EXISTS (SELECT 1 FROM task_assignees a WHERE a.task_id = tasks.id AND a.user_id = ?)"
+	if filter.OwnerID != "" {
+		conditions = append(conditions, "owner_id = ?")
+		args = append(args, filter.OwnerID)
+	}
+	if filter.AssigneeID != "" {
+		conditions = append(conditions, assigned)
+		args = append(args, filter.AssigneeID)
+	}
+	if filter.VisibleTo != "" {
+		conditions = append(conditions, "(owner_id = ? OR "+assigned+")")
+		args = append(args, filter.VisibleTo, filter.VisibleTo)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if filter.ExcludeStatus != "" {
+		conditions = append(conditions, "status <> ?")
+		args = append(args, string(filter.ExcludeStatus))
+	}
+	if filter.TitleContains != "" {
+		conditions = append(conditions, `LOWER(title) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(strings.ToLower(filter.TitleContains))+"%")
+	}
+	//tasks without a due date never match a due date range
+	if filter.DueAfter != nil {
+		conditions = append(conditions, "due_date >= ?")
+		args = append(args, d.timeValue(*filter.DueAfter))
+	}
+	if filter.DueBefore != nil {
+		conditions = append(conditions, "due_date <= ?")
+		args = append(args, d.timeValue(*filter.DueBefore))
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// escapeLike makes the LIKE wildcards in s match themselves
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// function to get task by id
+func (r *TaskRepository) GetTaskByID(ctx context.Context, taskID string) (*domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(taskID) {
+		return nil, domain.Validation("invalid task ID")
+	}
+	tasks, err := r.queryTasks(ctx, r.DB.sql, "SELECT "+taskColumns+" FROM tasks WHERE id = ?", taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, domain.NotFound("task not found")
+	}
+	return &tasks[0], nil
+}
+
+// function to update only the listed fields of a task, a nil due date is removed
+func (r *TaskRepository) UpdateTaskFields(ctx context.Context, taskID string, task *domain.Task, fields []string, version int64) error {
+	d := r.DB.dialect
+	var set []string
+	var args []any
+	var transition *domain.StatusTransition
+	for _, field := range fields {
+		switch field {
+		case "title":
+			set, args = append(set, "title = ?"), append(args, task.Title)
+		case "description":
+			set, args = append(set, "description = ?"), append(args, task.Description)
+		case "dueDate":
+			set, args = append(set, "due_date = ?"), append(args, d.nullTimeValue(task.DueDate))
+		case "ownerId":
+			set, args = append(set, "owner_id = ?"), append(args, task.OwnerID)
+		case "status":
+			last, ok := task.LastTransition()
+			if !ok {
+				return domain.Validation("a status change needs its transition in the status history")
+			}
+			set, args = append(set, "status = ?"), append(args, string(task.Status))
+			transition = &last
+		default:
+			return domain.Validation(fmt.Sprintf("field %q cannot be updated", field))
+		}
+	}
+	if len(set) == 0 {
+		return r.updateVersioned(ctx, taskID, version, "")
+	}
+	if transition == nil {
+		return r.updateVersioned(ctx, taskID, version, strings.Join(set, ", "), args...)
+	}
+
+	//the new status and its history entry are written in one transaction
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	return r.DB.inTx(ctx, func(tx *sql.Tx) error {
+		if err := execVersioned(ctx, tx, d, taskID, version, strings.Join(set, ", "), args...); err != nil {
+			return err
+		}
+		return appendTransition(ctx, tx, d, taskID, *transition)
+	})
+}
+
+// function to delete task by id, the assignees and status history go with it
+func (r *TaskRepository) DeleteTaskByID(ctx context.Context, taskID string, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	deleted, err := rowsAffected(r.DB.sql.ExecContext(ctx, r.DB.dialect.rebind("DELETE FROM tasks WHERE id = ? AND version = ?"), taskID, version))
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+// function to move a task to the trash
+func (r *TaskRepository) TrashTaskByID(ctx context.Context, taskID string, deletedBy string, at time.Time, version int64) error {
+	return r.updateVersioned(ctx, taskID, version, "deleted_at = ?, deleted_by = ?", r.DB.dialect.timeValue(at), deletedBy)
+}
+
+// function to take a task back out of the trash
+func (r *TaskRepository) RestoreTaskByID(ctx context.Context, taskID string, version int64) error {
+	return r.updateVersioned(ctx, taskID, version, "deleted_at = NULL, deleted_by = ''")
+}
+
+// function to permanently remove the tasks trashed before the cutoff
+func (r *TaskRepository) PurgeTrashedBefore(ctx context.Context, cutoff time.Time) ([]domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	d := r.DB.dialect
+	var purged []domain.Task
+	err := r.DB.inTx(ctx, func(tx *sql.Tx) error {
+		tasks, err := r.queryTasks(ctx, tx, "SELECT "+taskColumns+" FROM tasks WHERE deleted_at < ?", d.timeValue(cutoff))
+		if err != nil {
+			return err
+		}
+		purged = make([]domain.Task, 0, len(tasks))
+		for _, task := range tasks {
+			//a task restored since it was read stays
+			deleted, err := rowsAffected(tx.ExecContext(ctx, d.rebind("DELETE FROM tasks WHERE id = ? AND version = ?"), task.ID, task.Version))
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				purged = append(purged, task)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+// function to move a task to another status and record the transition.
+// the status only changes if the task is still in transition.From at the given version
+func (r *TaskRepository) TransitionTaskStatus(ctx context.Context, taskID string, transition domain.StatusTransition, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	d := r.DB.dialect
+	return r.DB.inTx(ctx, func(tx *sql.Tx) error {
+		updated, err := rowsAffected(tx.ExecContext(ctx, d.rebind("UPDATE tasks SET status = ?, version = version + 1 WHERE id = ? AND version = ? AND status = ?"),
+			string(transition.To), taskID, version, string(transition.From)))
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return domain.ErrVersionMismatch
+		}
+		return appendTransition(ctx, tx, d, taskID, transition)
+	})
+}
+
+// function to add a user to the task assignees, adding the same user twice is a no-op
+func (r *TaskRepository) AddAssignee(ctx context.Context, taskID string, userID string) error {
+	return r.updateAssignees(ctx, taskID, func(tx *sql.Tx, d dialect) error {
+		_, err := tx.ExecContext(ctx, d.rebind(`INSERT INTO task_assignees (task_id, user_id, position)
+			SELECT CAST(? AS TEXT), CAST(? AS TEXT), COALESCE(MAX(position), 0) + 1 FROM task_assignees WHERE task_id = ?
+			ON CONFLICT (task_id, user_id) DO NOTHING`), taskID, userID, taskID)
+		return err
+	})
+}
+
+// function to remove a user from the task assignees
+func (r *TaskRepository) RemoveAssignee(ctx context.Context, taskID string, userID string) error {
+	return r.updateAssignees(ctx, taskID, func(tx *sql.Tx, d dialect) error {
+		_, err := tx.ExecContext(ctx, d.rebind("DELETE FROM task_assignees WHERE task_id = ? AND user_id = ?"), taskID, userID)
+		return err
+	})
+}
+
+// updateAssignees bumps the version of the task and changes its assignees in one transaction
+func (r *TaskRepository) updateAssignees(ctx context.Context, taskID string, change func(tx *sql.Tx, d dialect) error) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	d := r.DB.dialect
+	return r.DB.inTx(ctx, func(tx *sql.Tx) error {
+		updated, err := rowsAffected(tx.ExecContext(ctx, d.rebind("UPDATE tasks SET version = version + 1 WHERE id = ?"), taskID))
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return domain.NotFound("task not found")
+		}
+		return change(tx, d)
+	})
+}
+
+// updateVersioned sets the columns of a task that is still at the given version and bumps the version
+func (r *TaskRepository) updateVersioned(ctx context.Context, taskID string, version int64, set string, args ...any) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	return execVersioned(ctx, r.DB.sql, r.DB.dialect, taskID, version, set, args...)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// execVersioned sets the columns of the task through exec, the database or a transaction
+func execVersioned(ctx context.Context, exec execer, d dialect, taskID string, version int64, set string, args ...any) error {
+	if set != "" {
+		set += ", "
+	}
+	query := "UPDATE tasks SET " + set + "version = version + 1 WHERE id = ? AND version = ?"
+	updated, err := rowsAffected(exec.ExecContext(ctx, d.rebind(query), append(args, taskID, version)...))
+	if err != nil {
+		return err
+	}
+	//no row means the task changed or is gone
+	if updated == 0 {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+// appendTransition adds the transition after the last entry of the task status history
+func appendTransition(ctx context.Context, tx *sql.Tx, d dialect, taskID string, transition domain.StatusTransition) error {
+	var position int
+	if err := tx.QueryRowContext(ctx, d.rebind("SELECT COALESCE(MAX(position), 0) + 1 FROM task_status_history WHERE task_id = ?"), taskID).Scan(&position); err != nil {
+		return err
+	}
+	return insertTransition(ctx, tx, d, taskID, position, transition)
+}
+
+func insertTransition(ctx context.Context, tx *sql.Tx, d dialect, taskID string, position int, transition domain.StatusTransition) error {
+	_, err := tx.ExecContext(ctx, d.rebind("INSERT INTO task_status_history (task_id, position, from_status, to_status, actor_id, at) VALUES (?, ?, ?, ?, ?, ?)"),
+		taskID, position, string(transition.From), string(transition.To), transition.ActorID, d.timeValue(transition.At))
+	return err
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// queryTasks runs a query selecting taskColumns and loads the assignees and status history of the tasks found
+func (r *TaskRepository) queryTasks(ctx context.Context, q queryer, query string, args ...any) ([]domain.Task, error) {
+	rows, err := q.QueryContext(ctx, r.DB.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]domain.Task, 0)
+	for rows.Next() {
+		var task domain.Task
+		var dueDate, deletedAt nullTime
+		var status string
+		if err := rows.Scan(&task.ID, &task.Title, &task.Description, &dueDate, &status, &task.OwnerID, &deletedAt, &task.DeletedBy, &task.Version); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		task.Status = domain.TaskStatus(status)
+		task.DueDate = dueDate.Time
+		task.DeletedAt = deletedAt.Time
+		task.Assignees = []string{}
+		tasks = append(tasks, task)
+	}
+	//the rows are closed before the next queries, sqlite only has one connection
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return tasks, nil
+	}
+	if err := r.loadTaskChildren(ctx, q, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// loadTaskChildren fills in the assignees and the status history of the tasks
+func (r *TaskRepository) loadTaskChildren(ctx context.Context, q queryer, tasks []domain.Task) error {
+	d := r.DB.dialect
+	index := map[string]*domain.Task{}
+	ids := make([]any, 0, len(tasks))
+	for i := range tasks {
+		index[tasks[i].ID] = &tasks[i]
+		ids = append(ids, tasks[i].ID)
+	}
+	in := "(" + placeholders(len(ids)) + ")"
+
+	rows, err := q.QueryContext(ctx, d.rebind("SELECT task_id, user_id FROM task_assignees WHERE task_id IN "+in+" ORDER BY task_id, position"), ids...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var taskID, userID string
+		if err := rows.Scan(&taskID, &userID); err != nil {
+			rows.Close()
+			return err
+		}
+		index[taskID].Assignees = append(index[taskID].Assignees, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = q.QueryContext(ctx, d.rebind("SELECT task_id, from_status, to_status, actor_id, at FROM task_status_history WHERE task_id IN "+in+" ORDER BY task_id, position"), ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var taskID, from, to, actorID string
+		var at nullTime
+		if err := rows.Scan(&taskID, &from, &to, &actorID, &at); err != nil {
+			return err
+		}
+		if at.Time == nil {
+			return errors.New("status transition without a time")
+		}
+		task := index[taskID]
+		task.StatusHistory = append(task.StatusHistory, domain.StatusTransition{
+			From: domain.TaskStatus(from), To: domain.TaskStatus(to), ActorID: actorID, At: *at.Time,
+		})
+	}
+	return rows.Err()
+}
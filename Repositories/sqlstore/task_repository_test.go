@@ -0,0 +1,21 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"task_management/Repositories/repotest"
+	"task_management/Repositories/sqlstore"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTaskRepositorySuite(t *testing.T) {
+	runDialects(t, func(dialect, dsn string) suite.TestingSuite {
+		return &repotest.TaskRepoContract{
+			NewRepo: func(t *testing.T) usecases.ITaskRepo {
+				return sqlstore.NewTaskRepository(openTestDB(t, dialect, dsn), 0)
+			},
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// TokenRevocationRepository keeps the revoked tokens in the revoked_tokens and
+// user_revocations tables, expired rows are deleted on the next revocation
+type TokenRevocationRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewTokenRevocationRepository(db *DB, timeout time.Duration) usecases.ITokenRevocationRepo {
+	return &TokenRevocationRepository{DB: db, Timeout: timeout}
+}
+
+// revokes a single access token until it expires
+func (r *TokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(tokenID) {
+		return domain.Validation("invalid token ID")
+	}
+	d := r.DB.dialect
+	if _, err := r.DB.sql.ExecContext(ctx, d.rebind("DELETE FROM revoked_tokens WHERE expires_at <= ?"), d.timeValue(time.Now())); err != nil {
+		return err
+	}
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind(`INSERT INTO revoked_tokens (id, expires_at) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET expires_at = CASE WHEN excluded.expires_at > revoked_tokens.expires_at THEN excluded.expires_at ELSE revoked_tokens.expires_at END`),
+		tokenID, d.timeValue(expiresAt))
+	return err
+}
+
+// revokes the tokens of the user issued up to at, a later cutoff replaces an earlier one
+func (r *TokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID string, at, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user ID")
+	}
+	d := r.DB.dialect
+	if _, err := r.DB.sql.ExecContext(ctx, d.rebind("DELETE FROM user_revocations WHERE expires_at <= ?"), d.timeValue(time.Now())); err != nil {
+		return err
+	}
+	_, err := r.DB.sql.ExecContext(ctx, d.rebind(`INSERT INTO user_revocations (user_id, revoked_at, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			revoked_at = CASE WHEN excluded.revoked_at > user_revocations.revoked_at THEN excluded.revoked_at ELSE user_revocations.revoked_at END,
+			expires_at = CASE WHEN excluded.expires_at > user_revocations.expires_at THEN excluded.expires_at ELSE user_revocations.expires_at END`),
+		userID, d.timeValue(at), d.timeValue(expiresAt))
+	return err
+}
+
+// reports whether the token or every token of its user was revoked
+func (r *TokenRevocationRepository) IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	d := r.DB.dialect
+	var revoked bool
+	err := r.DB.sql.QueryRowContext(ctx, d.rebind(`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE id = ?)
+		OR EXISTS (SELECT 1 FROM user_revocations WHERE user_id = ? AND revoked_at >= ?)`),
+		tokenID, userID, d.timeValue(issuedAt)).Scan(&revoked)
+	return revoked, err
+}
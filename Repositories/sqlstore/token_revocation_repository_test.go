@@ -0,0 +1,21 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"task_management/Repositories/repotest"
+	"task_management/Repositories/sqlstore"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTokenRevocationRepositorySuite(t *testing.T) {
+	runDialects(t, func(dialect, dsn string) suite.TestingSuite {
+		return &repotest.TokenRevocationRepoContract{
+			NewRepo: func(t *testing.T) usecases.ITokenRevocationRepo {
+				return sqlstore.NewTokenRevocationRepository(openTestDB(t, dialect, dsn), 0)
+			},
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// UserRepository keeps the users in the users table, usernames are unique
+type UserRepository struct {
+	DB *DB
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewUserRepository(db *DB, timeout time.Duration) usecases.IUserRepository {
+	return &UserRepository{DB: db, Timeout: timeout}
+}
+
+// inserts a new user
+func (r *UserRepository) CreateUser(ctx context.Context, user *domain.User) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	id := domain.NewID()
+	_, err := r.DB.sql.ExecContext(ctx, r.DB.dialect.rebind("INSERT INTO users (id, username, password, role) VALUES (?, ?, ?, ?)"),
+		id, user.Username, user.Password, user.Role)
+	if isUniqueViolation(err) {
+		return domain.Conflict("username already exists")
+	}
+	if err != nil {
+		return err
+	}
+	user.ID = id
+	return nil
+}
+
+// retrieves a user based on the given username
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	return r.findOne(ctx, "username = ?", username)
+}
+
+// retrieves a user based on the given id
+func (r *UserRepository) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	if !domain.IsValidID(userID) {
+		return nil, domain.Validation("invalid user id")
+	}
+	return r.findOne(ctx, "id = ?", userID)
+}
+
+// the columns scanned by scanUser, in order
+const userColumns = "id, username, password, role, deactivated_at"
+
+func (r *UserRepository) findOne(ctx context.Context, where string, arg any) (*domain.User, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	user, err := scanUser(r.DB.sql.QueryRowContext(ctx, r.DB.dialect.rebind("SELECT "+userColumns+" FROM users WHERE "+where), arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func scanUser(row interface{ Scan(dest ...any) error }) (*domain.User, error) {
+	var user domain.User
+	var role string
+	var deactivatedAt nullTime
+	if err := row.Scan(&user.ID, &user.Username, &user.Password, &role, &deactivatedAt); err != nil {
+		return nil, err
+	}
+	user.Role = domain.Role(role)
+	user.DeactivatedAt = deactivatedAt.Time
+	return &user, nil
+}
+
+// lists one page of the users matching the filter, ordered by username
+func (r *UserRepository) ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserPage, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	d := r.DB.dialect
+	var conditions []string
+	var args []any
+	if filter.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, string(filter.Role))
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, `LOWER(username) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(strings.ToLower(filter.Search))+"%")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.DB.sql.QueryRowContext(ctx, d.rebind("SELECT COUNT(*) FROM users"+where), args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	page, pageArgs := d.limitOffset(filter.Limit, offset)
+	query := "SELECT " + userColumns + " FROM users" + where + " ORDER BY " + d.orderBy("username", false) + page
+	rows, err := r.DB.sql.QueryContext(ctx, d.rebind(query), append(args, pageArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &domain.UserPage{Users: users, Total: total}
+	if next := offset + int64(len(users)); len(users) > 0 && next < total {
+		result.NextCursor = domain.EncodeCursor(next)
+	}
+	return result, nil
+}
+
+// counts the number of users that matches the username
+func (r *UserRepository) CountByUsername(ctx context.Context, username string) (int64, error) {
+	return r.count(ctx, " WHERE username = ?", username)
+}
+
+// counts the total number of users
+func (r *UserRepository) CountAll(ctx context.Context) (int64, error) {
+	return r.count(ctx, "")
+}
+
+func (r *UserRepository) count(ctx context.Context, where string, args ...any) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	var count int64
+	err := r.DB.sql.QueryRowContext(ctx, r.DB.dialect.rebind("SELECT COUNT(*) FROM users"+where), args...).Scan(&count)
+	return count, err
+}
+
+// counts the active users that have the role
+func (r *UserRepository) CountByRole(ctx context.Context, role domain.Role) (int64, error) {
+	return r.count(ctx, " WHERE role = ? AND deactivated_at IS NULL", string(role))
+}
+
+// sets the role of the user with the id provided
+func (r *UserRepository) UpdateRole(ctx context.Context, userID string, role domain.Role) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user id")
+	}
+	const query = "UPDATE users SET role = ? WHERE id = ?"
+	if role != domain.RoleAdmin {
+		return r.updateKeepingAdmin(ctx, userID, query, string(role), userID)
+	}
+	return r.updateOne(ctx, query, string(role), userID)
+}
+
+// sets or clears the deactivation time of the user with the id provided
+func (r *UserRepository) UpdateDeactivatedAt(ctx context.Context, userID string, at *time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user id")
+	}
+	query, value := "UPDATE users SET deactivated_at = ? WHERE id = ?", r.DB.dialect.nullTimeValue(at)
+	if at != nil {
+		return r.updateKeepingAdmin(ctx, userID, query, value, userID)
+	}
+	return r.updateOne(ctx, query, value, userID)
+}
+
+// removes the user with the id provided
+func (r *UserRepository) DeleteUser(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user id")
+	}
+	return r.updateKeepingAdmin(ctx, userID, "DELETE FROM users WHERE id = ?", userID)
+}
+
+// updateKeepingAdmin runs a statement that takes the admin role or the account away from
+// one user, it is refused when the user is the last active admin. the active admins are
+// locked until the statement ran so two concurrent changes cannot both pass the check
+func (r *UserRepository) updateKeepingAdmin(ctx context.Context, userID, query string, args ...any) error {
+	return r.DB.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, r.DB.dialect.rebind("SELECT id FROM users WHERE role = ? AND deactivated_at IS NULL"+r.DB.dialect.forUpdate()), string(domain.RoleAdmin))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		admin, others := false, 0
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			if id == userID {
+				admin = true
+			} else {
+				others++
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if admin && others == 0 {
+			return domain.ErrLastAdmin
+		}
+
+		updated, err := rowsAffected(tx.ExecContext(ctx, r.DB.dialect.rebind(query), args...))
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return domain.NotFound("user not found")
+		}
+		return nil
+	})
+}
+
+// updateOne runs a statement on one user and reports a missing user as not found
+func (r *UserRepository) updateOne(ctx context.Context, query string, args ...any) error {
+	updated, err := rowsAffected(r.DB.sql.ExecContext(ctx, r.DB.dialect.rebind(query), args...))
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return domain.NotFound("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"task_management/Repositories/repotest"
+	"task_management/Repositories/sqlstore"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestUserRepositorySuite(t *testing.T) {
+	runDialects(t, func(dialect, dsn string) suite.TestingSuite {
+		return &repotest.UserRepoContract{
+			NewRepo: func(t *testing.T) usecases.IUserRepository {
+				return sqlstore.NewUserRepository(openTestDB(t, dialect, dsn), 0)
+			},
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// MemoryTaskRepository keeps the tasks in memory, it behaves like the mongo
+// repository and is safe for concurrent use. everything is lost on restart
+type MemoryTaskRepository struct {
+	mu    sync.RWMutex
+	tasks map[string]*domain.Task
+}
+
+func NewMemoryTaskRepository() usecases.ITaskRepo {
+	return &MemoryTaskRepository{tasks: map[string]*domain.Task{}}
+}
+
+// function to create a new task
+func (r *MemoryTaskRepository) CreateTask(ctx context.Context, task *domain.Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if task.ID == "" {
+		task.ID = domain.NewID()
+	}
+	if !domain.IsValidID(task.ID) {
+		return domain.Validation("invalid task ID")
+	}
+	if _, ok := r.tasks[task.ID]; ok {
+		return domain.Conflict("task already exists")
+	}
+	r.tasks[task.ID] = copyTask(task)
+	return nil
+}
+
+// function to get one page of the tasks matching the filter
+func (r *MemoryTaskRepository) GetAllTasks(ctx context.Context, filter domain.TaskFilter) (*domain.TaskPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matching := make([]*domain.Task, 0)
+	for _, task := range r.tasks {
+		if matchesTaskFilter(task, filter) {
+			matching = append(matching, task)
+		}
+	}
+	sortTasks(matching, filter)
+
+	total := int64(len(matching))
+	tasks := make([]domain.Task, 0)
+	for i := offset; i < total && (filter.Limit <= 0 || int64(len(tasks)) < filter.Limit); i++ {
+		tasks = append(tasks, *copyTask(matching[i]))
+	}
+
+	page := &domain.TaskPage{Tasks: tasks, Total: total}
+	if next := offset + int64(len(tasks)); len(tasks) > 0 && next < total {
+		page.NextCursor = domain.EncodeCursor(next)
+	}
+	return page, nil
+}
+
+// matchesTaskFilter applies the same conditions as the mongo query of buildTaskFilter
+func matchesTaskFilter(task *domain.Task, filter domain.TaskFilter) bool {
+	if task.IsTrashed() != filter.Trashed {
+		return false
+	}
+	if filter.OwnerID != "" && task.OwnerID != filter.OwnerID {
+		return false
+	}
+	if filter.AssigneeID != "" && !task.IsAssignee(filter.AssigneeID) {
+		return false
+	}
+	if filter.VisibleTo != "" && task.OwnerID != filter.VisibleTo && !task.IsAssignee(filter.VisibleTo) {
+		return false
+	}
+	if filter.Status != "" && task.Status != filter.Status {
+		return false
+	}
+	if filter.ExcludeStatus != "" && task.Status == filter.ExcludeStatus {
+		return false
+	}
+	if filter.TitleContains != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(filter.TitleContains)) {
+		return false
+	}
+	//tasks without a due date never match a due date range
+	if filter.DueAfter != nil || filter.DueBefore != nil {
+		if task.DueDate == nil {
+			return false
+		}
+		if filter.DueAfter != nil && task.DueDate.Before(*filter.DueAfter) {
+			return false
+		}
+		if filter.DueBefore != nil && task.DueDate.After(*filter.DueBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTasks orders like the mongo repository: by the requested field with the id breaking ties,
+// tasks without a due date come first in ascending order
+func sortTasks(tasks []*domain.Task, filter domain.TaskFilter) {
+	compare := func(a, b *domain.Task) int {
+		switch filter.SortBy {
+		case domain.SortByTitle:
+			return strings.Compare(a.Title, b.Title)
+		case domain.SortByStatus:
+			return strings.Compare(string(a.Status), string(b.Status))
+		case domain.SortByDueDate:
+			return compareDueDates(a.DueDate, b.DueDate)
+		}
+		return 0
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		c := compare(tasks[i], tasks[j])
+		if c == 0 {
+			c = strings.Compare(tasks[i].ID, tasks[j].ID)
+		}
+		if filter.SortDesc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func compareDueDates(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(*b)
+}
+
+// function to get task by id
+func (r *MemoryTaskRepository) GetTaskByID(ctx context.Context, taskID string) (*domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !domain.IsValidID(taskID) {
+		return nil, domain.Validation("invalid task ID")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return nil, domain.NotFound("task not found")
+	}
+	return copyTask(task), nil
+}
+
+// function to update only the listed fields of a task, a nil due date is removed
+func (r *MemoryTaskRepository) UpdateTaskFields(ctx context.Context, taskID string, task *domain.Task, fields []string, version int64) error {
+	var transition domain.StatusTransition
+	for _, field := range fields {
+		switch field {
+		case "title", "description", "dueDate", "ownerId":
+		case "status":
+			var ok bool
+			if transition, ok = task.LastTransition(); !ok {
+				return domain.Validation("a status change needs its transition in the status history")
+			}
+		default:
+			return domain.Validation(fmt.Sprintf("field %q cannot be updated", field))
+		}
+	}
+	return r.update(ctx, taskID, version, func(stored *domain.Task) error {
+		for _, field := range fields {
+			switch field {
+			case "title":
+				stored.Title = task.Title
+			case "description":
+				stored.Description = task.Description
+			case "dueDate":
+				stored.DueDate = copyTime(task.DueDate)
+			case "ownerId":
+				stored.OwnerID = task.OwnerID
+			case "status":
+				stored.Status = task.Status
+				stored.StatusHistory = append(stored.StatusHistory, transition)
+			}
+		}
+		return nil
+	})
+}
+
+// function to delete task by id
+func (r *MemoryTaskRepository) DeleteTaskByID(ctx context.Context, taskID string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok || task.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	delete(r.tasks, taskID)
+	return nil
+}
+
+// function to move a task to the trash
+func (r *MemoryTaskRepository) TrashTaskByID(ctx context.Context, taskID string, deletedBy string, at time.Time, version int64) error {
+	return r.update(ctx, taskID, version, func(task *domain.Task) error {
+		task.DeletedAt = &at
+		task.DeletedBy = deletedBy
+		return nil
+	})
+}
+
+// function to take a task back out of the trash
+func (r *MemoryTaskRepository) RestoreTaskByID(ctx context.Context, taskID string, version int64) error {
+	return r.update(ctx, taskID, version, func(task *domain.Task) error {
+		task.DeletedAt = nil
+		task.DeletedBy = ""
+		return nil
+	})
+}
+
+// function to permanently remove the tasks trashed before the cutoff
+func (r *MemoryTaskRepository) PurgeTrashedBefore(ctx context.Context, cutoff time.Time) ([]domain.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := []domain.Task{}
+	for id, task := range r.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(cutoff) {
+			delete(r.tasks, id)
+			purged = append(purged, *copyTask(task))
+		}
+	}
+	return purged, nil
+}
+
+// function to move a task to another status and record the transition.
+// the status only changes if the task is still in transition.From at the given version
+func (r *MemoryTaskRepository) TransitionTaskStatus(ctx context.Context, taskID string, transition domain.StatusTransition, version int64) error {
+	return r.update(ctx, taskID, version, func(task *domain.Task) error {
+		if task.Status != transition.From {
+			return domain.ErrVersionMismatch
+		}
+		task.Status = transition.To
+		task.StatusHistory = append(task.StatusHistory, transition)
+		return nil
+	})
+}
+
+// function to add a user to the task assignees, adding the same user twice is a no-op
+func (r *MemoryTaskRepository) AddAssignee(ctx context.Context, taskID string, userID string) error {
+	return r.updateAssignees(ctx, taskID, func(task *domain.Task) {
+		if !task.IsAssignee(userID) {
+			task.Assignees = append(task.Assignees, userID)
+		}
+	})
+}
+
+// function to remove a user from the task assignees
+func (r *MemoryTaskRepository) RemoveAssignee(ctx context.Context, taskID string, userID string) error {
+	return r.updateAssignees(ctx, taskID, func(task *domain.Task) {
+		assignees := make([]string, 0, len(task.Assignees))
+		for _, id := range task.Assignees {
+			if id != userID {
+				assignees = append(assignees, id)
+			}
+		}
+		task.Assignees = assignees
+	})
+}
+
+func (r *MemoryTaskRepository) updateAssignees(ctx context.Context, taskID string, change func(task *domain.Task)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, ok := r.tasks[taskID]
+	if !ok {
+		return domain.NotFound("task not found")
+	}
+	change(task)
+	task.Version++
+	return nil
+}
+
+// update applies change to the task while it is still at the given version and bumps the version,
+// the stored task is left untouched when change fails
+func (r *MemoryTaskRepository) update(ctx context.Context, taskID string, version int64, change func(task *domain.Task) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(taskID) {
+		return domain.Validation("invalid task ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tasks[taskID]
+	if !ok || stored.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	task := copyTask(stored)
+	if err := change(task); err != nil {
+		return err
+	}
+	task.Version++
+	r.tasks[taskID] = task
+	return nil
+}
+
+// copyTask copies a task so callers never share memory with the stored one
+func copyTask(task *domain.Task) *domain.Task {
+	c := *task
+	c.DueDate = copyTime(task.DueDate)
+	c.DeletedAt = copyTime(task.DeletedAt)
+	if task.Assignees != nil {
+		c.Assignees = append([]string{}, task.Assignees...)
+	}
+	if task.StatusHistory != nil {
+		c.StatusHistory = append([]domain.StatusTransition{}, task.StatusHistory...)
+	}
+	return &c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,17 @@
+package repositories_test
+
+import (
+	"testing"
+
+	repositories "task_management/Repositories"
+	"task_management/Repositories/repotest"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMemoryTaskRepositorySuite(t *testing.T) {
+	suite.Run(t, &repotest.TaskRepoContract{
+		NewRepo: func(*testing.T) usecases.ITaskRepo { return repositories.NewMemoryTaskRepository() },
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"time"
 	domain "task_management/Domain"
 	"task_management/db"
 	"task_management/usecases"
@@ -22,100 +24,375 @@ type ITaskMongoCollection interface {
 	CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
 	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
 	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult
 }
+// taskDocument is a task as stored in mongo, the id is kept as an object id
+type taskDocument struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	domain.Task `bson:",inline"`
+}
+
+func (d *taskDocument) toDomain() *domain.Task {
+	task := d.Task
+	task.ID = d.ID.Hex()
+	return &task
+}
+
 type TaskRepository struct {
 	Collection ITaskMongoCollection
-	 Context context.Context
-	
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
 }
-func NewTaskRepository() usecases.ITaskRepo {
+func NewTaskRepository(timeout time.Duration) usecases.ITaskRepo {
 	col:=db.GetTasksCollection()
-	ctx:= context.Background()
 	return &TaskRepository{
 		Collection: col,
-		Context: ctx,
+		Timeout: timeout,
 	}
 }
 // function to create a new task in the database
-func ( r *TaskRepository) CreateTask(task *domain.Task) error{
-	_,err:= r.Collection.InsertOne(r.Context,task)
+func (r *TaskRepository) CreateTask(ctx context.Context, task *domain.Task) error{
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	if task.ID == "" {
+		task.ID = domain.NewID()
+	}
+	objID, err := primitive.ObjectIDFromHex(task.ID)
+	if err != nil {
+		return domain.Validation("invalid task ID")
+	}
+	_,err = r.Collection.InsertOne(ctx, &taskDocument{ID: objID, Task: *task})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Conflict("task already exists")
+	}
 	return err
 }
 
-//function to get all tasks 
-func (r *TaskRepository) GetAllTasks() ([]domain.Task, error) {
-    // Initialize empty slice to return empty slice in case of no tasks
-    tasks := make([]domain.Task, 0)
+//function to get one page of the tasks matching the filter
+func (r *TaskRepository) GetAllTasks(ctx context.Context, filter domain.TaskFilter) (*domain.TaskPage, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+    offset, err := domain.DecodeCursor(filter.Cursor)
+    if err != nil {
+        return nil, err
+    }
+    query := buildTaskFilter(filter)
 
-    cur, err := r.Collection.Find(r.Context, bson.M{})
+    total, err := r.Collection.CountDocuments(ctx, query)
+    if err != nil {
+        return nil, fmt.Errorf("failed to count tasks: %v", err)
+    }
+
+
+    cur, err := r.Collection.Find(ctx, query, buildTaskFindOptions(filter, offset))
     if err != nil {
         return nil, fmt.Errorf("failed to fetch tasks: %v", err)  
     }
-    defer cur.Close(r.Context)
+    defer cur.Close(ctx)
 
     // Decode all 
-    if err := cur.All(r.Context, &tasks); err != nil {
+    var docs []taskDocument
+    if err := cur.All(ctx, &docs); err != nil {
         return nil, fmt.Errorf("failed to decode tasks: %v", err)
     }
+    // Initialize empty slice to return empty slice in case of no tasks
+    tasks := make([]domain.Task, 0, len(docs))
+    for i := range docs {
+        tasks = append(tasks, *docs[i].toDomain())
+    }
 
-    return tasks, nil
+    page := &domain.TaskPage{Tasks: tasks, Total: total}
+    if next := offset + int64(len(tasks)); len(tasks) > 0 && next < total {
+        page.NextCursor = domain.EncodeCursor(next)
+    }
+    return page, nil
+}
+
+// translates the domain filter into a mongo query
+func buildTaskFilter(filter domain.TaskFilter) bson.M {
+	//trashed tasks only show up when the trash is listed
+	query := bson.M{"deletedAt": nil}
+	if filter.Trashed {
+		query["deletedAt"] = bson.M{"$ne": nil}
+	}
+	if filter.OwnerID != "" {
+		query["ownerId"] = filter.OwnerID
+	}
+	if filter.AssigneeID != "" {
+		query["assignees"] = filter.AssigneeID
+	}
+	if filter.VisibleTo != "" {
+		query["$or"] = bson.A{
+			bson.M{"ownerId": filter.VisibleTo},
+			bson.M{"assignees": filter.VisibleTo},
+		}
+	}
+	switch {
+	case filter.Status != "" && filter.ExcludeStatus != "":
+		query["status"] = bson.M{"$eq": filter.Status, "$ne": filter.ExcludeStatus}
+	case filter.Status != "":
+		query["status"] = filter.Status
+	case filter.ExcludeStatus != "":
+		query["status"] = bson.M{"$ne": filter.ExcludeStatus}
+	}
+	if filter.TitleContains != "" {
+		query["title"] = primitive.Regex{Pattern: regexp.QuoteMeta(filter.TitleContains), Options: "i"}
+	}
+	if filter.DueAfter != nil || filter.DueBefore != nil {
+		due := bson.M{}
+		if filter.DueAfter != nil {
+			due["$gte"] = *filter.DueAfter
+		}
+		if filter.DueBefore != nil {
+			due["$lte"] = *filter.DueBefore
+		}
+		query["dueDate"] = due
+	}
+	return query
 }
+
+// sorts by the requested field, the id breaks ties so pages never overlap
+func buildTaskFindOptions(filter domain.TaskFilter, offset int64) *options.FindOptions {
+	direction := 1
+	if filter.SortDesc {
+		direction = -1
+	}
+	sort := bson.D{}
+	if filter.SortBy != "" && filter.SortBy != domain.SortByID {
+		sort = append(sort, bson.E{Key: filter.SortBy, Value: direction})
+	}
+	sort = append(sort, bson.E{Key: "_id", Value: direction})
+
+	opts := options.Find().SetSort(sort).SetSkip(offset)
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	return opts
+}
+
 //function to get task by id
-func (r *TaskRepository) GetTaskByID( taskID string) (*domain.Task, error) {
+func (r *TaskRepository) GetTaskByID(ctx context.Context, taskID string) (*domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
 	//check id 
 	objID, err := primitive.ObjectIDFromHex(taskID)
 	if err != nil {
-		return nil, errors.New("invalid task ID")
+		return nil, domain.Validation("invalid task ID")
 	}
 
 	//find task mapped with that id 
 
-	var task domain.Task
-	err = r.Collection.FindOne(r.Context, bson.M{"_id": objID}).Decode(&task)
+	var doc taskDocument
+	err = r.Collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.NotFound("task not found")
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &task, nil
+	return doc.toDomain(), nil
 }
-//function to update task by id 
-
-func (r *TaskRepository) UpdateTaskByID(taskID string, updatedTask *domain.Task) error {
+//function to update only the listed fields of a task, a nil due date is removed
+func (r *TaskRepository) UpdateTaskFields(ctx context.Context, taskID string, task *domain.Task, fields []string, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
 	objID, err := primitive.ObjectIDFromHex(taskID)
 	if err != nil {
-		return errors.New("invalid task ID")
+		return domain.Validation("invalid task ID")
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"title":       updatedTask.Title,
-			"description": updatedTask.Description,
-			"status":   updatedTask.Status,
-		},
+	set := bson.M{}
+	unset := bson.M{}
+	push := bson.M{}
+	for _, field := range fields {
+		switch field {
+		case "title":
+			set["title"] = task.Title
+		case "description":
+			set["description"] = task.Description
+		case "dueDate":
+			if task.DueDate == nil {
+				unset["dueDate"] = ""
+			} else {
+				set["dueDate"] = task.DueDate
+			}
+		case "ownerId":
+			set["ownerId"] = task.OwnerID
+		case "status":
+			transition, ok := task.LastTransition()
+			if !ok {
+				return domain.Validation("a status change needs its transition in the status history")
+			}
+			set["status"] = task.Status
+			push["statusHistory"] = transition
+		default:
+			return domain.Validation(fmt.Sprintf("field %q cannot be updated", field))
+		}
+	}
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(push) > 0 {
+		update["$push"] = push
 	}
 
-	result, err := r.Collection.UpdateOne(r.Context, bson.M{"_id": objID}, update)
+	result, err := r.Collection.UpdateOne(ctx, versionFilter(objID, version), update)
 	if err != nil {
 		return err
 	}
-	//count and if 0 it means no task
 	if result.MatchedCount == 0 {
-		return errors.New("task not found")
+		return domain.ErrVersionMismatch
 	}
 	return nil
 }
+
 //function to delete task by id
-func (r *TaskRepository) DeleteTaskByID( taskID string) error {
+func (r *TaskRepository) DeleteTaskByID(ctx context.Context, taskID string, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
 	objID, err := primitive.ObjectIDFromHex(taskID)
 	if err != nil {
-		return errors.New("invalid task ID")
+		return domain.Validation("invalid task ID")
 	}
 
-	result, err := r.Collection.DeleteOne(r.Context, bson.M{"_id": objID})
+	result, err := r.Collection.DeleteOne(ctx, versionFilter(objID, version))
 	if err != nil {
 		return err
 	}
 	if result.DeletedCount == 0 {
-		return errors.New("task not found")
+		return domain.ErrVersionMismatch
 	}
 	return nil
-}
\ No newline at end of file
+}
+//function to move a task to the trash
+func (r *TaskRepository) TrashTaskByID(ctx context.Context, taskID string, deletedBy string, at time.Time, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return domain.Validation("invalid task ID")
+	}
+
+	update := bson.M{
+		"$set": bson.M{"deletedAt": at, "deletedBy": deletedBy},
+		"$inc": bson.M{"version": 1},
+	}
+	result, err := r.Collection.UpdateOne(ctx, versionFilter(objID, version), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+//function to take a task back out of the trash
+func (r *TaskRepository) RestoreTaskByID(ctx context.Context, taskID string, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return domain.Validation("invalid task ID")
+	}
+
+	update := bson.M{
+		"$unset": bson.M{"deletedAt": "", "deletedBy": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := r.Collection.UpdateOne(ctx, versionFilter(objID, version), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+//function to permanently remove the tasks trashed before the cutoff
+func (r *TaskRepository) PurgeTrashedBefore(ctx context.Context, cutoff time.Time) ([]domain.Task, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	//one task at a time, a task is only returned once it is removed
+	purged := []domain.Task{}
+	for {
+		var doc taskDocument
+		err := r.Collection.FindOneAndDelete(ctx, bson.M{"deletedAt": bson.M{"$lt": cutoff}}).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return purged, nil
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, *doc.toDomain())
+	}
+}
+
+//function to move a task to another status and record the transition.
+//the status only changes if the task is still in transition.From at the given version
+func (r *TaskRepository) TransitionTaskStatus(ctx context.Context, taskID string, transition domain.StatusTransition, version int64) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return domain.Validation("invalid task ID")
+	}
+
+	filter := versionFilter(objID, version)
+	filter["status"] = transition.From
+	update := bson.M{
+		"$set":  bson.M{"status": transition.To},
+		"$push": bson.M{"statusHistory": transition},
+		"$inc":  bson.M{"version": 1},
+	}
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+//function to add a user to the task assignees, adding the same user twice is a no-op
+func (r *TaskRepository) AddAssignee(ctx context.Context, taskID string, userID string) error {
+	return r.updateAssignees(ctx, taskID, bson.M{"$addToSet": bson.M{"assignees": userID}, "$inc": bson.M{"version": 1}})
+}
+
+//function to remove a user from the task assignees
+func (r *TaskRepository) RemoveAssignee(ctx context.Context, taskID string, userID string) error {
+	return r.updateAssignees(ctx, taskID, bson.M{"$pull": bson.M{"assignees": userID}, "$inc": bson.M{"version": 1}})
+}
+
+func (r *TaskRepository) updateAssignees(ctx context.Context, taskID string, update bson.M) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(taskID)
+	if err != nil {
+		return domain.Validation("invalid task ID")
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.NotFound("task not found")
+	}
+	return nil
+}
+
+// matches the task only while it is still at the given version.
+// tasks stored before versioning have no version field and count as version 0
+func versionFilter(objID primitive.ObjectID, version int64) bson.M {
+	if version == 0 {
+		return bson.M{"_id": objID, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": objID, "version": version}
+}
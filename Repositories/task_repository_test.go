@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 	
 
 	domain "task_management/Domain"
@@ -59,6 +60,24 @@ func (m *MockTaskCollection) DeleteOne(ctx context.Context, filter interface{},
 	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
 }
 
+func (m *MockTaskCollection) FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*mongo.SingleResult)
+}
+
+// insertedDocument decodes the document handed to InsertOne
+func insertedDocument(args mock.Arguments) bson.M {
+	raw, err := bson.Marshal(args.Get(1))
+	if err != nil {
+		panic(err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		panic(err)
+	}
+	return doc
+}
+
 // MockCursor mocks the MongoDB Cursor
 type MockCursor struct {
 	mock.Mock
@@ -119,7 +138,6 @@ func (suite *TaskRepositoryTestSuite) SetupTest() {
 	suite.mockContext = context.Background()
 	suite.repo = &repositories.TaskRepository{
 		Collection: suite.mockCol,
-		Context:    suite.mockContext,
 	}
 }
 
@@ -140,16 +158,16 @@ func (suite *TaskRepositoryTestSuite) TestCreateTask() {
 
 		suite.mockCol.On("InsertOne",
 			suite.mockContext,
-			mock.AnythingOfType("*domain.Task"),
+			mock.AnythingOfType("*repositories.taskDocument"),
 		).Return(&mongo.InsertOneResult{InsertedID: primitive.NewObjectID()}, nil).Run(func(args mock.Arguments) {
-			insertedTask := args.Get(1).(*domain.Task)
-			suite.NotNil(insertedTask.ID)
-			suite.Equal(task.Title, insertedTask.Title)
-			suite.Equal(task.Description, insertedTask.Description)
-			suite.Equal(task.Status, insertedTask.Status)
+			insertedTask := insertedDocument(args)
+			suite.IsType(primitive.ObjectID{}, insertedTask["_id"])
+			suite.Equal(task.Title, insertedTask["title"])
+			suite.Equal(task.Description, insertedTask["description"])
+			suite.Equal(string(task.Status), insertedTask["status"])
 		}).Once()
 
-		err := suite.repo.CreateTask(task)
+		err := suite.repo.CreateTask(suite.mockContext, task)
 		suite.NoError(err)
 		suite.mockCol.AssertExpectations(suite.T())
 	})
@@ -160,12 +178,371 @@ func (suite *TaskRepositoryTestSuite) TestCreateTask() {
 
 		suite.mockCol.On("InsertOne",
 			suite.mockContext,
-			mock.AnythingOfType("*domain.Task"),
+			mock.AnythingOfType("*repositories.taskDocument"),
 		).Return(nil, errors.New("db insert error")).Once()
 
-		err := suite.repo.CreateTask(task)
+		err := suite.repo.CreateTask(suite.mockContext, task)
 		suite.Error(err)
 		suite.EqualError(err, "db insert error")
 		suite.mockCol.AssertExpectations(suite.T())
 	})
 }
+
+func (suite *TaskRepositoryTestSuite) TestOperationTimeout() {
+	type ctxKey struct{}
+
+	suite.Run("Timeout bounds the call", func() {
+		suite.SetupTest()
+		suite.repo.Timeout = time.Minute
+		callerCtx := context.WithValue(suite.mockContext, ctxKey{}, "request")
+
+		suite.mockCol.On("InsertOne",
+			mock.MatchedBy(func(ctx context.Context) bool {
+				deadline, ok := ctx.Deadline()
+				//the operation context is derived from the caller's context
+				return ok && time.Until(deadline) <= time.Minute && ctx.Value(ctxKey{}) == "request"
+			}),
+			mock.AnythingOfType("*repositories.taskDocument"),
+		).Return(&mongo.InsertOneResult{}, nil).Once()
+
+		err := suite.repo.CreateTask(callerCtx, &domain.Task{Title: "Test Task"})
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	suite.Run("Cancelled caller", func() {
+		suite.SetupTest()
+		suite.repo.Timeout = time.Minute
+		ctx, cancel := context.WithCancel(suite.mockContext)
+		cancel()
+
+		suite.mockCol.On("FindOneAndDelete",
+			mock.MatchedBy(func(ctx context.Context) bool { return errors.Is(ctx.Err(), context.Canceled) }),
+			mock.Anything,
+		).Return(mongo.NewSingleResultFromDocument(bson.M{}, context.Canceled, nil)).Once()
+
+		_, err := suite.repo.PurgeTrashedBefore(ctx, time.Now())
+		suite.ErrorIs(err, context.Canceled)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestGetAllTasks() {
+	ownerID := domain.NewID()
+	storedID := primitive.NewObjectID()
+	stored := bson.M{"_id": storedID, "title": "Mine", "ownerId": ownerID}
+
+	// Test Case 1  Tasks are filtered by owner
+	suite.Run("Filter by owner", func() {
+		suite.SetupTest()
+
+		query := bson.M{"deletedAt": nil, "ownerId": ownerID}
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{stored}, nil, nil)
+		suite.Require().NoError(err)
+		suite.mockCol.On("CountDocuments", suite.mockContext, query).Return(int64(1), nil).Once()
+		suite.mockCol.On("Find", suite.mockContext, query).Return(cursor, nil).Once()
+
+		page, err := suite.repo.GetAllTasks(suite.mockContext, domain.TaskFilter{OwnerID: ownerID})
+		suite.NoError(err)
+		suite.Len(page.Tasks, 1)
+		suite.Equal(int64(1), page.Total)
+		suite.Empty(page.NextCursor)
+		suite.Equal(storedID.Hex(), page.Tasks[0].ID)
+		suite.Equal(ownerID, page.Tasks[0].OwnerID)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 2  Empty filter returns everything
+	suite.Run("No filter", func() {
+		suite.SetupTest()
+
+		cursor, err := mongo.NewCursorFromDocuments(nil, nil, nil)
+		suite.Require().NoError(err)
+		query := bson.M{"deletedAt": nil}
+		suite.mockCol.On("CountDocuments", suite.mockContext, query).Return(int64(0), nil).Once()
+		suite.mockCol.On("Find", suite.mockContext, query).Return(cursor, nil).Once()
+
+		page, err := suite.repo.GetAllTasks(suite.mockContext, domain.TaskFilter{})
+		suite.NoError(err)
+		suite.NotNil(page.Tasks)
+		suite.Empty(page.Tasks)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 3  A cursor is returned while more tasks are left
+	suite.Run("Next page", func() {
+		suite.SetupTest()
+
+		query := bson.M{
+			"deletedAt": nil,
+			"status":    domain.StatusInProgress,
+			"title":     primitive.Regex{Pattern: `report\.pdf`, Options: "i"},
+		}
+		cursor, err := mongo.NewCursorFromDocuments([]interface{}{stored}, nil, nil)
+		suite.Require().NoError(err)
+		suite.mockCol.On("CountDocuments", suite.mockContext, query).Return(int64(5), nil).Once()
+		suite.mockCol.On("Find", suite.mockContext, query).Return(cursor, nil).Once()
+
+		page, err := suite.repo.GetAllTasks(suite.mockContext, domain.TaskFilter{
+			Status:        domain.StatusInProgress,
+			TitleContains: "report.pdf",
+			Limit:         1,
+			Cursor:        domain.EncodeCursor(2),
+		})
+		suite.NoError(err)
+		suite.Equal(int64(5), page.Total)
+		suite.Equal(domain.EncodeCursor(3), page.NextCursor)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 4  Overdue tasks are due before now and not completed
+	suite.Run("Overdue", func() {
+		suite.SetupTest()
+
+		now := time.Now()
+		query := bson.M{
+			"deletedAt": nil,
+			"status":    bson.M{"$ne": domain.StatusCompleted},
+			"dueDate":   bson.M{"$lte": now},
+		}
+		cursor, err := mongo.NewCursorFromDocuments(nil, nil, nil)
+		suite.Require().NoError(err)
+		suite.mockCol.On("CountDocuments", suite.mockContext, query).Return(int64(0), nil).Once()
+		suite.mockCol.On("Find", suite.mockContext, query).Return(cursor, nil).Once()
+
+		_, err = suite.repo.GetAllTasks(suite.mockContext, domain.TaskFilter{DueBefore: &now, ExcludeStatus: domain.StatusCompleted})
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 5  Listing the trash only returns trashed tasks
+	suite.Run("Trash", func() {
+		suite.SetupTest()
+
+		query := bson.M{"deletedAt": bson.M{"$ne": nil}, "ownerId": ownerID}
+		cursor, err := mongo.NewCursorFromDocuments(nil, nil, nil)
+		suite.Require().NoError(err)
+		suite.mockCol.On("CountDocuments", suite.mockContext, query).Return(int64(0), nil).Once()
+		suite.mockCol.On("Find", suite.mockContext, query).Return(cursor, nil).Once()
+
+		_, err = suite.repo.GetAllTasks(suite.mockContext, domain.TaskFilter{OwnerID: ownerID, Trashed: true})
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 6  Invalid cursor never reaches the database
+	suite.Run("Invalid cursor", func() {
+		suite.SetupTest()
+
+		page, err := suite.repo.GetAllTasks(suite.mockContext, domain.TaskFilter{Cursor: "not a cursor"})
+		suite.Nil(page)
+		suite.EqualError(err, "invalid cursor")
+		suite.mockCol.AssertNotCalled(suite.T(), "Find")
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestTransitionTaskStatus() {
+	taskID := primitive.NewObjectID()
+	transition := domain.StatusTransition{From: domain.StatusNotStarted, To: domain.StatusInProgress, ActorID: "actor", At: time.Now()}
+	filter := bson.M{"_id": taskID, "status": domain.StatusNotStarted, "version": int64(3)}
+	update := bson.M{
+		"$set":  bson.M{"status": domain.StatusInProgress},
+		"$push": bson.M{"statusHistory": transition},
+		"$inc":  bson.M{"version": 1},
+	}
+
+	// Test Case 1  Task still in the expected status
+	suite.Run("Success", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("UpdateOne", suite.mockContext, filter, update).Return(&mongo.UpdateResult{MatchedCount: 1, ModifiedCount: 1}, nil).Once()
+
+		err := suite.repo.TransitionTaskStatus(suite.mockContext, taskID.Hex(), transition, 3)
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 2  Status changed in the meantime
+	suite.Run("Status changed", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("UpdateOne", suite.mockContext, filter, update).Return(&mongo.UpdateResult{}, nil).Once()
+
+		err := suite.repo.TransitionTaskStatus(suite.mockContext, taskID.Hex(), transition, 3)
+		suite.ErrorIs(err, domain.ErrVersionMismatch)
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestUpdateTaskFields() {
+	taskID := primitive.NewObjectID()
+	task := &domain.Task{Title: "New title", Description: "ignored"}
+
+	// Test Case 1  Only the listed fields are set, a nil due date is unset
+	suite.Run("Success", func() {
+		suite.SetupTest()
+
+		update := bson.M{
+			"$set":   bson.M{"title": "New title"},
+			"$unset": bson.M{"dueDate": ""},
+			"$inc":   bson.M{"version": 1},
+		}
+		suite.mockCol.On("UpdateOne", suite.mockContext, bson.M{"_id": taskID, "version": int64(2)}, update).Return(&mongo.UpdateResult{MatchedCount: 1}, nil).Once()
+
+		err := suite.repo.UpdateTaskFields(suite.mockContext, taskID.Hex(), task, []string{"title", "dueDate"}, 2)
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 2  A status change pushes its transition in the same update
+	suite.Run("Status", func() {
+		suite.SetupTest()
+
+		transition := domain.StatusTransition{From: domain.StatusNotStarted, To: domain.StatusInProgress, ActorID: "actor", At: time.Now()}
+		moved := &domain.Task{Title: "New title", Status: domain.StatusInProgress, StatusHistory: []domain.StatusTransition{transition}}
+		update := bson.M{
+			"$set":  bson.M{"title": "New title", "status": domain.StatusInProgress},
+			"$push": bson.M{"statusHistory": transition},
+			"$inc":  bson.M{"version": 1},
+		}
+		suite.mockCol.On("UpdateOne", suite.mockContext, bson.M{"_id": taskID, "version": int64(2)}, update).Return(&mongo.UpdateResult{MatchedCount: 1}, nil).Once()
+
+		err := suite.repo.UpdateTaskFields(suite.mockContext, taskID.Hex(), moved, []string{"title", "status"}, 2)
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 3  A status without its transition is refused
+	suite.Run("Status without transition", func() {
+		suite.SetupTest()
+
+		err := suite.repo.UpdateTaskFields(suite.mockContext, taskID.Hex(), &domain.Task{Status: domain.StatusCompleted}, []string{"status"}, 2)
+		suite.ErrorIs(err, domain.ErrValidation)
+		suite.mockCol.AssertNotCalled(suite.T(), "UpdateOne")
+	})
+
+	// Test Case 4  Fields outside the patchable set are refused
+	suite.Run("Unknown field", func() {
+		suite.SetupTest()
+
+		err := suite.repo.UpdateTaskFields(suite.mockContext, taskID.Hex(), task, []string{"assignees"}, 2)
+		suite.EqualError(err, `field "assignees" cannot be updated`)
+		suite.mockCol.AssertNotCalled(suite.T(), "UpdateOne")
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestDeleteTaskByID() {
+	taskID := primitive.NewObjectID()
+
+	// Test Case 1  Delete at the current version
+	suite.Run("Success", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("DeleteOne", suite.mockContext, bson.M{"_id": taskID, "version": int64(4)}).Return(&mongo.DeleteResult{DeletedCount: 1}, nil).Once()
+
+		err := suite.repo.DeleteTaskByID(suite.mockContext, taskID.Hex(), 4)
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 2  Tasks stored before versioning match version 0
+	suite.Run("Unversioned task", func() {
+		suite.SetupTest()
+
+		filter := bson.M{"_id": taskID, "version": bson.M{"$in": bson.A{0, nil}}}
+		suite.mockCol.On("DeleteOne", suite.mockContext, filter).Return(&mongo.DeleteResult{DeletedCount: 1}, nil).Once()
+
+		err := suite.repo.DeleteTaskByID(suite.mockContext, taskID.Hex(), 0)
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 3  Version moved on in the meantime
+	suite.Run("Version mismatch", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("DeleteOne", suite.mockContext, bson.M{"_id": taskID, "version": int64(4)}).Return(&mongo.DeleteResult{}, nil).Once()
+
+		err := suite.repo.DeleteTaskByID(suite.mockContext, taskID.Hex(), 4)
+		suite.ErrorIs(err, domain.ErrVersionMismatch)
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestTrashTaskByID() {
+	taskID := primitive.NewObjectID()
+	at := time.Now()
+	update := bson.M{
+		"$set": bson.M{"deletedAt": at, "deletedBy": "actor"},
+		"$inc": bson.M{"version": 1},
+	}
+
+	// Test Case 1  Task is marked as deleted instead of removed
+	suite.Run("Success", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("UpdateOne", suite.mockContext, bson.M{"_id": taskID, "version": int64(2)}, update).Return(&mongo.UpdateResult{MatchedCount: 1}, nil).Once()
+
+		err := suite.repo.TrashTaskByID(suite.mockContext, taskID.Hex(), "actor", at, 2)
+		suite.NoError(err)
+		suite.mockCol.AssertExpectations(suite.T())
+		suite.mockCol.AssertNotCalled(suite.T(), "DeleteOne")
+	})
+
+	// Test Case 2  Version moved on in the meantime
+	suite.Run("Version mismatch", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("UpdateOne", suite.mockContext, bson.M{"_id": taskID, "version": int64(2)}, update).Return(&mongo.UpdateResult{}, nil).Once()
+
+		err := suite.repo.TrashTaskByID(suite.mockContext, taskID.Hex(), "actor", at, 2)
+		suite.ErrorIs(err, domain.ErrVersionMismatch)
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestPurgeTrashedBefore() {
+	cutoff := time.Now()
+
+	// Test Case 1  Only tasks trashed before the cutoff are removed
+	suite.Run("Success", func() {
+		suite.SetupTest()
+
+		filter := bson.M{"deletedAt": bson.M{"$lt": cutoff}}
+		first, second := primitive.NewObjectID(), primitive.NewObjectID()
+		suite.mockCol.On("FindOneAndDelete", suite.mockContext, filter).Return(mongo.NewSingleResultFromDocument(bson.M{"_id": first, "title": "First"}, nil, nil)).Once()
+		suite.mockCol.On("FindOneAndDelete", suite.mockContext, filter).Return(mongo.NewSingleResultFromDocument(bson.M{"_id": second, "title": "Second"}, nil, nil)).Once()
+		suite.mockCol.On("FindOneAndDelete", suite.mockContext, filter).Return(mongo.NewSingleResultFromDocument(bson.M{}, mongo.ErrNoDocuments, nil)).Once()
+
+		purged, err := suite.repo.PurgeTrashedBefore(suite.mockContext, cutoff)
+		suite.NoError(err)
+		suite.Require().Len(purged, 2)
+		suite.Equal(first.Hex(), purged[0].ID)
+		suite.Equal("Second", purged[1].Title)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+}
+
+func (suite *TaskRepositoryTestSuite) TestGetTaskByIDNotFound() {
+	taskID := primitive.NewObjectID()
+
+	// Test Case 1  Missing tasks are translated into a not found error
+	suite.Run("Not found", func() {
+		suite.SetupTest()
+
+		result := mongo.NewSingleResultFromDocument(bson.M{}, mongo.ErrNoDocuments, nil)
+		suite.mockCol.On("FindOne", suite.mockContext, bson.M{"_id": taskID}).Return(result).Once()
+
+		task, err := suite.repo.GetTaskByID(suite.mockContext, taskID.Hex())
+		suite.Nil(task)
+		suite.EqualError(err, "task not found")
+		suite.ErrorIs(err, domain.ErrNotFound)
+	})
+
+	// Test Case 2  Malformed ids are a validation error
+	suite.Run("Invalid id", func() {
+		suite.SetupTest()
+
+		task, err := suite.repo.GetTaskByID(suite.mockContext, "not-an-id")
+		suite.Nil(task)
+		suite.ErrorIs(err, domain.ErrValidation)
+		suite.mockCol.AssertNotCalled(suite.T(), "FindOne")
+	})
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// userRevocation revokes the tokens of a user issued up to At
+type userRevocation struct {
+	At        time.Time
+	ExpiresAt time.Time
+}
+
+// MemoryTokenRevocationRepository keeps the revoked tokens in memory, entries are
+// dropped once they expired. it is safe for concurrent use
+type MemoryTokenRevocationRepository struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]userRevocation
+}
+
+func NewMemoryTokenRevocationRepository() usecases.ITokenRevocationRepo {
+	return &MemoryTokenRevocationRepository{
+		tokens: map[string]time.Time{},
+		users:  map[string]userRevocation{},
+	}
+}
+
+// revokes a single access token until it expires
+func (r *MemoryTokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(tokenID) {
+		return domain.Validation("invalid token ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dropExpired()
+	if expiresAt.After(r.tokens[tokenID]) {
+		r.tokens[tokenID] = expiresAt
+	}
+	return nil
+}
+
+// revokes the tokens of the user issued up to at, a later cutoff replaces an earlier one
+func (r *MemoryTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID string, at, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user ID")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.dropExpired()
+	revocation := r.users[userID]
+	if at.After(revocation.At) {
+		revocation.At = at
+	}
+	if expiresAt.After(revocation.ExpiresAt) {
+		revocation.ExpiresAt = expiresAt
+	}
+	r.users[userID] = revocation
+	return nil
+}
+
+// reports whether the token or every token of its user was revoked
+func (r *MemoryTokenRevocationRepository) IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[tokenID]; ok {
+		return true, nil
+	}
+	revocation, ok := r.users[userID]
+	return ok && !issuedAt.After(revocation.At), nil
+}
+
+// dropExpired forgets the entries whose tokens all expired, the caller holds the lock
+func (r *MemoryTokenRevocationRepository) dropExpired() {
+	now := time.Now()
+	for tokenID, expiresAt := range r.tokens {
+		if !now.Before(expiresAt) {
+			delete(r.tokens, tokenID)
+		}
+	}
+	for userID, revocation := range r.users {
+		if !now.Before(revocation.ExpiresAt) {
+			delete(r.users, userID)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package repositories_test
+
+import (
+	"testing"
+
+	repositories "task_management/Repositories"
+	"task_management/Repositories/repotest"
+	"task_management/usecases"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMemoryTokenRevocationRepositorySuite(t *testing.T) {
+	suite.Run(t, &repotest.TokenRevocationRepoContract{
+		NewRepo: func(*testing.T) usecases.ITokenRevocationRepo {
+			return repositories.NewMemoryTokenRevocationRepository()
+		},
+	})
+}
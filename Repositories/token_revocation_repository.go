@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/db"
+	"task_management/usecases"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ITokenRevocationMongoCollection interface {
+	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+}
+
+// TokenRevocationRepository keeps the revoked tokens in mongo. Tokens holds one document
+// per revoked jti and Users one per user cutoff, the TTL indexes db.Connect creates
+// remove them once the tokens they revoke expired
+type TokenRevocationRepository struct {
+	Tokens ITokenRevocationMongoCollection
+	Users  ITokenRevocationMongoCollection
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
+}
+
+func NewTokenRevocationRepository(timeout time.Duration) usecases.ITokenRevocationRepo {
+	return &TokenRevocationRepository{
+		Tokens:  db.GetRevokedTokensCollection(),
+		Users:   db.GetUserRevocationsCollection(),
+		Timeout: timeout,
+	}
+}
+
+// revokes a single access token until it expires
+func (r *TokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return domain.Validation("invalid token ID")
+	}
+
+	update := bson.M{"$max": bson.M{"expiresAt": expiresAt}}
+	_, err = r.Tokens.UpdateOne(ctx, bson.M{"_id": objID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// revokes the tokens of the user issued up to at, a later cutoff replaces an earlier one
+func (r *TokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID string, at, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.Validation("invalid user ID")
+	}
+
+	update := bson.M{"$max": bson.M{"revokedAt": at, "expiresAt": expiresAt}}
+	_, err = r.Users.UpdateOne(ctx, bson.M{"_id": objID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// reports whether the token or every token of its user was revoked
+func (r *TokenRevocationRepository) IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	tokenObjID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return false, domain.Validation("invalid token ID")
+	}
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, domain.Validation("invalid user ID")
+	}
+
+	revoked, err := exists(r.Tokens.FindOne(ctx, bson.M{"_id": tokenObjID}))
+	if err != nil || revoked {
+		return revoked, err
+	}
+	return exists(r.Users.FindOne(ctx, bson.M{"_id": userObjID, "revokedAt": bson.M{"$gte": issuedAt}}))
+}
+
+// exists tells a found document from a missing one
+func exists(result *mongo.SingleResult) (bool, error) {
+	err := result.Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
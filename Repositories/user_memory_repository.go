@@ -0,0 +1,244 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	domain "task_management/Domain"
+	"task_management/usecases"
+)
+
+// MemoryUserRepository keeps the users in memory, usernames are unique like
+// in the mongo collection. it is safe for concurrent use
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]*domain.User
+}
+
+func NewMemoryUserRepository() usecases.IUserRepository {
+	return &MemoryUserRepository{users: map[string]*domain.User{}}
+}
+
+// inserts a new user
+func (r *MemoryUserRepository) CreateUser(ctx context.Context, user *domain.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username {
+			return domain.Conflict("username already exists")
+		}
+	}
+	user.ID = domain.NewID()
+	r.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+// retrieves a user based on the given username
+func (r *MemoryUserRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, domain.NotFound("user not found")
+}
+
+// retrieves a user based on the given id
+func (r *MemoryUserRepository) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !domain.IsValidID(userID) {
+		return nil, domain.Validation("invalid user id")
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, domain.NotFound("user not found")
+	}
+	return cloneUser(user), nil
+}
+
+// counts the number of users that matches the username
+func (r *MemoryUserRepository) CountByUsername(ctx context.Context, username string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if user.Username == username {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// counts the total number of users
+func (r *MemoryUserRepository) CountAll(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.users)), nil
+}
+
+// counts the active users that have the role
+func (r *MemoryUserRepository) CountByRole(ctx context.Context, role domain.Role) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if user.Role == role && user.IsActive() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// lists one page of the users matching the filter, ordered by username
+func (r *MemoryUserRepository) ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := strings.ToLower(filter.Search)
+	matched := []domain.User{}
+	for _, user := range r.users {
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(user.Username), search) {
+			continue
+		}
+		matched = append(matched, *cloneUser(user))
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Username < matched[j].Username })
+
+	total := int64(len(matched))
+	start := min(offset, total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+	page := &domain.UserPage{Users: matched[start:end], Total: total}
+	if end < total {
+		page.NextCursor = domain.EncodeCursor(end)
+	}
+	return page, nil
+}
+
+// sets the role of the user with the id provided
+func (r *MemoryUserRepository) UpdateRole(ctx context.Context, userID string, role domain.Role) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user id")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return domain.NotFound("user not found")
+	}
+	if role != domain.RoleAdmin && r.isLastAdmin(user) {
+		return domain.ErrLastAdmin
+	}
+	user.Role = role
+	return nil
+}
+
+// sets or clears the deactivation time of the user with the id provided
+func (r *MemoryUserRepository) UpdateDeactivatedAt(ctx context.Context, userID string, at *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user id")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return domain.NotFound("user not found")
+	}
+	if at != nil && r.isLastAdmin(user) {
+		return domain.ErrLastAdmin
+	}
+	user.DeactivatedAt = copyTime(at)
+	return nil
+}
+
+// removes the user with the id provided
+func (r *MemoryUserRepository) DeleteUser(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !domain.IsValidID(userID) {
+		return domain.Validation("invalid user id")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return domain.NotFound("user not found")
+	}
+	if r.isLastAdmin(user) {
+		return domain.ErrLastAdmin
+	}
+	delete(r.users, userID)
+	return nil
+}
+
+// isLastAdmin reports whether user is the only active admin, the caller holds the lock
+func (r *MemoryUserRepository) isLastAdmin(user *domain.User) bool {
+	if user.Role != domain.RoleAdmin || !user.IsActive() {
+		return false
+	}
+	for _, other := range r.users {
+		if other.ID != user.ID && other.Role == domain.RoleAdmin && other.IsActive() {
+			return false
+		}
+	}
+	return true
+}
+
+// cloneUser copies a user so callers never share memory with the stored one
+func cloneUser(user *domain.User) *domain.User {
+	c := *user
+	c.DeactivatedAt = copyTime(user.DeactivatedAt)
+	return &c
+}
@@ -3,6 +3,9 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"time"
 
 	domain "task_management/Domain"
 	"task_management/db"
@@ -19,75 +22,291 @@ type IUserMongoCollection interface {
 	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
 	CountDocuments(cts context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error)
 	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+}
+
+// userDocument is a user as stored in mongo, the id is kept as an object id
+type userDocument struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	domain.User `bson:",inline"`
+}
+
+func (d *userDocument) toDomain() *domain.User {
+	user := d.User
+	user.ID = d.ID.Hex()
+	return &user
 }
 
 // implementation of userrepository and uses monogb
 
 type UserRepository struct {
 	Collection IUserMongoCollection
-	Context    context.Context
+	// Timeout bounds every operation on top of the caller's deadline, 0 means no extra bound
+	Timeout time.Duration
 }
 
 // constructor to initialize userRepositoryImpl
-func NewUserRepository() usecases.IUserRepository {
+func NewUserRepository(timeout time.Duration) usecases.IUserRepository {
 
 	col := db.GetUsersCollection()
-	ctx := context.Background()
 
 	return &UserRepository{
 		Collection: col,
-		Context:    ctx,
+		Timeout:    timeout,
 	}
 }
 
 // inserts a new user to mongodb collection
-func (r *UserRepository) CreateUser(user *domain.User) error {
-	user.ID = primitive.NewObjectID()
-
-	_, err := r.Collection.InsertOne(r.Context, user)
-	return err
+func (r *UserRepository) CreateUser(ctx context.Context, user *domain.User) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID := primitive.NewObjectID()
+	_, err := r.Collection.InsertOne(ctx, &userDocument{ID: objID, User: *user})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Conflict("username already exists")
+	}
+	if err != nil {
+		return err
+	}
+	user.ID = objID.Hex()
+	return nil
 }
 
 // retrieves a user based on the given username
-func (r *UserRepository) FindByUsername(username string) (*domain.User, error) {
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
 
-	var user domain.User
-	err := r.Collection.FindOne(r.Context, bson.M{"username": username}).Decode(&user)
+	var doc userDocument
+	err := r.Collection.FindOne(ctx, bson.M{"username": username}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain(), nil
+}
+
+// retrieves a user based on the given id
+func (r *UserRepository) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, domain.Validation("invalid user id")
+	}
+	var doc userDocument
+	err = r.Collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.NotFound("user not found")
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return doc.toDomain(), nil
 }
 
 // counts the number of users that matches the username
-func (r *UserRepository) CountByUsername(username string) (int64, error) {
-	return r.Collection.CountDocuments(r.Context, bson.M{"username": username})
+func (r *UserRepository) CountByUsername(ctx context.Context, username string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	return r.Collection.CountDocuments(ctx, bson.M{"username": username})
 }
 
 //counts the total number of user documents in the collection
 
-func (r *UserRepository) CountAll() (int64, error) {
-	return r.Collection.CountDocuments(r.Context, bson.M{})
+func (r *UserRepository) CountAll(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	return r.Collection.CountDocuments(ctx, bson.M{})
+}
+
+// counts the active users that have the role
+func (r *UserRepository) CountByRole(ctx context.Context, role domain.Role) (int64, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	return r.Collection.CountDocuments(ctx, bson.M{"role": role, "deactivatedAt": bson.M{"$exists": false}})
+}
+
+// lists one page of the users matching the filter, ordered by username
+func (r *UserRepository) ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserPage, error) {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	offset, err := domain.DecodeCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{}
+	if filter.Role != "" {
+		query["role"] = filter.Role
+	}
+	if filter.Search != "" {
+		query["username"] = primitive.Regex{Pattern: regexp.QuoteMeta(filter.Search), Options: "i"}
+	}
+
+	total, err := r.Collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "username", Value: 1}}).SetSkip(offset)
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	cur, err := r.Collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	var docs []userDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+	users := make([]domain.User, 0, len(docs))
+	for i := range docs {
+		users = append(users, *docs[i].toDomain())
+	}
+
+	page := &domain.UserPage{Users: users, Total: total}
+	if next := offset + int64(len(users)); len(users) > 0 && next < total {
+		page.NextCursor = domain.EncodeCursor(next)
+	}
+	return page, nil
 }
 
-// updates the user role to admin based the id provided
-func (r *UserRepository) PromoteUser(userID string) error {
+// sets the role of the user with the id provided
+func (r *UserRepository) UpdateRole(ctx context.Context, userID string, role domain.Role) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return errors.New("invalid user id")
+		return domain.Validation("invalid user id")
 	}
 	//filters the id
 	filter := bson.M{"_id": objID}
 	// the thing to be updated
-	update := bson.M{"$set": bson.M{"role": "Admin"}}
+	update := bson.M{"$set": bson.M{"role": role}}
 
-	result, err := r.Collection.UpdateOne(r.Context, filter, update)
+	if role != domain.RoleAdmin {
+		result, err := r.Collection.UpdateOne(ctx, activeAdmin(objID), update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount > 0 {
+			return r.keepAdmin(ctx, func(ctx context.Context) error {
+				_, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"role": domain.RoleAdmin}})
+				return err
+			})
+		}
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 	//if no result or the count is 0 it returns error
 	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+		return domain.NotFound("user not found")
+	}
+	return nil
+}
+
+// sets or clears the deactivation time of the user with the id provided
+func (r *UserRepository) UpdateDeactivatedAt(ctx context.Context, userID string, at *time.Time) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.Validation("invalid user id")
+	}
+	update := bson.M{"$unset": bson.M{"deactivatedAt": ""}}
+	if at != nil {
+		update = bson.M{"$set": bson.M{"deactivatedAt": *at}}
+
+		result, err := r.Collection.UpdateOne(ctx, activeAdmin(objID), update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount > 0 {
+			return r.keepAdmin(ctx, func(ctx context.Context) error {
+				_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$unset": bson.M{"deactivatedAt": ""}})
+				return err
+			})
+		}
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.NotFound("user not found")
+	}
+	return nil
+}
+
+// removes the user with the id provided
+func (r *UserRepository) DeleteUser(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, r.Timeout)
+	defer cancel()
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.Validation("invalid user id")
+	}
+
+	//an active admin is kept to be put back when it was the last one
+	var admin userDocument
+	err = r.Collection.FindOne(ctx, activeAdmin(objID)).Decode(&admin)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if err == nil {
+		result, err := r.Collection.DeleteOne(ctx, activeAdmin(objID))
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount > 0 {
+			return r.keepAdmin(ctx, func(ctx context.Context) error {
+				_, err := r.Collection.InsertOne(ctx, &admin)
+				return err
+			})
+		}
+	}
+
+	result, err := r.Collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.NotFound("user not found")
 	}
 	return nil
 }
+
+// activeAdmin matches the user with the id while they are an active admin
+func activeAdmin(objID primitive.ObjectID) bson.M {
+	return bson.M{"_id": objID, "role": domain.RoleAdmin, "deactivatedAt": bson.M{"$exists": false}}
+}
+
+// keepAdmin undoes a change that took the admin role or the account away from an active
+// admin when no active admin is left. mongo has no transactions on a standalone server so
+// the change is made first and checked after, two concurrent changes may both be undone
+// but they are never both kept
+func (r *UserRepository) keepAdmin(ctx context.Context, undo func(ctx context.Context) error) error {
+	admins, err := r.CountByRole(ctx, domain.RoleAdmin)
+	if err == nil && admins > 0 {
+		return nil
+	}
+	//the change is undone even when the caller's context ended
+	undoCtx, cancel := withTimeout(context.WithoutCancel(ctx), r.Timeout)
+	defer cancel()
+	if undoErr := undo(undoCtx); undoErr != nil {
+		return fmt.Errorf("failed to restore the last admin: %w", undoErr)
+	}
+	if err != nil {
+		return err
+	}
+	return domain.ErrLastAdmin
+}
@@ -9,7 +9,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
-	// "go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -49,6 +49,19 @@ func (m *MockUserCollection) UpdateOne(ctx context.Context, filter interface{},
 	return args.Get(0).(*mongo.UpdateResult), args.Error(1)
 }
 
+func (m *MockUserCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*mongo.Cursor), args.Error(1)
+}
+
+func (m *MockUserCollection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
+}
+
 
 type UserRepositoryTestSuite struct {
 	suite.Suite
@@ -62,7 +75,6 @@ func (suite *UserRepositoryTestSuite) SetupTest() {
 	suite.mockContext = context.Background()
 	suite.repo = &repositories.UserRepository{
 		Collection: suite.mockCol,
-		Context:    suite.mockContext,
 	}
 }
 
@@ -84,18 +96,19 @@ func (suite *UserRepositoryTestSuite) TestCreateUser() {
 		
 		suite.mockCol.On("InsertOne",
 			suite.mockContext,
-			mock.AnythingOfType("*domain.User"), 
+			mock.AnythingOfType("*repositories.userDocument"), 
 		).Return(&mongo.InsertOneResult{InsertedID: primitive.NewObjectID()}, nil).Run(func(args mock.Arguments) {
 			
-			insertedUser := args.Get(1).(*domain.User)
-			suite.NotNil(insertedUser.ID) 
-			suite.Equal(user.Username, insertedUser.Username)
-			suite.Equal(user.Password, insertedUser.Password)
-			suite.Equal(user.Role, insertedUser.Role)
+			insertedUser := insertedDocument(args)
+			suite.IsType(primitive.ObjectID{}, insertedUser["_id"])
+			suite.Equal(user.Username, insertedUser["username"])
+			suite.Equal(user.Password, insertedUser["password"])
+			suite.Equal(string(user.Role), insertedUser["role"])
 		}).Once()
 
-		err := suite.repo.CreateUser(user)
+		err := suite.repo.CreateUser(suite.mockContext, user)
 		suite.NoError(err)
+		suite.True(domain.IsValidID(user.ID))
 		suite.mockCol.AssertExpectations(suite.T())
 	})
 
@@ -107,10 +120,10 @@ func (suite *UserRepositoryTestSuite) TestCreateUser() {
 		
 		suite.mockCol.On("InsertOne",
 			suite.mockContext,
-			mock.AnythingOfType("*domain.User"),
+			mock.AnythingOfType("*repositories.userDocument"),
 		).Return(nil, errors.New("db insert error")).Once()
 
-		err := suite.repo.CreateUser(user)
+		err := suite.repo.CreateUser(suite.mockContext, user)
 		suite.Error(err)
 		suite.EqualError(err, "db insert error")
 		suite.mockCol.AssertExpectations(suite.T())
@@ -132,7 +145,7 @@ func (suite *UserRepositoryTestSuite) TestCreateUser() {
 
 // 		mockSingleResult := new(MockableSingleResult)
 		
-// 		mockSingleResult.On("Decode", mock.AnythingOfType("*domain.User")).Return(expectedUser, nil).Once()
+// 		mockSingleResult.On("Decode", mock.AnythingOfType("*repositories.userDocument")).Return(expectedUser, nil).Once()
 
 		
 // 		suite.mockCol.On("FindOne",
@@ -140,11 +153,66 @@ func (suite *UserRepositoryTestSuite) TestCreateUser() {
 // 			bson.M{"username": username},
 // 		).Return(mockSingleResult).Once()
 
-// 		user, err := suite.repo.FindByUsername(username)
+// 		user, err := suite.repo.FindByUsername(suite.mockContext, username)
 // 		suite.NoError(err)
 // 		suite.NotNil(user)
 // 		suite.Equal(expectedUser.Username, user.Username)
 // 		suite.Equal(expectedUser.ID, user.ID)
 // 		mockSingleResult.AssertExpectations(suite.T())
 // 		suite.mockCol.AssertExpectations(suite.T())
-// 	})}
\ No newline at end of file
+// 	})}
+func (suite *UserRepositoryTestSuite) TestFindByUsernameErrors() {
+	// Test Case 1: a missing user is a not found error
+	suite.Run("User_Not_Found", func() {
+		suite.SetupTest()
+
+		result := mongo.NewSingleResultFromDocument(bson.M{}, mongo.ErrNoDocuments, nil)
+		suite.mockCol.On("FindOne", suite.mockContext, bson.M{"username": "ghost"}).Return(result).Once()
+
+		user, err := suite.repo.FindByUsername(suite.mockContext, "ghost")
+		suite.Nil(user)
+		suite.EqualError(err, "user not found")
+		suite.ErrorIs(err, domain.ErrNotFound)
+	})
+
+	// Test Case 2: duplicate usernames are a conflict
+	suite.Run("Duplicate_Username", func() {
+		suite.SetupTest()
+
+		duplicate := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "duplicate key"}}}
+		suite.mockCol.On("InsertOne", suite.mockContext, mock.AnythingOfType("*repositories.userDocument")).Return(nil, duplicate).Once()
+
+		err := suite.repo.CreateUser(suite.mockContext, &domain.User{Username: "taken"})
+		suite.EqualError(err, "username already exists")
+		suite.ErrorIs(err, domain.ErrConflict)
+	})
+}
+
+func (suite *UserRepositoryTestSuite) TestUpdateRoleLastAdmin() {
+	objID := primitive.NewObjectID()
+	activeAdmin := bson.M{"_id": objID, "role": domain.RoleAdmin, "deactivatedAt": bson.M{"$exists": false}}
+
+	// Test Case 1: the demotion is undone when no active admin is left
+	suite.Run("Undone", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("UpdateOne", suite.mockContext, activeAdmin, bson.M{"$set": bson.M{"role": domain.RoleUser}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil).Once()
+		suite.mockCol.On("CountDocuments", suite.mockContext, bson.M{"role": domain.RoleAdmin, "deactivatedAt": bson.M{"$exists": false}}).Return(int64(0), nil).Once()
+		suite.mockCol.On("UpdateOne", mock.Anything, bson.M{"_id": objID}, bson.M{"$set": bson.M{"role": domain.RoleAdmin}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil).Once()
+
+		err := suite.repo.UpdateRole(suite.mockContext, objID.Hex(), domain.RoleUser)
+		suite.ErrorIs(err, domain.ErrLastAdmin)
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+
+	// Test Case 2: the demotion is kept while another active admin is left
+	suite.Run("Kept", func() {
+		suite.SetupTest()
+
+		suite.mockCol.On("UpdateOne", suite.mockContext, activeAdmin, bson.M{"$set": bson.M{"role": domain.RoleUser}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil).Once()
+		suite.mockCol.On("CountDocuments", suite.mockContext, bson.M{"role": domain.RoleAdmin, "deactivatedAt": bson.M{"$exists": false}}).Return(int64(1), nil).Once()
+
+		suite.NoError(suite.repo.UpdateRole(suite.mockContext, objID.Hex(), domain.RoleUser))
+		suite.mockCol.AssertExpectations(suite.T())
+	})
+}
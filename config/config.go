@@ -0,0 +1,293 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the service
+type Config struct {
+	Server  ServerConfig
+	Storage StorageConfig
+	Mongo   MongoConfig
+	SQL     SQLConfig
+	JWT     JWTConfig
+	Tasks   TasksConfig
+}
+
+type ServerConfig struct {
+	// Port the HTTP server listens on
+	Port int
+}
+
+// Addr is the listen address of the server
+func (s ServerConfig) Addr() string {
+	return ":" + strconv.Itoa(s.Port)
+}
+
+// storage backends the repositories can run on
+const (
+	BackendMongo    = "mongo"
+	BackendMemory   = "memory"
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+type StorageConfig struct {
+	// Backend is where the data is kept, the memory backend forgets everything on restart
+	Backend string
+}
+
+type MongoConfig struct {
+	URL      string
+	Database string
+	// OperationTimeout bounds every single database call
+	OperationTimeout time.Duration
+}
+
+type SQLConfig struct {
+	// DSN is the SQLite file name or the PostgreSQL connection URL
+	DSN string
+	// OperationTimeout bounds every single database call
+	OperationTimeout time.Duration
+}
+
+type JWTConfig struct {
+	// Secret signs and verifies the access tokens
+	Secret string
+	// TTL is how long an access token stays valid, keep it short and renew it with a refresh token
+	TTL time.Duration
+	// RefreshTTL is how long a refresh token stays valid, every refresh hands out a new one
+	RefreshTTL time.Duration
+	// TokenPrecedence picks the access token of a request that carries both an
+	// Authorization header and an auth_token cookie
+	TokenPrecedence string
+}
+
+// token precedence policies, the header wins by default
+const (
+	TokenFromHeader = "header"
+	TokenFromCookie = "cookie"
+)
+
+type TasksConfig struct {
+	// TrashRetention is how long deleted tasks can be restored
+	TrashRetention time.Duration
+	// PurgeInterval is how often expired tasks are removed from the trash
+	PurgeInterval time.Duration
+}
+
+// MinSecretLength is the shortest JWT secret accepted, HS256 wants at least 32 bytes
+const MinSecretLength = 32
+
+// Default returns the settings used when nothing else is configured
+func Default() *Config {
+	return &Config{
+		Server:  ServerConfig{Port: 8080},
+		Storage: StorageConfig{Backend: BackendMongo},
+		Mongo:   MongoConfig{Database: "db", OperationTimeout: 5 * time.Second},
+		SQL:     SQLConfig{OperationTimeout: 5 * time.Second},
+		JWT:     JWTConfig{TTL: 15 * time.Minute, RefreshTTL: 7 * 24 * time.Hour, TokenPrecedence: TokenFromHeader},
+		Tasks: TasksConfig{
+			TrashRetention: 30 * 24 * time.Hour,
+			PurgeInterval:  time.Hour,
+		},
+	}
+}
+
+// fileConfig is the layout of the optional YAML or TOML config file, durations are strings like "24h"
+type fileConfig struct {
+	Server struct {
+		Port int `yaml:"port" toml:"port"`
+	} `yaml:"server" toml:"server"`
+	Storage struct {
+		Backend string `yaml:"backend" toml:"backend"`
+	} `yaml:"storage" toml:"storage"`
+	Mongo struct {
+		URL              string `yaml:"url" toml:"url"`
+		Database         string `yaml:"database" toml:"database"`
+		OperationTimeout string `yaml:"operationTimeout" toml:"operationTimeout"`
+	} `yaml:"mongo" toml:"mongo"`
+	SQL struct {
+		DSN              string `yaml:"dsn" toml:"dsn"`
+		OperationTimeout string `yaml:"operationTimeout" toml:"operationTimeout"`
+	} `yaml:"sql" toml:"sql"`
+	JWT struct {
+		Secret          string `yaml:"secret" toml:"secret"`
+		TTL             string `yaml:"ttl" toml:"ttl"`
+		RefreshTTL      string `yaml:"refreshTtl" toml:"refreshTtl"`
+		TokenPrecedence string `yaml:"tokenPrecedence" toml:"tokenPrecedence"`
+	} `yaml:"jwt" toml:"jwt"`
+	Tasks struct {
+		TrashRetention string `yaml:"trashRetention" toml:"trashRetention"`
+		PurgeInterval  string `yaml:"purgeInterval" toml:"purgeInterval"`
+	} `yaml:"tasks" toml:"tasks"`
+}
+
+// Load builds the configuration from the defaults, the optional config file, the .env
+// file and the environment, later sources win. every missing or invalid setting is
+// reported in one error
+func Load(file string) (*Config, error) {
+	//the .env file is optional and never overrides variables that are already set
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	cfg := Default()
+	var problems []string
+	if file != "" {
+		if err := readFile(file, cfg, &problems); err != nil {
+			return nil, err
+		}
+	}
+	readEnv(cfg, &problems)
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return cfg, nil
+}
+
+// readFile applies the settings of a .yaml, .yml or .toml file
+func readFile(file string, cfg *Config, problems *[]string) error {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var fc fileConfig
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &fc)
+	case ".toml":
+		err = toml.Unmarshal(raw, &fc)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, use .yaml or .toml", file, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", file, err)
+	}
+
+	if fc.Server.Port != 0 {
+		cfg.Server.Port = fc.Server.Port
+	}
+	setString(&cfg.Storage.Backend, fc.Storage.Backend)
+	setString(&cfg.Mongo.URL, fc.Mongo.URL)
+	setString(&cfg.Mongo.Database, fc.Mongo.Database)
+	setDuration(&cfg.Mongo.OperationTimeout, "mongo.operationTimeout", fc.Mongo.OperationTimeout, problems)
+	setString(&cfg.SQL.DSN, fc.SQL.DSN)
+	setDuration(&cfg.SQL.OperationTimeout, "sql.operationTimeout", fc.SQL.OperationTimeout, problems)
+	setString(&cfg.JWT.Secret, fc.JWT.Secret)
+	setDuration(&cfg.JWT.TTL, "jwt.ttl", fc.JWT.TTL, problems)
+	setDuration(&cfg.JWT.RefreshTTL, "jwt.refreshTtl", fc.JWT.RefreshTTL, problems)
+	setString(&cfg.JWT.TokenPrecedence, fc.JWT.TokenPrecedence)
+	setDuration(&cfg.Tasks.TrashRetention, "tasks.trashRetention", fc.Tasks.TrashRetention, problems)
+	setDuration(&cfg.Tasks.PurgeInterval, "tasks.purgeInterval", fc.Tasks.PurgeInterval, problems)
+	return nil
+}
+
+// readEnv applies the environment variables, mongo_url is still read for older .env files
+func readEnv(cfg *Config, problems *[]string) {
+	if port := os.Getenv("PORT"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("PORT must be a number, got %q", port))
+		} else {
+			cfg.Server.Port = n
+		}
+	}
+	setString(&cfg.Storage.Backend, os.Getenv("STORAGE_BACKEND"))
+	setString(&cfg.Mongo.URL, os.Getenv("mongo_url"))
+	setString(&cfg.Mongo.URL, os.Getenv("MONGO_URL"))
+	setString(&cfg.Mongo.Database, os.Getenv("MONGO_DATABASE"))
+	setDuration(&cfg.Mongo.OperationTimeout, "MONGO_OPERATION_TIMEOUT", os.Getenv("MONGO_OPERATION_TIMEOUT"), problems)
+	setString(&cfg.SQL.DSN, os.Getenv("SQL_DSN"))
+	setDuration(&cfg.SQL.OperationTimeout, "SQL_OPERATION_TIMEOUT", os.Getenv("SQL_OPERATION_TIMEOUT"), problems)
+	setString(&cfg.JWT.Secret, os.Getenv("JWT_SECRET"))
+	setDuration(&cfg.JWT.TTL, "JWT_TTL", os.Getenv("JWT_TTL"), problems)
+	setDuration(&cfg.JWT.RefreshTTL, "JWT_REFRESH_TTL", os.Getenv("JWT_REFRESH_TTL"), problems)
+	setString(&cfg.JWT.TokenPrecedence, os.Getenv("JWT_TOKEN_PRECEDENCE"))
+	setDuration(&cfg.Tasks.TrashRetention, "TRASH_RETENTION", os.Getenv("TRASH_RETENTION"), problems)
+	setDuration(&cfg.Tasks.PurgeInterval, "TRASH_PURGE_INTERVAL", os.Getenv("TRASH_PURGE_INTERVAL"), problems)
+}
+
+// validate lists the settings that are missing or out of range
+func (c *Config) validate() []string {
+	var problems []string
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("server port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	switch c.Storage.Backend {
+	case BackendMongo:
+		if c.Mongo.URL == "" {
+			problems = append(problems, "MONGO_URL (mongo.url) is required")
+		}
+		if c.Mongo.Database == "" {
+			problems = append(problems, "MONGO_DATABASE (mongo.database) cannot be empty")
+		}
+	case BackendSQLite, BackendPostgres:
+		if c.SQL.DSN == "" {
+			problems = append(problems, fmt.Sprintf("SQL_DSN (sql.dsn) is required by the %s backend", c.Storage.Backend))
+		}
+	case BackendMemory:
+	default:
+		problems = append(problems, fmt.Sprintf("STORAGE_BACKEND (storage.backend) must be one of %s, %s, %s or %s, got %q",
+			BackendMongo, BackendSQLite, BackendPostgres, BackendMemory, c.Storage.Backend))
+	}
+	if c.Mongo.OperationTimeout <= 0 {
+		problems = append(problems, "MONGO_OPERATION_TIMEOUT (mongo.operationTimeout) must be positive")
+	}
+	if c.SQL.OperationTimeout <= 0 {
+		problems = append(problems, "SQL_OPERATION_TIMEOUT (sql.operationTimeout) must be positive")
+	}
+	if c.JWT.Secret == "" {
+		problems = append(problems, "JWT_SECRET (jwt.secret) is required")
+	} else if len(c.JWT.Secret) < MinSecretLength {
+		problems = append(problems, fmt.Sprintf("JWT_SECRET (jwt.secret) must be at least %d characters", MinSecretLength))
+	}
+	if c.JWT.TTL <= 0 {
+		problems = append(problems, "JWT_TTL (jwt.ttl) must be positive")
+	}
+	if c.JWT.RefreshTTL <= c.JWT.TTL {
+		problems = append(problems, "JWT_REFRESH_TTL (jwt.refreshTtl) must be longer than JWT_TTL (jwt.ttl)")
+	}
+	if c.JWT.TokenPrecedence != TokenFromHeader && c.JWT.TokenPrecedence != TokenFromCookie {
+		problems = append(problems, fmt.Sprintf("JWT_TOKEN_PRECEDENCE (jwt.tokenPrecedence) must be %s or %s, got %q",
+			TokenFromHeader, TokenFromCookie, c.JWT.TokenPrecedence))
+	}
+	if c.Tasks.TrashRetention <= 0 {
+		problems = append(problems, "TRASH_RETENTION (tasks.trashRetention) must be positive")
+	}
+	if c.Tasks.PurgeInterval <= 0 {
+		problems = append(problems, "TRASH_PURGE_INTERVAL (tasks.purgeInterval) must be positive")
+	}
+	return problems
+}
+
+func setString(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+func setDuration(target *time.Duration, name, value string, problems *[]string) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Sprintf("%s must be a duration such as 24h, got %q", name, value))
+		return
+	}
+	*target = d
+}
@@ -0,0 +1,192 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"task_management/config"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+type ConfigTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *ConfigTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	for _, name := range []string{"PORT", "STORAGE_BACKEND", "mongo_url", "MONGO_URL", "MONGO_DATABASE", "MONGO_OPERATION_TIMEOUT", "SQL_DSN", "SQL_OPERATION_TIMEOUT", "JWT_SECRET", "JWT_TTL", "JWT_REFRESH_TTL", "JWT_TOKEN_PRECEDENCE", "TRASH_RETENTION", "TRASH_PURGE_INTERVAL"} {
+		suite.T().Setenv(name, "")
+	}
+}
+
+func (suite *ConfigTestSuite) writeFile(name, content string) string {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (suite *ConfigTestSuite) TestLoadDefaults() {
+	suite.T().Setenv("MONGO_URL", "mongodb://localhost:27017")
+	suite.T().Setenv("JWT_SECRET", testSecret)
+
+	cfg, err := config.Load("")
+
+	suite.NoError(err)
+	suite.Equal(":8080", cfg.Server.Addr())
+	suite.Equal(config.BackendMongo, cfg.Storage.Backend)
+	suite.Equal("db", cfg.Mongo.Database)
+	suite.Equal(5*time.Second, cfg.Mongo.OperationTimeout)
+	suite.Equal(15*time.Minute, cfg.JWT.TTL)
+	suite.Equal(7*24*time.Hour, cfg.JWT.RefreshTTL)
+	suite.Equal(config.TokenFromHeader, cfg.JWT.TokenPrecedence)
+	suite.Equal(30*24*time.Hour, cfg.Tasks.TrashRetention)
+	suite.Equal(time.Hour, cfg.Tasks.PurgeInterval)
+}
+
+func (suite *ConfigTestSuite) TestLoadFiles() {
+	suite.Run("yaml", func() {
+		suite.SetupTest()
+		file := suite.writeFile("config.yaml", `
+server:
+  port: 9000
+mongo:
+  url: mongodb://mongo:27017
+  database: tasks
+  operationTimeout: 2s
+jwt:
+  secret: `+testSecret+`
+  ttl: 1h
+  refreshTtl: 72h
+  tokenPrecedence: cookie
+tasks:
+  trashRetention: 48h
+  purgeInterval: 10m
+`)
+		cfg, err := config.Load(file)
+
+		suite.NoError(err)
+		suite.Equal(9000, cfg.Server.Port)
+		suite.Equal("mongodb://mongo:27017", cfg.Mongo.URL)
+		suite.Equal("tasks", cfg.Mongo.Database)
+		suite.Equal(2*time.Second, cfg.Mongo.OperationTimeout)
+		suite.Equal(testSecret, cfg.JWT.Secret)
+		suite.Equal(time.Hour, cfg.JWT.TTL)
+		suite.Equal(72*time.Hour, cfg.JWT.RefreshTTL)
+		suite.Equal(config.TokenFromCookie, cfg.JWT.TokenPrecedence)
+		suite.Equal(48*time.Hour, cfg.Tasks.TrashRetention)
+		suite.Equal(10*time.Minute, cfg.Tasks.PurgeInterval)
+	})
+	suite.Run("toml", func() {
+		suite.SetupTest()
+		file := suite.writeFile("config.toml", `
+[server]
+port = 9001
+
+[mongo]
+url = "mongodb://mongo:27017"
+
+[jwt]
+secret = "`+testSecret+`"
+ttl = "2h"
+`)
+		cfg, err := config.Load(file)
+
+		suite.NoError(err)
+		suite.Equal(9001, cfg.Server.Port)
+		suite.Equal("db", cfg.Mongo.Database)
+		suite.Equal(2*time.Hour, cfg.JWT.TTL)
+	})
+	suite.Run("unsupported format", func() {
+		suite.SetupTest()
+		file := suite.writeFile("config.json", `{}`)
+
+		_, err := config.Load(file)
+
+		suite.ErrorContains(err, "unsupported format")
+	})
+}
+
+func (suite *ConfigTestSuite) TestEnvOverridesFile() {
+	file := suite.writeFile("config.yaml", `
+server:
+  port: 9000
+mongo:
+  url: mongodb://file:27017
+jwt:
+  secret: `+testSecret+`
+`)
+	suite.T().Setenv("PORT", "9100")
+	suite.T().Setenv("MONGO_URL", "mongodb://env:27017")
+
+	cfg, err := config.Load(file)
+
+	suite.NoError(err)
+	suite.Equal(9100, cfg.Server.Port)
+	suite.Equal("mongodb://env:27017", cfg.Mongo.URL)
+	suite.Equal(testSecret, cfg.JWT.Secret)
+}
+
+func (suite *ConfigTestSuite) TestStorageBackend() {
+	suite.Run("memory needs no mongo", func() {
+		suite.SetupTest()
+		suite.T().Setenv("STORAGE_BACKEND", config.BackendMemory)
+		suite.T().Setenv("JWT_SECRET", testSecret)
+
+		cfg, err := config.Load("")
+
+		suite.NoError(err)
+		suite.Equal(config.BackendMemory, cfg.Storage.Backend)
+	})
+	suite.Run("sql needs a dsn", func() {
+		suite.SetupTest()
+		suite.T().Setenv("STORAGE_BACKEND", config.BackendSQLite)
+		suite.T().Setenv("JWT_SECRET", testSecret)
+
+		_, err := config.Load("")
+		suite.ErrorContains(err, "SQL_DSN (sql.dsn) is required by the sqlite backend")
+
+		suite.T().Setenv("SQL_DSN", "tasks.db")
+		cfg, err := config.Load("")
+		suite.NoError(err)
+		suite.Equal("tasks.db", cfg.SQL.DSN)
+		suite.Equal(5*time.Second, cfg.SQL.OperationTimeout)
+	})
+	suite.Run("unknown backend", func() {
+		suite.SetupTest()
+		suite.T().Setenv("STORAGE_BACKEND", "redis")
+		suite.T().Setenv("JWT_SECRET", testSecret)
+
+		_, err := config.Load("")
+
+		suite.ErrorContains(err, `STORAGE_BACKEND (storage.backend) must be one of mongo, sqlite, postgres or memory, got "redis"`)
+	})
+}
+
+func (suite *ConfigTestSuite) TestLoadReportsEveryProblem() {
+	suite.T().Setenv("PORT", "eighty")
+	suite.T().Setenv("JWT_SECRET", "short")
+	suite.T().Setenv("JWT_TTL", "a day")
+	suite.T().Setenv("JWT_REFRESH_TTL", "1m")
+	suite.T().Setenv("JWT_TOKEN_PRECEDENCE", "query")
+
+	_, err := config.Load("")
+
+	suite.Error(err)
+	suite.ErrorContains(err, "invalid configuration")
+	suite.ErrorContains(err, `PORT must be a number, got "eighty"`)
+	suite.ErrorContains(err, "MONGO_URL (mongo.url) is required")
+	suite.ErrorContains(err, "JWT_SECRET (jwt.secret) must be at least 32 characters")
+	suite.ErrorContains(err, `JWT_TTL must be a duration such as 24h, got "a day"`)
+	suite.ErrorContains(err, "JWT_REFRESH_TTL (jwt.refreshTtl) must be longer than JWT_TTL (jwt.ttl)")
+	suite.ErrorContains(err, `JWT_TOKEN_PRECEDENCE (jwt.tokenPrecedence) must be header or cookie, got "query"`)
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
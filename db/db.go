@@ -2,50 +2,114 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"time"
 
-	"sync"
+	"task_management/config"
 
-	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
-const database= "db"
+
+// connectTimeout bounds how long Connect waits for the server to answer
+const connectTimeout = 10 * time.Second
 
 var (
-	client     *mongo.Client
-	clientOnce sync.Once
+	client   *mongo.Client
+	database string
 )
 
-func getClient() (*mongo.Client, error) {
-	var err error
-	clientOnce.Do(func() {
-		err := godotenv.Load()
-		if err != nil {
-			log.Fatal("Error loading .env file")
-		}
+// ErrNotConnected is returned by the collection getters before Connect succeeded
+var ErrNotConnected = errors.New("mongo client is not connected, call db.Connect first")
 
-	
-		uri := os.Getenv("mongo_url")
-		
-		clientOptions := options.Client().ApplyURI(uri)
-		
-		client, err = mongo.Connect(context.TODO(), clientOptions)
-		if err != nil {
-			return
-		}
-		
-		err = client.Ping(context.TODO(), nil)
+// Connect opens the mongo client the collection getters use and checks the server answers
+func Connect(cfg config.MongoConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	c, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URL))
+	if err != nil {
+		return fmt.Errorf("connecting to mongo: %w", err)
+	}
+	if err := c.Ping(ctx, nil); err != nil {
+		_ = c.Disconnect(context.Background())
+		return fmt.Errorf("pinging mongo: %w", err)
+	}
+
+	if err := ensureIndexes(ctx, c.Database(cfg.Database)); err != nil {
+		_ = c.Disconnect(context.Background())
+		return err
+	}
+
+	client = c
+	database = cfg.Database
+	fmt.Println("Connected to MongoDB!")
+	return nil
+}
+
+// ensureIndexes creates the indexes the repositories rely on, the unique username index
+// is what turns a second user with the same name into a duplicate key error
+func ensureIndexes(ctx context.Context, d *mongo.Database) error {
+	_, err := d.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("creating the username index: %w", err)
+	}
+	//expired refresh tokens are useless, mongo removes them on its own
+	_, err = d.Collection("refresh_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "familyId", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("creating the refresh token indexes: %w", err)
+	}
+	_, err = d.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("creating the session indexes: %w", err)
+	}
+	_, err = d.Collection("personal_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("creating the personal token indexes: %w", err)
+	}
+	//a revocation is only needed until the tokens it revokes expired
+	for _, name := range []string{"revoked_tokens", "user_revocations"} {
+		_, err = d.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
 		if err != nil {
-			return
+			return fmt.Errorf("creating the %s index: %w", name, err)
 		}
-		
-		fmt.Println("Connected to MongoDB!")
-	})
-	
-	return client, err
+	}
+	return nil
+}
+
+// Disconnect closes the mongo client
+func Disconnect(ctx context.Context) error {
+	if client == nil {
+		return nil
+	}
+	return client.Disconnect(ctx)
+}
+
+func getClient() (*mongo.Client, error) {
+	if client == nil {
+		return nil, ErrNotConnected
+	}
+	return client, nil
 }
 
 func GetUsersCollection() (*mongo.Collection) {
@@ -62,4 +126,52 @@ func GetTasksCollection() (*mongo.Collection) {
 		return nil
 	}
 	return client.Database(database).Collection("tasks")
-}
\ No newline at end of file
+}
+
+func GetAuditCollection() (*mongo.Collection) {
+	client, err := getClient()
+	if err != nil {
+		return nil
+	}
+	return client.Database(database).Collection("audit")
+}
+
+func GetRefreshTokensCollection() (*mongo.Collection) {
+	client, err := getClient()
+	if err != nil {
+		return nil
+	}
+	return client.Database(database).Collection("refresh_tokens")
+}
+
+func GetSessionsCollection() (*mongo.Collection) {
+	client, err := getClient()
+	if err != nil {
+		return nil
+	}
+	return client.Database(database).Collection("sessions")
+}
+
+func GetPersonalTokensCollection() (*mongo.Collection) {
+	client, err := getClient()
+	if err != nil {
+		return nil
+	}
+	return client.Database(database).Collection("personal_tokens")
+}
+
+func GetRevokedTokensCollection() (*mongo.Collection) {
+	client, err := getClient()
+	if err != nil {
+		return nil
+	}
+	return client.Database(database).Collection("revoked_tokens")
+}
+
+func GetUserRevocationsCollection() (*mongo.Collection) {
+	client, err := getClient()
+	if err != nil {
+		return nil
+	}
+	return client.Database(database).Collection("user_revocations")
+}
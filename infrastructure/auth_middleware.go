@@ -1,65 +1,104 @@
 package infrastruture
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
+	domain "task_management/Domain"
 	usecases "task_management/usecases"
+	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 type AuthService struct{
 	jwtSecret []byte
+	// users is read on every request so deleted and deactivated users lose access right away
+	users usecases.IUserRepository
+	// revocations holds the tokens revoked by a logout
+	revocations usecases.ITokenRevocationRepo
+	// sessions is read on every request, tokens of revoked sessions are refused
+	sessions usecases.ISessionRepo
+	// personalTokens is read for requests authenticating with a personal access token
+	personalTokens usecases.IPersonalTokenRepo
+	// precedence picks the token of a request that sends both a header and a cookie
+	precedence TokenPrecedence
 }
 
-func NewAuthService( secret string)usecases.IAuthService{
-	return &AuthService{jwtSecret: []byte(secret)}
+// TokenPrecedence decides whether the Authorization header or the auth_token cookie
+// is used when a request carries both
+type TokenPrecedence string
 
-}
+const (
+	HeaderFirst TokenPrecedence = "header"
+	CookieFirst TokenPrecedence = "cookie"
+)
+
+// lastSeenInterval is how stale the last seen time of a session or the last used time
+// of a personal access token may get, it saves a write on every request
+const lastSeenInterval = time.Minute
+
+func NewAuthService( secret string, users usecases.IUserRepository, revocations usecases.ITokenRevocationRepo, sessions usecases.ISessionRepo, personalTokens usecases.IPersonalTokenRepo, precedence TokenPrecedence)usecases.IAuthService{
+	return &AuthService{jwtSecret: []byte(secret), users: users, revocations: revocations, sessions: sessions, personalTokens: personalTokens, precedence: precedence}
 
-var jwtSecret = []byte("wellwellwell")
+}
 
 func (a *AuthService)AuthWithRole(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		//read the token from cookie
-		cookie, err := c.Request.Cookie("auth_token")
-
+		//read the token from the Authorization header or the cookie
+		token, fromHeader, err := a.accessToken(c.Request)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized: no auth cookie"})
+			abortWithError(c, err)
 			return
 		}
-		tokenstr := cookie.Value
 
-		//parse the token using jwt.Parse
-
-		token, err := jwt.Parse(tokenstr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("error in signing method")
+		//personal access tokens are only accepted in the header
+		var personal *domain.PersonalToken
+		var claims *domain.AccessClaims
+		var userID string
+		if fromHeader && strings.HasPrefix(token, domain.PersonalTokenPrefix) {
+			personal, err = a.findPersonalToken(c, token)
+			if err != nil {
+				abortWithError(c, err)
+				return
 			}
+			userID = personal.UserID
+		} else {
+			claims, err = parseAccessToken(a.jwtSecret, token)
+			if err != nil {
+				abortWithError(c, err)
+				return
+			}
+			userID = claims.UserID
+		}
 
-			return jwtSecret, nil
-		})
-
-		//check error
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized: invalid token" + err.Error()})
+		//the token outlives the account, check it still exists and is active
+		user, err := a.users.FindByID(c.Request.Context(), userID)
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrValidation) {
+			abortWithError(c, domain.Unauthorized("unauthorized: user no longer exists"))
 			return
 		}
-
-		//extract claims -role and id from the token
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized: invalid token claims"})
+		if err != nil {
+			abortWithError(c, domain.Internal("failed to find user", err))
 			return
 		}
-		userID, ok1 := claims["sub"].(string)
-		role, ok2 := claims["role"].(string)
-
-		if !ok1 || !ok2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized: missing user info in token"})
+		if !user.IsActive() {
+			abortWithError(c, domain.ErrAccountDeactivated)
 			return
 		}
+
+		//the role is read from the account, a demotion applies to the tokens already issued
+		role := string(user.Role)
+		if personal != nil {
+			if err := a.checkScope(c, personal); err != nil {
+				abortWithError(c, err)
+				return
+			}
+		} else {
+			if err := a.checkSession(c, claims); err != nil {
+				abortWithError(c, err)
+				return
+			}
+		}
 		c.Set("userID", userID)
 		c.Set("userRole", role)
 
@@ -72,7 +111,7 @@ func (a *AuthService)AuthWithRole(allowedRoles ...string) gin.HandlerFunc {
 			}
 		}
 		if !authorized {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "role not authorized"})
+			abortWithError(c, domain.Forbidden("role not authorized"))
 			return
 		}
 
@@ -80,3 +119,130 @@ func (a *AuthService)AuthWithRole(allowedRoles ...string) gin.HandlerFunc {
 
 	}
 }
+
+// TokenScopes opens the routes after it to personal access tokens, GET and HEAD need
+// the read scope and every other method the write scope. it has to run before
+// AuthWithRole, routes without it refuse personal access tokens
+func (a *AuthService) TokenScopes(read, write domain.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		c.Set(tokenScopeKey, scope)
+		c.Next()
+	}
+}
+
+// tokenScopeKey holds the scope TokenScopes asks of personal access tokens
+const tokenScopeKey = "tokenScope"
+
+// checkSession refuses logged out tokens and tokens of revoked sessions, the session is
+// put in the context
+func (a *AuthService) checkSession(c *gin.Context, claims *domain.AccessClaims) error {
+	//logged out tokens are refused until they expire
+	revoked, err := a.revocations.IsRevoked(c.Request.Context(), claims.TokenID, claims.UserID, claims.IssuedAt)
+	if err != nil {
+		return domain.Internal("failed to check token revocation", err)
+	}
+	if revoked {
+		return domain.Unauthorized("unauthorized: token was revoked")
+	}
+	session, err := a.sessions.FindSession(c.Request.Context(), claims.SessionID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) && !errors.Is(err, domain.ErrValidation) {
+		return domain.Internal("failed to find session", err)
+	}
+	now := time.Now()
+	if err != nil || session.UserID != claims.UserID || !session.IsActive(now) {
+		return domain.Unauthorized("unauthorized: session was revoked")
+	}
+	if now.Sub(session.LastSeenAt) >= lastSeenInterval {
+		//a failed update only leaves the last seen time stale
+		_ = a.sessions.TouchSession(c.Request.Context(), session.ID, now)
+	}
+	c.Set("sessionID", session.ID)
+	return nil
+}
+
+// findPersonalToken looks a personal access token up by its hash, unknown, expired and
+// revoked tokens are refused
+func (a *AuthService) findPersonalToken(c *gin.Context, token string) (*domain.PersonalToken, error) {
+	personal, err := a.personalTokens.FindPersonalTokenByHash(c.Request.Context(), hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidPersonalToken
+	}
+	if err != nil {
+		return nil, domain.Internal("failed to find personal token", err)
+	}
+	now := time.Now()
+	if !personal.IsActive(now) {
+		return nil, domain.ErrInvalidPersonalToken
+	}
+	if personal.LastUsedAt == nil || now.Sub(*personal.LastUsedAt) >= lastSeenInterval {
+		//a failed update only leaves the last used time stale
+		_ = a.personalTokens.TouchPersonalToken(c.Request.Context(), personal.ID, now)
+	}
+	return personal, nil
+}
+
+// checkScope refuses personal access tokens on routes TokenScopes did not open and on
+// routes needing a scope the token was not given
+func (a *AuthService) checkScope(c *gin.Context, personal *domain.PersonalToken) error {
+	value, ok := c.Get(tokenScopeKey)
+	scope, _ := value.(domain.Scope)
+	if !ok || scope == "" {
+		return domain.InsufficientScope("")
+	}
+	if !personal.HasScope(scope) {
+		return domain.InsufficientScope(scope)
+	}
+	return nil
+}
+
+// AccessToken returns the access token the middleware would check for the request
+func (a *AuthService) AccessToken(r *http.Request) (string, error) {
+	token, _, err := a.accessToken(r)
+	return token, err
+}
+
+// accessToken finds the access token of the request, the precedence policy picks
+// between the Authorization header and the auth_token cookie when both are sent
+func (a *AuthService) accessToken(r *http.Request) (token string, fromHeader bool, err error) {
+	var cookieToken string
+	if cookie, err := r.Cookie("auth_token"); err == nil {
+		cookieToken = cookie.Value
+	}
+	if cookieToken != "" && a.precedence == CookieFirst {
+		return cookieToken, false, nil
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if cookieToken == "" {
+			return "", false, domain.Unauthorized("unauthorized: no auth token")
+		}
+		return cookieToken, false, nil
+	}
+	token, ok := bearerToken(header)
+	if !ok {
+		return "", false, domain.Unauthorized("unauthorized: authorization header must be Bearer <token>")
+	}
+	return token, true, nil
+}
+
+// bearerToken returns the token of an Authorization header value of the form "Bearer <token>",
+// the scheme is case insensitive
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// abortWithError stops the request, the error handler middleware writes the response
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -1,82 +1,113 @@
 package infrastruture_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"testing"
+	"time"
+
 	domain "task_management/Domain"
+	repositories "task_management/Repositories"
 	infrastruture "task_management/infrastructure"
-	"time"
+	"task_management/usecases"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
-//define a test suite struct for authmiddleware
+// define a test suite struct for authmiddleware
 type AuthMiddlewareTestSuite struct {
 	suite.Suite
 	authService *infrastruture.AuthService
-	secret      string
+	jwtService  usecases.IJWTService
+	users       usecases.IUserRepository
+	sessions    usecases.ISessionRepo
 }
 
-func (suite *AuthMiddlewareTestSuite) setupTest(){
-	suite.secret="wellwellwell"
-	suite.authService = infrastruture.NewAuthService(suite.secret).(*infrastruture.AuthService)
-
+func TestAuthMiddlewareSuite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite.Run(t, new(AuthMiddlewareTestSuite))
 }
 
-//function to create token for test 
-func (suite *AuthMiddlewareTestSuite) createToken(userID int, role domain.Role) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"role": role,
-		"sub":  userID, 
-		"exp":  time.Now().Add(time.Hour).Unix(),
-	})
-
-	tokenStr, _ := token.SignedString([]byte(suite.secret))
-	return tokenStr
+func (suite *AuthMiddlewareTestSuite) SetupTest() {
+	secret := "wellwellwell"
+	suite.users = repositories.NewMemoryUserRepository()
+	suite.sessions = repositories.NewMemorySessionRepository()
+	suite.jwtService = infrastruture.NewJWTService(secret, time.Hour, 24*time.Hour)
+	suite.authService = infrastruture.NewAuthService(secret, suite.users, repositories.NewMemoryTokenRevocationRepository(), suite.sessions, repositories.NewMemoryPersonalTokenRepository(), infrastruture.HeaderFirst).(*infrastruture.AuthService)
 }
-//test with valid token and role
-func (suite *AuthMiddlewareTestSuite) TestAuthWithValidTokenAndRole(){
-	router:=gin.New()
-	router.Use(suite.authService.AuthWithRole("Admin"))
-
-	router.GET("/protected",func(c *gin.Context){
-		userID:=c.GetString("userID")
-		role:=c.GetString("Role")
-		c.IndentedJSON(http.StatusOK,gin.H{"userID":userID,"role":role})
 
-	})
-	token:=suite.createToken(1,"Admin")
-	req:=httptest.NewRequest(http.MethodGet,"/protected",nil)
-	req.AddCookie(&http.Cookie{Name:"auth_token",Value:token})
-	w:=httptest.NewRecorder()
+// createToken stores a user with the role and a session for it, and returns the user and an access token of the session
+func (suite *AuthMiddlewareTestSuite) createToken(username string, role domain.Role) (*domain.User, *domain.Session, string) {
+	ctx := context.Background()
+	user := &domain.User{Username: username, Password: "hash", Role: role}
+	suite.Require().NoError(suite.users.CreateUser(ctx, user))
 
-	router.ServeHTTP(w,req)
-	assert.Equal(suite.T(),http.StatusOK,w.Code)
-	assert.Contains(suite.T(), w.Body.String(), 1)
-	assert.Contains(suite.T(), w.Body.String(), "Admin")
+	now := time.Now()
+	session := &domain.Session{UserID: user.ID, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(24 * time.Hour)}
+	suite.Require().NoError(suite.sessions.CreateSession(ctx, session))
 
-	
+	token, err := suite.jwtService.GenerateToken(user.ID, role, session.ID)
+	suite.Require().NoError(err)
+	return user, session, token
 }
 
-// Test with valid token but wrong role
-func (suite *AuthMiddlewareTestSuite) TestAuthWithInvalidRole() {
+// serve sends a request with the token cookie to a route only admins may use
+func (suite *AuthMiddlewareTestSuite) serve(token string) *httptest.ResponseRecorder {
 	router := gin.New()
-	router.Use(suite.authService.AuthWithRole("Admin")) // only admin allowed
-
+	router.Use(infrastruture.ErrorHandler())
+	router.Use(suite.authService.AuthWithRole(string(domain.RoleAdmin)))
 	router.GET("/protected", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		c.JSON(http.StatusOK, gin.H{"userID": c.GetString("userID"), "role": c.GetString("userRole")})
 	})
 
-	token := suite.createToken(1, "User") 
 	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
 	req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
 	w := httptest.NewRecorder()
-
 	router.ServeHTTP(w, req)
+	return w
+}
+
+// test with valid token and role
+func (suite *AuthMiddlewareTestSuite) TestAuthWithValidTokenAndRole() {
+	user, _, token := suite.createToken("alice", domain.RoleAdmin)
+
+	w := suite.serve(token)
 
-	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
-	assert.Contains(suite.T(), w.Body.String(), "role not authorized")
-}
\ No newline at end of file
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), user.ID)
+	suite.Contains(w.Body.String(), string(domain.RoleAdmin))
+}
+
+// test with valid token but wrong role
+func (suite *AuthMiddlewareTestSuite) TestAuthWithInvalidRole() {
+	_, _, token := suite.createToken("bob", domain.RoleUser)
+
+	w := suite.serve(token)
+
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.Contains(w.Body.String(), "role not authorized")
+}
+
+// test the token of a revoked session is refused
+func (suite *AuthMiddlewareTestSuite) TestAuthWithRevokedSession() {
+	_, session, token := suite.createToken("alice", domain.RoleAdmin)
+	suite.Require().NoError(suite.sessions.RevokeSession(context.Background(), session.ID, time.Now()))
+
+	w := suite.serve(token)
+
+	suite.Equal(http.StatusUnauthorized, w.Code)
+	suite.Contains(w.Body.String(), "session was revoked")
+}
+
+// test the token of a deleted user is refused
+func (suite *AuthMiddlewareTestSuite) TestAuthWithDeletedUser() {
+	user, _, token := suite.createToken("bob", domain.RoleUser)
+	suite.Require().NoError(suite.users.DeleteUser(context.Background(), user.ID))
+
+	w := suite.serve(token)
+
+	suite.Equal(http.StatusUnauthorized, w.Code)
+	suite.Contains(w.Body.String(), "user no longer exists")
+}
@@ -0,0 +1,130 @@
+package infrastruture
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	domain "task_management/Domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// status codes of the domain error kinds
+var errorKindStatus = []struct {
+	kind   error
+	status int
+}{
+	{domain.ErrValidation, http.StatusBadRequest},
+	{domain.ErrUnauthorized, http.StatusUnauthorized},
+	{domain.ErrForbidden, http.StatusForbidden},
+	{domain.ErrNotFound, http.StatusNotFound},
+	{domain.ErrConflict, http.StatusConflict},
+	{domain.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{domain.ErrInternal, http.StatusInternalServerError},
+}
+
+// StatusCode picks the HTTP status of an error. errors with a StatusCode method
+// keep their own status, operations that ran out of time are gateway timeouts and
+// errors of an unknown kind are internal errors
+func StatusCode(err error) int {
+	var withStatus interface{ StatusCode() int }
+	if errors.As(err, &withStatus) {
+		return withStatus.StatusCode()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	for _, k := range errorKindStatus {
+		if errors.Is(err, k.kind) {
+			return k.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// ProblemContentType is the media type of RFC 7807 error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body with a machine readable code
+// and the per field errors of validation problems
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []domain.FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem describes an error as a problem, the cause of internal errors is never included
+func NewProblem(err error, instance string) *Problem {
+	status := StatusCode(err)
+	problem := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: instance,
+		Code:     domain.ErrorCode(err),
+	}
+
+	var domainErr *domain.Error
+	isDomainErr := errors.As(err, &domainErr)
+	if isDomainErr {
+		problem.Errors = domainErr.Fields
+	}
+	var withStatus interface{ StatusCode() int }
+	if !isDomainErr && errors.As(err, &withStatus) {
+		problem.Code = codeFromStatus(status)
+	}
+	if status == http.StatusInternalServerError && !isDomainErr {
+		problem.Detail = "internal server error"
+	}
+	if status == http.StatusGatewayTimeout {
+		problem.Detail = "the request took too long and was cancelled"
+		problem.Code = "timeout"
+	}
+	return problem
+}
+
+// AbortWithProblem writes err as a problem+json response and stops the request
+func AbortWithProblem(c *gin.Context, err error) {
+	problem := NewProblem(err, c.Request.URL.Path)
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// ErrorHandler writes the response of the last error a handler added with c.Error
+// as application/problem+json. internal errors are logged, their cause is never sent to the client
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		if status := StatusCode(err); status == http.StatusInternalServerError || status == http.StatusGatewayTimeout {
+			log.Printf("[%s] %s %s: %v", RequestIDFromContext(c.Request.Context()), c.Request.Method, c.Request.URL.Path, errorCause(err))
+		}
+		AbortWithProblem(c, err)
+	}
+}
+
+// codeFromStatus names errors that only carry a status, e.g. 428 becomes precondition_required
+func codeFromStatus(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// errorCause is the underlying error of a domain error, for the logs
+func errorCause(err error) error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) && domainErr.Err != nil {
+		return domainErr.Err
+	}
+	return err
+}
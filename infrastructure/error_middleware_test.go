@@ -0,0 +1,121 @@
+package infrastruture_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	domain "task_management/Domain"
+	infrastruture "task_management/infrastructure"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorMiddlewareTestSuite struct {
+	suite.Suite
+}
+
+func TestErrorMiddlewareSuite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite.Run(t, new(ErrorMiddlewareTestSuite))
+}
+
+// serve runs a request through a router whose only handler fails with err
+func (s *ErrorMiddlewareTestSuite) serve(err error) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(infrastruture.ErrorHandler())
+	router.GET("/tasks/:id", func(c *gin.Context) {
+		_ = c.Error(err)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/42", nil))
+	return w
+}
+
+// problem decodes the problem details of a response
+func (s *ErrorMiddlewareTestSuite) problem(w *httptest.ResponseRecorder) infrastruture.Problem {
+	var problem infrastruture.Problem
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &problem))
+	return problem
+}
+
+func (s *ErrorMiddlewareTestSuite) TestStatusCodes() {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{domain.Validation("title cannot be empty"), http.StatusBadRequest},
+		{domain.Unauthorized("invalid username or password"), http.StatusUnauthorized},
+		{domain.Forbidden("only the task owner can do this"), http.StatusForbidden},
+		{domain.NotFound("task not found"), http.StatusNotFound},
+		{domain.Conflict("username already exists"), http.StatusConflict},
+		{domain.ErrVersionMismatch, http.StatusPreconditionFailed},
+		{domain.Internal("failed to retrieve", errors.New("connection reset")), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		s.Run(tc.err.Error(), func() {
+			w := s.serve(tc.err)
+
+			s.Equal(tc.status, w.Code)
+			s.Contains(w.Body.String(), tc.err.Error())
+		})
+	}
+}
+
+func (s *ErrorMiddlewareTestSuite) TestUnknownErrorsAreHidden() {
+	w := s.serve(fmt.Errorf("mongo: connection refused on 10.0.0.3"))
+
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Contains(w.Body.String(), "internal server error")
+	s.NotContains(w.Body.String(), "10.0.0.3")
+}
+
+func (s *ErrorMiddlewareTestSuite) TestInternalCauseIsHidden() {
+	w := s.serve(domain.Internal("failed to retrieve", errors.New("mongo: connection refused")))
+
+	s.Contains(w.Body.String(), "failed to retrieve")
+	s.NotContains(w.Body.String(), "connection refused")
+}
+
+func (s *ErrorMiddlewareTestSuite) TestProblemDetails() {
+	w := s.serve(domain.NotFound("task not found"))
+
+	s.Equal(infrastruture.ProblemContentType, w.Header().Get("Content-Type"))
+	s.Equal(infrastruture.Problem{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "task not found",
+		Instance: "/tasks/42",
+		Code:     "not_found",
+	}, s.problem(w))
+}
+
+func (s *ErrorMiddlewareTestSuite) TestFieldErrors() {
+	w := s.serve(domain.FieldValidation("title", "title cannot be empty"))
+
+	problem := s.problem(w)
+	s.Equal(http.StatusBadRequest, problem.Status)
+	s.Equal("validation_failed", problem.Code)
+	s.Equal([]domain.FieldError{{Field: "title", Message: "title cannot be empty"}}, problem.Errors)
+}
+
+func (s *ErrorMiddlewareTestSuite) TestSpecificCodes() {
+	w := s.serve(domain.ErrVersionMismatch)
+
+	s.Equal("version_mismatch", s.problem(w).Code)
+}
+
+func (s *ErrorMiddlewareTestSuite) TestTimeouts() {
+	w := s.serve(domain.Internal("failed to retrieve", fmt.Errorf("find: %w", context.DeadlineExceeded)))
+
+	problem := s.problem(w)
+	s.Equal(http.StatusGatewayTimeout, w.Code)
+	s.Equal("timeout", problem.Code)
+	s.NotContains(w.Body.String(), "deadline")
+}
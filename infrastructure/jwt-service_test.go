@@ -4,6 +4,7 @@ import (
 	domain "task_management/Domain"
 	infrastruture "task_management/infrastructure"
 	"testing"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/suite"
@@ -15,8 +16,12 @@ type JWTServiceTestSuite struct {
 
 }
 
+func (s *JWTServiceTestSuite) SetupTest() {
+	s.service = infrastruture.NewJWTService("wellwellwell", time.Hour, 24*time.Hour).(*infrastruture.JWTService)
+}
+
 func (s *JWTServiceTestSuite) TestGenerateToken(){
-	token,err:=s.service.GenerateToken("1",domain.RoleUser)
+	token,err:=s.service.GenerateToken("1",domain.RoleUser, "session")
 	s.NoError(err)
 	s.NotEmpty(token)
 
@@ -29,13 +34,14 @@ func (s *JWTServiceTestSuite) TestGenerateToken(){
 
 	claims:=parsed.Claims.(jwt.MapClaims)
 	s.Equal("1",claims["sub"])
-	s.Equal(domain.RoleUser,claims["role"])
+	s.Equal(string(domain.RoleUser),claims["role"])
+	s.Equal("session",claims["sid"])
 	
 }
 
 func (s *JWTServiceTestSuite) TestInvalidSecret() {
 	
-	token, _ := s.service.GenerateToken("1", domain.RoleUser)
+	token, _ := s.service.GenerateToken("1", domain.RoleUser, "session")
 	
 	// Try to parse with wrong secret
 	_, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
@@ -44,6 +50,47 @@ func (s *JWTServiceTestSuite) TestInvalidSecret() {
 	s.Error(err)
 }
 
+func (s *JWTServiceTestSuite) TestParseToken() {
+	before := time.Now().Truncate(time.Millisecond)
+	token, err := s.service.GenerateToken("1", domain.RoleUser, "session")
+	s.Require().NoError(err)
+
+	claims, err := s.service.ParseToken(token)
+	s.Require().NoError(err)
+	s.Equal("1", claims.UserID)
+	s.Equal(domain.RoleUser, claims.Role)
+	s.Equal("session", claims.SessionID)
+	s.True(domain.IsValidID(claims.TokenID))
+	s.False(claims.IssuedAt.Before(before))
+	s.WithinDuration(time.Now().Add(time.Hour), claims.ExpiresAt, 2*time.Second)
+
+	//every token gets its own jti
+	other, _ := s.service.GenerateToken("1", domain.RoleUser, "session")
+	otherClaims, err := s.service.ParseToken(other)
+	s.Require().NoError(err)
+	s.NotEqual(claims.TokenID, otherClaims.TokenID)
+
+	_, err = infrastruture.NewJWTService("notwell", time.Hour, 24*time.Hour).ParseToken(token)
+	s.ErrorIs(err, domain.ErrUnauthorized)
+	_, err = s.service.ParseToken("not-a-token")
+	s.ErrorIs(err, domain.ErrUnauthorized)
+}
+
+func (s *JWTServiceTestSuite) TestGenerateRefreshToken() {
+	token, hash, err := s.service.GenerateRefreshToken()
+	s.NoError(err)
+	s.NotEmpty(token)
+
+	//the stored hash is not the token but can be found again from it
+	s.NotEqual(token, hash)
+	s.Equal(hash, s.service.HashRefreshToken(token))
+
+	other, otherHash, err := s.service.GenerateRefreshToken()
+	s.NoError(err)
+	s.NotEqual(token, other)
+	s.NotEqual(hash, otherHash)
+}
+
 func TestJWTServiceSuite(t *testing.T) {
 	suite.Run(t, new(JWTServiceTestSuite))
 }
\ No newline at end of file
@@ -1,6 +1,12 @@
 package infrastruture
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
 	domain "task_management/Domain"
 	usecases "task_management/usecases"
 	"time"
@@ -10,26 +16,125 @@ import (
 
 // JWTServiceImpl implements domain.JWTService
 type JWTService struct {
-	secretKey []byte
+	secretKey  []byte
+	ttl        time.Duration
+	refreshTTL time.Duration
 }
 
-// NewJWTService returns a new instance of JWTServiceImpl with a secret key
-func NewJWTService(secret string)usecases.IJWTService {
+// NewJWTService returns a new instance of JWTServiceImpl with a secret key,
+// the access tokens it signs expire after ttl and the refresh tokens after refreshTTL
+func NewJWTService(secret string, ttl, refreshTTL time.Duration)usecases.IJWTService {
 	return &JWTService{
-		secretKey: []byte(secret),
+		secretKey:  []byte(secret),
+		ttl:        ttl,
+		refreshTTL: refreshTTL,
 	}
 }
 
+// refreshTokenBytes is the amount of randomness in a refresh or personal access token
+const refreshTokenBytes = 32
 
-// GenerateToken creates a signed JWT token for the given user ID and role
-func (j *JWTService) GenerateToken(userID string, role domain.Role) (string, error) {
+
+// GenerateToken creates a signed JWT token for the given user ID, role and session.
+// every token gets its own jti so it can be revoked alone
+func (j *JWTService) GenerateToken(userID string, role domain.Role, sessionID string) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
+		"jti":  domain.NewID(),
 		"sub":  userID,
 		"role": role,
-		"exp":  time.Now().Add(24 * time.Hour).Unix(),
+		"sid":  sessionID,
+		//milliseconds, a login right after a "log out everywhere" must not look older than it
+		"iat": float64(now.UnixMilli()) / 1000,
+		"exp": now.Add(j.ttl).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(j.secretKey)
+}
+
+// ParseToken verifies an access token and reads its claims
+func (j *JWTService) ParseToken(token string) (*domain.AccessClaims, error) {
+	return parseAccessToken(j.secretKey, token)
+}
+
+// parseAccessToken is shared with the auth middleware, tokens without a jti are refused
+func parseAccessToken(secret []byte, tokenstr string) (*domain.AccessClaims, error) {
+	token, err := jwt.Parse(tokenstr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("error in signing method")
+		}
+		return secret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, domain.Unauthorized("unauthorized: invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, domain.Unauthorized("unauthorized: invalid token claims")
+	}
+	userID, ok1 := claims["sub"].(string)
+	role, ok2 := claims["role"].(string)
+	if !ok1 || !ok2 {
+		return nil, domain.Unauthorized("unauthorized: missing user info in token")
+	}
+	tokenID, ok1 := claims["jti"].(string)
+	issuedAt, ok2 := claims["iat"].(float64)
+	expiresAt, ok3 := claims["exp"].(float64)
+	sessionID, ok4 := claims["sid"].(string)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, domain.Unauthorized("unauthorized: invalid token claims")
+	}
+	return &domain.AccessClaims{
+		TokenID:   tokenID,
+		UserID:    userID,
+		Role:      domain.Role(role),
+		SessionID: sessionID,
+		IssuedAt:  time.UnixMilli(int64(math.Round(issuedAt * 1000))).UTC(),
+		ExpiresAt: time.Unix(int64(expiresAt), 0).UTC(),
+	}, nil
+}
+
+// GenerateRefreshToken creates an opaque random refresh token, only its hash is stored
+func (j *JWTService) GenerateRefreshToken() (string, string, error) {
+	raw := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, j.HashRefreshToken(token), nil
+}
+
+// HashRefreshToken hashes a refresh token, the tokens are random so a plain sha256 is enough
+func (j *JWTService) HashRefreshToken(token string) string {
+	return hashToken(token)
+}
+
+// GeneratePersonalToken creates a random personal access token, the prefix tells it
+// apart from the signed access tokens. only its hash is stored
+func (j *JWTService) GeneratePersonalToken() (string, string, error) {
+	raw := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := domain.PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+// hashToken hashes an opaque random token, it is shared with the auth middleware
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AccessTTL is how long the access tokens stay valid
+func (j *JWTService) AccessTTL() time.Duration {
+	return j.ttl
+}
+
+// RefreshTTL is how long the refresh tokens stay valid
+func (j *JWTService) RefreshTTL() time.Duration {
+	return j.refreshTTL
 }
 
 
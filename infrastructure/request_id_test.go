@@ -0,0 +1,52 @@
+package infrastruture_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	infrastruture "task_management/infrastructure"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+)
+
+type RequestIDTestSuite struct {
+	suite.Suite
+}
+
+func TestRequestIDSuite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suite.Run(t, new(RequestIDTestSuite))
+}
+
+// serve runs a request through the middleware and returns the response and the id the handler saw
+func (s *RequestIDTestSuite) serve(header string) (*httptest.ResponseRecorder, string) {
+	var seen string
+	router := gin.New()
+	router.Use(infrastruture.RequestID())
+	router.GET("/tasks", func(c *gin.Context) {
+		seen = infrastruture.RequestIDFromContext(c.Request.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	if header != "" {
+		req.Header.Set(infrastruture.RequestIDHeader, header)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, seen
+}
+
+func (s *RequestIDTestSuite) TestGeneratesID() {
+	w, seen := s.serve("")
+
+	s.Len(seen, 32)
+	s.Equal(seen, w.Header().Get(infrastruture.RequestIDHeader))
+}
+
+func (s *RequestIDTestSuite) TestKeepsClientID() {
+	w, seen := s.serve("trace-123")
+
+	s.Equal("trace-123", seen)
+	s.Equal("trace-123", w.Header().Get(infrastruture.RequestIDHeader))
+}
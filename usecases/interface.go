@@ -1,18 +1,31 @@
 package usecases
 
 import (
+	"context"
+	"net/http"
 	domain "task_management/Domain"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // user related interfaces
 type IUserRepository interface {
-	CreateUser(user *domain.User) error
-	FindByUsername(username string) (*domain.User, error)
-	CountByUsername(username string) (int64, error)
-	CountAll() (int64, error)
-	PromoteUser(userID string) error
+	CreateUser(ctx context.Context, user *domain.User) error
+	FindByUsername(ctx context.Context, username string) (*domain.User, error)
+	FindByID(ctx context.Context, userID string) (*domain.User, error)
+	ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserPage, error)
+	CountByUsername(ctx context.Context, username string) (int64, error)
+	CountAll(ctx context.Context) (int64, error)
+	// CountByRole only counts the active users
+	CountByRole(ctx context.Context, role domain.Role) (int64, error)
+	// UpdateRole, UpdateDeactivatedAt and DeleteUser return domain.ErrLastAdmin instead of
+	// taking the admin role or the account away from the last active admin, the check and
+	// the write are atomic
+	UpdateRole(ctx context.Context, userID string, role domain.Role) error
+	// UpdateDeactivatedAt deactivates the user, a nil time reactivates them
+	UpdateDeactivatedAt(ctx context.Context, userID string, at *time.Time) error
+	DeleteUser(ctx context.Context, userID string) error
 }
 
 type IPasswordService interface {
@@ -21,18 +34,107 @@ type IPasswordService interface {
 }
 
 type IJWTService interface {
-	GenerateToken(userID string, role domain.Role) (string, error)
+	// GenerateToken signs a short lived access token with a unique jti for the session
+	GenerateToken(userID string, role domain.Role, sessionID string) (string, error)
+	// ParseToken checks the signature and expiry of an access token and returns its claims
+	ParseToken(token string) (*domain.AccessClaims, error)
+	// GenerateRefreshToken returns a new random refresh token and the hash to store in its place
+	GenerateRefreshToken() (token string, hash string, err error)
+	// HashRefreshToken returns the stored hash of a refresh token sent by a client
+	HashRefreshToken(token string) string
+	// GeneratePersonalToken returns a new random personal access token and the hash to store in its place
+	GeneratePersonalToken() (token string, hash string, err error)
+	AccessTTL() time.Duration
+	RefreshTTL() time.Duration
+}
+
+// refresh tokens are rotated: each one is exchanged once for a new token of the same
+// family, a used token coming back means it leaked and its family is revoked
+type IRefreshTokenRepo interface {
+	CreateToken(ctx context.Context, token *domain.RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*domain.RefreshToken, error)
+	// MarkUsed returns domain.ErrConflict when the token was already used, only one
+	// of two concurrent refreshes with the same token wins
+	MarkUsed(ctx context.Context, tokenID string, at time.Time) error
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeUserTokens revokes every refresh token of the user
+	RevokeUserTokens(ctx context.Context, userID string, at time.Time) error
+}
+
+// sessions are checked by the auth middleware on every request
+type ISessionRepo interface {
+	CreateSession(ctx context.Context, session *domain.Session) error
+	FindSession(ctx context.Context, sessionID string) (*domain.Session, error)
+	// ListSessions returns the sessions of the user that are not revoked, last seen first
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	// TouchSession moves the last seen time forward, never back
+	TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error
+	// ExtendSession touches the session and moves its expiry to expiresAt
+	ExtendSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error
+	// RevokeSession keeps the first revocation time of an already revoked session
+	RevokeSession(ctx context.Context, sessionID string, at time.Time) error
+	RevokeUserSessions(ctx context.Context, userID string, at time.Time) error
+}
+
+// personal access tokens are looked up by their hash on every request that sends one
+type IPersonalTokenRepo interface {
+	CreatePersonalToken(ctx context.Context, token *domain.PersonalToken) error
+	FindPersonalToken(ctx context.Context, tokenID string) (*domain.PersonalToken, error)
+	FindPersonalTokenByHash(ctx context.Context, hash string) (*domain.PersonalToken, error)
+	// ListPersonalTokens returns the tokens of the user that are not revoked, newest first
+	ListPersonalTokens(ctx context.Context, userID string) ([]domain.PersonalToken, error)
+	// TouchPersonalToken moves the last used time forward, never back
+	TouchPersonalToken(ctx context.Context, tokenID string, usedAt time.Time) error
+	// RevokePersonalToken keeps the first revocation time of an already revoked token
+	RevokePersonalToken(ctx context.Context, tokenID string, at time.Time) error
+	RevokeUserPersonalTokens(ctx context.Context, userID string, at time.Time) error
+}
+
+// access tokens are checked against the revocation store on every request. entries
+// only matter until the tokens they revoke expire, stores may drop them after expiresAt
+type ITokenRevocationRepo interface {
+	// RevokeToken revokes a single access token by its jti
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// RevokeUserTokens revokes every access token of the user issued before or at at
+	RevokeUserTokens(ctx context.Context, userID string, at, expiresAt time.Time) error
+	// IsRevoked reports whether the token was revoked on its own or along with every token of its user
+	IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error)
 }
 
 // task related interfaces
 
+// writes taking a version only succeed while the stored task still has that
+// version and return domain.ErrVersionMismatch otherwise, every write bumps the version
 type ITaskRepo interface {
-	CreateTask(task *domain.Task) error
-	GetAllTasks() ([]domain.Task, error)
-	GetTaskByID(taskID string) (*domain.Task, error)
-	UpdateTaskByID(taskID string, updatedTask *domain.Task) error
-	DeleteTaskByID(taskID string) error
+	CreateTask(ctx context.Context, task *domain.Task) error
+	GetAllTasks(ctx context.Context, filter domain.TaskFilter) (*domain.TaskPage, error)
+	GetTaskByID(ctx context.Context, taskID string) (*domain.Task, error)
+	// UpdateTaskFields writes the title, description, dueDate, ownerId and status fields,
+	// a status change also appends the last entry of the task status history
+	UpdateTaskFields(ctx context.Context, taskID string, task *domain.Task, fields []string, version int64) error
+	// DeleteTaskByID removes the task for good, TrashTaskByID is the soft delete
+	DeleteTaskByID(ctx context.Context, taskID string, version int64) error
+	TrashTaskByID(ctx context.Context, taskID string, deletedBy string, at time.Time, version int64) error
+	RestoreTaskByID(ctx context.Context, taskID string, version int64) error
+	// PurgeTrashedBefore removes the tasks trashed before the cutoff for good and returns
+	// them, on an error the tasks already removed are returned with it
+	PurgeTrashedBefore(ctx context.Context, cutoff time.Time) ([]domain.Task, error)
+	TransitionTaskStatus(ctx context.Context, taskID string, transition domain.StatusTransition, version int64) error
+	AddAssignee(ctx context.Context, taskID string, userID string) error
+	RemoveAssignee(ctx context.Context, taskID string, userID string) error
+}
+
+// the audit trail is append only, there is no way to change or remove a record
+type IAuditRepo interface {
+	AddRecord(ctx context.Context, record *domain.AuditRecord) error
+	GetRecords(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error)
 }
 type IAuthService interface {
 	AuthWithRole(roles ...string) gin.HandlerFunc
+	// TokenScopes opens the routes after it to personal access tokens, reads need the
+	// read scope and every other method the write scope
+	TokenScopes(read, write domain.Scope) gin.HandlerFunc
+	// AccessToken returns the access token a request authenticates with, the token
+	// precedence policy picks between the Authorization header and the auth_token cookie
+	AccessToken(r *http.Request) (string, error)
 }
@@ -1,71 +1,642 @@
 package usecases
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	domain "task_management/Domain"
+	"time"
 
 	// repositories "task_management/Repositories"
+)
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
+// page sizes used when listing tasks
+const (
+	DefaultPageSize int64 = 20
+	MaxPageSize     int64 = 100
 )
 
+// DefaultTrashRetention keeps deleted tasks restorable for 30 days
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
 // define TaskUseCase struct
 type TaskUseCase struct {
 	TaskRepo ITaskRepo
+	UserRepo IUserRepository
+	// AuditRepo gets a record of every change made to a task
+	AuditRepo IAuditRepo
+	// Transitions is the status graph every status change must follow
+	Transitions domain.TransitionGraph
+	// TrashRetention is how long deleted tasks stay in the trash before they are purged
+	TrashRetention time.Duration
+	// Now is the clock used for due date checks and transition timestamps
+	Now func() time.Time
 }
 
-func NewTaskUseCase(repo ITaskRepo) *TaskUseCase {
+func NewTaskUseCase(repo ITaskRepo, userRepo IUserRepository, auditRepo IAuditRepo) *TaskUseCase {
 	return &TaskUseCase{
-		TaskRepo: repo,
+		TaskRepo:       repo,
+		UserRepo:       userRepo,
+		AuditRepo:      auditRepo,
+		Transitions:    domain.DefaultTransitionGraph(),
+		TrashRetention: DefaultTrashRetention,
+		Now:            time.Now,
 	}
 }
 
-// add new task usecase
-func (uc *TaskUseCase) AddTask(input *domain.InputTask) (*domain.Task, error) {
+// add new task usecase, the actor becomes the owner of the task
+func (uc *TaskUseCase) AddTask(ctx context.Context, actor domain.Actor, input *domain.InputTask) (*domain.Task, error) {
+
+	//new tasks start as not-started unless told otherwise
+	status := input.Status
+	if status == "" {
+		status = domain.StatusNotStarted
+	}
+	if err := validateFields(TaskRules, map[string]string{
+		"title":       input.Title,
+		"description": input.Description,
+		"status":      string(status),
+		"dueDate":     input.DueDate,
+	}); err != nil {
+		return nil, err
+	}
+	dueDate, err := parseDueDate(input.DueDate)
+	if err != nil {
+		return nil, err
+	}
+	if dueDate != nil && dueDate.Before(uc.Now()) && !input.AllowPastDueDate {
+		return nil, domain.FieldValidation("dueDate", "dueDate is in the past, set allowPastDueDate to create the task anyway")
+	}
 
 	task := &domain.Task{
-		ID:          primitive.NewObjectID(),
+		ID:          domain.NewID(),
 		Title:       input.Title,
 		Description: input.Description,
-		Status:      input.Status,
+		DueDate:     dueDate,
+		Status:      status,
+		OwnerID:     actor.UserID,
+		Assignees:   []string{},
+		Version:     1,
+		StatusHistory: []domain.StatusTransition{
+			{To: status, ActorID: actor.UserID, At: uc.Now()},
+		},
 	}
 
-	err := uc.TaskRepo.CreateTask(task)
+	err = uc.TaskRepo.CreateTask(ctx, task)
+	if errors.Is(err, domain.ErrConflict) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, errors.New("failed to create task")
+		return nil, domain.Internal("failed to create task", err)
+	}
+	if err := uc.audit(ctx, actor, domain.AuditCreate, task.ID, nil, task); err != nil {
+		return nil, err
 	}
 	return task, nil
 }
 
-// getalltasksusecase
-func (uc *TaskUseCase) GetAllTasks() ([]domain.Task, error) {
+// getalltasksusecase, admins see every task while users only see the tasks
+// they own or are assigned to
+func (uc *TaskUseCase) GetAllTasks(ctx context.Context, actor domain.Actor, filter domain.TaskFilter) (*domain.TaskPage, error) {
 
-	tasks, err := uc.TaskRepo.GetAllTasks()
+	if err := normalizeTaskFilter(&filter); err != nil {
+		return nil, err
+	}
+	if err := uc.resolveDueDateModes(&filter); err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin() {
+		filter.VisibleTo = actor.UserID
+	}
+
+	page, err := uc.TaskRepo.GetAllTasks(ctx, filter)
 	if err != nil {
-		return nil, errors.New("failed to retrieve")
+		return nil, domain.Internal("failed to retrieve", err)
 	}
-	return tasks, nil
+	return page, nil
 
 }
 
 // get task byID use case
-func (uc *TaskUseCase) GetTaskByID(id string) (*domain.Task, error) {
+func (uc *TaskUseCase) GetTaskByID(ctx context.Context, actor domain.Actor, id string) (*domain.Task, error) {
+
+	return uc.findVisibleTask(ctx, actor, id)
+}
+
+// update task by id, owners and assignees can update a task.
+// version is the task version the client last read, the updated task is returned
+func (uc *TaskUseCase) UpdateTaskByID(ctx context.Context, actor domain.Actor, id string, input *domain.InputTask, version int64) (*domain.Task, error) {
+
+	task, err := uc.findVisibleTask(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Version != version {
+		return nil, domain.ErrVersionMismatch
+	}
+	//an empty status keeps the current one
+	values := map[string]string{"title": input.Title, "description": input.Description, "dueDate": input.DueDate}
+	if input.Status != "" {
+		values["status"] = string(input.Status)
+	}
+	if err := validateFields(TaskRules, values); err != nil {
+		return nil, err
+	}
+	dueDate, err := parseDueDate(input.DueDate)
+	if err != nil {
+		return nil, err
+	}
+	before := *task
+	//the owner and the assignees never change through an update
+	fields := []string{"title", "description", "dueDate"}
+	//a status change has to follow the transition graph and is written with the other fields
+	if input.Status != "" && input.Status != task.Status {
+		if err := uc.moveStatus(actor, task, input.Status); err != nil {
+			return nil, err
+		}
+		fields = append(fields, "status")
+	}
+	task.Title = input.Title
+	task.Description = input.Description
+	task.DueDate = dueDate
+
+	if err := uc.TaskRepo.UpdateTaskFields(ctx, id, task, fields, task.Version); err != nil {
+		return nil, err
+	}
+	task.Version++
+	if err := uc.audit(ctx, actor, domain.AuditUpdate, id, &before, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// applies a partial update to a visible task, only the fields set in the patch change.
+// version is the task version the client last read, the updated task is returned
+func (uc *TaskUseCase) PatchTask(ctx context.Context, actor domain.Actor, id string, patch *domain.TaskPatch, version int64) (*domain.Task, error) {
 
-	task, err := uc.TaskRepo.GetTaskByID(id)
+	task, err := uc.findVisibleTask(ctx, actor, id)
 	if err != nil {
-		return nil, errors.New("task not found")
+		return nil, err
+	}
+	if task.Version != version {
+		return nil, domain.ErrVersionMismatch
+	}
+
+	//validate every changed field before anything is written
+	if err := validateFields(TaskRules, patchValues(patch)); err != nil {
+		return nil, err
+	}
+	before := *task
+	var fields []string
+	if patch.Title != nil {
+		task.Title = *patch.Title
+		fields = append(fields, "title")
+	}
+	if patch.Description != nil {
+		task.Description = *patch.Description
+		fields = append(fields, "description")
+	}
+	if patch.DueDate != nil {
+		dueDate, err := parseDueDate(*patch.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		task.DueDate = dueDate
+		fields = append(fields, "dueDate")
+	}
+	//the status goes through the transition graph and is written with the other fields
+	if patch.Status != nil && *patch.Status != task.Status {
+		if err := uc.moveStatus(actor, task, *patch.Status); err != nil {
+			return nil, err
+		}
+		fields = append(fields, "status")
+	}
+
+	//an empty patch changes nothing, it is neither written nor audited
+	if len(fields) == 0 {
+		return task, nil
+	}
+	if err := uc.TaskRepo.UpdateTaskFields(ctx, id, task, fields, task.Version); err != nil {
+		return nil, err
+	}
+	task.Version++
+	if err := uc.audit(ctx, actor, domain.AuditUpdate, id, &before, task); err != nil {
+		return nil, err
 	}
 	return task, nil
 }
 
-// update task by id
-func (uc *TaskUseCase) UpdateTaskByID(id string, input *domain.Task) error {
+// delete task by id moves the task to the trash, only the owner can delete a task.
+// version is the task version the client last read
+func (uc *TaskUseCase) DeleteTaskByID(ctx context.Context, actor domain.Actor, id string, version int64) error {
+
+	task, err := uc.findOwnTask(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if task.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	deletedAt := uc.Now()
+	if err := uc.TaskRepo.TrashTaskByID(ctx, id, actor.UserID, deletedAt, version); err != nil {
+		return err
+	}
+	trashed := *task
+	trashed.DeletedAt = &deletedAt
+	trashed.DeletedBy = actor.UserID
+	return uc.audit(ctx, actor, domain.AuditDelete, id, task, &trashed)
+}
+
+// lists the trashed tasks the actor can see
+func (uc *TaskUseCase) GetTrash(ctx context.Context, actor domain.Actor, filter domain.TaskFilter) (*domain.TaskPage, error) {
 
-	return uc.TaskRepo.UpdateTaskByID(id, input)
+	filter.Trashed = true
+	return uc.GetAllTasks(ctx, actor, filter)
 }
 
-// delete task by id
-func (uc *TaskUseCase) DeleteTaskByID(id string) error {
+// takes a task the actor owns back out of the trash and returns it
+func (uc *TaskUseCase) RestoreTask(ctx context.Context, actor domain.Actor, id string) (*domain.Task, error) {
 
-	return uc.TaskRepo.DeleteTaskByID(id)
+	task, err := uc.findTrashedTask(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.TaskRepo.RestoreTaskByID(ctx, id, task.Version); err != nil {
+		return nil, err
+	}
+	before := *task
+	task.DeletedAt = nil
+	task.DeletedBy = ""
+	task.Version++
+	if err := uc.audit(ctx, actor, domain.AuditRestore, id, &before, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// permanently removes a trashed task, only admins can purge
+func (uc *TaskUseCase) PurgeTask(ctx context.Context, actor domain.Actor, id string) error {
+
+	if !actor.IsAdmin() {
+		return domain.Forbidden("only admins can purge tasks")
+	}
+	task, err := uc.findTrashedTask(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if err := uc.TaskRepo.DeleteTaskByID(ctx, id, task.Version); err != nil {
+		return err
+	}
+	return uc.audit(ctx, actor, domain.AuditPurge, id, task, nil)
+}
+
+// permanently removes the tasks that stayed in the trash longer than the retention window
+// and returns how many were removed, every purge is audited as done by the system actor
+func (uc *TaskUseCase) PurgeExpiredTrash(ctx context.Context) (int64, error) {
+
+	purged, err := uc.TaskRepo.PurgeTrashedBefore(ctx, uc.Now().Add(-uc.TrashRetention))
+	//the tasks removed before an error are gone too and get their record
+	for i := range purged {
+		if err := uc.audit(ctx, domain.SystemActor, domain.AuditPurge, purged[i].ID, &purged[i], nil); err != nil {
+			return int64(len(purged)), err
+		}
+	}
+	return int64(len(purged)), err
+}
+
+// moves a visible task to another status and returns the updated task
+func (uc *TaskUseCase) TransitionTask(ctx context.Context, actor domain.Actor, id string, to domain.TaskStatus) (*domain.Task, error) {
+
+	task, err := uc.findVisibleTask(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *task
+	if err := uc.transition(ctx, actor, task, to); err != nil {
+		return nil, err
+	}
+	if err := uc.audit(ctx, actor, domain.AuditTransition, id, &before, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// returns the status transitions of a visible task, oldest first
+func (uc *TaskUseCase) GetTaskTransitions(ctx context.Context, actor domain.Actor, id string) ([]domain.StatusTransition, error) {
+
+	task, err := uc.findVisibleTask(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.StatusHistory == nil {
+		return []domain.StatusTransition{}, nil
+	}
+	return task.StatusHistory, nil
+}
+
+// transition checks the move against the transition graph, stores it and applies it to task
+func (uc *TaskUseCase) transition(ctx context.Context, actor domain.Actor, task *domain.Task, to domain.TaskStatus) error {
+	moved := *task
+	if err := uc.moveStatus(actor, &moved, to); err != nil {
+		return err
+	}
+	transition, _ := moved.LastTransition()
+	if err := uc.TaskRepo.TransitionTaskStatus(ctx, task.ID, transition, task.Version); err != nil {
+		return err
+	}
+	moved.Version++
+	*task = moved
+	return nil
+}
+
+// moveStatus checks the move against the transition graph and applies it to task
+// without storing it, the caller writes the status with UpdateTaskFields
+func (uc *TaskUseCase) moveStatus(actor domain.Actor, task *domain.Task, to domain.TaskStatus) error {
+	if err := uc.checkTransition(task.Status, to); err != nil {
+		return err
+	}
+	transition := domain.StatusTransition{From: task.Status, To: to, ActorID: actor.UserID, At: uc.Now()}
+	task.Status = to
+	//a new slice, the task copies taken before the move keep their history
+	task.StatusHistory = append(append([]domain.StatusTransition(nil), task.StatusHistory...), transition)
+	return nil
+}
+
+// checkTransition rejects unknown statuses and moves missing from the transition graph
+func (uc *TaskUseCase) checkTransition(from, to domain.TaskStatus) error {
+	if !to.IsValid() {
+		return invalidStatusError(to)
+	}
+	if !uc.Transitions.Allows(from, to) {
+		return domain.Conflict(fmt.Sprintf("cannot move task from %q to %q", from, to))
+	}
+	return nil
+}
+
+func invalidStatusError(status domain.TaskStatus) error {
+	return domain.FieldValidation("status", fmt.Sprintf("invalid status %q: must be one of %s, %s, %s", status, domain.StatusNotStarted, domain.StatusInProgress, domain.StatusCompleted))
+}
+
+// assigns an existing user to the task and returns the updated task
+func (uc *TaskUseCase) AddAssignee(ctx context.Context, actor domain.Actor, taskID string, userID string) (*domain.Task, error) {
+
+	before, err := uc.findOwnTask(ctx, actor, taskID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = uc.UserRepo.FindByID(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrValidation) {
+		return nil, domain.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, domain.Internal("failed to find user", err)
+	}
+	if err := uc.TaskRepo.AddAssignee(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+	return uc.reloadAndAudit(ctx, actor, domain.AuditAssign, taskID, before)
+}
+
+// removes a user from the task assignees and returns the updated task
+func (uc *TaskUseCase) RemoveAssignee(ctx context.Context, actor domain.Actor, taskID string, userID string) (*domain.Task, error) {
+
+	task, err := uc.findOwnTask(ctx, actor, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !task.IsAssignee(userID) {
+		return nil, domain.NotFound("user is not assigned to this task")
+	}
+	if err := uc.TaskRepo.RemoveAssignee(ctx, taskID, userID); err != nil {
+		return nil, err
+	}
+	return uc.reloadAndAudit(ctx, actor, domain.AuditUnassign, taskID, task)
+}
+
+// returns the audit trail of a task the actor can see, trashed tasks included
+func (uc *TaskUseCase) GetTaskHistory(ctx context.Context, actor domain.Actor, id string, filter domain.AuditFilter) (*domain.AuditPage, error) {
+
+	task, err := uc.loadTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin() && task.OwnerID != actor.UserID && !task.IsAssignee(actor.UserID) {
+		return nil, domain.NotFound("task not found")
+	}
+	filter.TaskID = id
+	return uc.getAuditRecords(ctx, filter)
+}
+
+// returns the audit trail of every task, only admins can read it
+func (uc *TaskUseCase) GetAuditTrail(ctx context.Context, actor domain.Actor, filter domain.AuditFilter) (*domain.AuditPage, error) {
+
+	if !actor.IsAdmin() {
+		return nil, domain.Forbidden("only admins can read the audit trail")
+	}
+	return uc.getAuditRecords(ctx, filter)
+}
+
+func (uc *TaskUseCase) getAuditRecords(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = DefaultPageSize
+	case filter.Limit < 0:
+		return nil, domain.FieldValidation("limit", "limit must be positive")
+	case filter.Limit > MaxPageSize:
+		filter.Limit = MaxPageSize
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, domain.Validation("from must be before to")
+	}
+	if _, err := domain.DecodeCursor(filter.Cursor); err != nil {
+		return nil, err
+	}
+
+	page, err := uc.AuditRepo.GetRecords(ctx, filter)
+	if err != nil {
+		return nil, domain.Internal("failed to retrieve", err)
+	}
+	return page, nil
+}
+
+// audit adds the record of a change to the audit trail, before or after is nil
+// when the task did not exist before or does not exist anymore
+func (uc *TaskUseCase) audit(ctx context.Context, actor domain.Actor, action domain.AuditAction, taskID string, before, after *domain.Task) error {
+	record := &domain.AuditRecord{
+		TaskID:  taskID,
+		Action:  action,
+		ActorID: actor.UserID,
+		At:      uc.Now(),
+		Changes: domain.DiffTasks(before, after),
+	}
+	if err := uc.AuditRepo.AddRecord(ctx, record); err != nil {
+		return domain.Internal("failed to record the change in the audit trail", err)
+	}
+	return nil
+}
+
+// reloadAndAudit loads the task after a repository side change and audits the difference
+func (uc *TaskUseCase) reloadAndAudit(ctx context.Context, actor domain.Actor, action domain.AuditAction, taskID string, before *domain.Task) (*domain.Task, error) {
+	task, err := uc.TaskRepo.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.audit(ctx, actor, action, taskID, before, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// normalizeTaskFilter applies the paging defaults and rejects filters the repository cannot serve
+func normalizeTaskFilter(filter *domain.TaskFilter) error {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = DefaultPageSize
+	case filter.Limit < 0:
+		return domain.FieldValidation("limit", "limit must be positive")
+	case filter.Limit > MaxPageSize:
+		filter.Limit = MaxPageSize
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = domain.SortByID
+	case domain.SortByID, domain.SortByTitle, domain.SortByDueDate, domain.SortByStatus:
+	default:
+		return domain.FieldValidation("sort", "invalid sort field")
+	}
+
+	if filter.DueAfter != nil && filter.DueBefore != nil && filter.DueAfter.After(*filter.DueBefore) {
+		return domain.Validation("dueAfter must be before dueBefore")
+	}
+
+	_, err := domain.DecodeCursor(filter.Cursor)
+	return err
+}
+
+// patchValues lists the fields a patch sets for validation
+func patchValues(patch *domain.TaskPatch) map[string]string {
+	values := map[string]string{}
+	if patch.Title != nil {
+		values["title"] = *patch.Title
+	}
+	if patch.Description != nil {
+		values["description"] = *patch.Description
+	}
+	if patch.Status != nil {
+		values["status"] = string(*patch.Status)
+	}
+	if patch.DueDate != nil {
+		values["dueDate"] = *patch.DueDate
+	}
+	return values
+}
+
+// parseDueDate reads an RFC3339 due date, an empty value means no due date
+func parseDueDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	dueDate, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, domain.FieldValidation("dueDate", fmt.Sprintf("invalid dueDate %q: expected an RFC3339 date such as 2025-01-31T17:00:00Z", value))
+	}
+	return &dueDate, nil
+}
+
+// resolveDueDateModes turns the overdue and due-within query modes into a due date range.
+// overdue tasks are past their due date and not completed yet
+func (uc *TaskUseCase) resolveDueDateModes(filter *domain.TaskFilter) error {
+	if !filter.Overdue && filter.DueWithinDays == 0 {
+		return nil
+	}
+	if filter.Overdue && filter.DueWithinDays != 0 {
+		return domain.Validation("overdue cannot be combined with dueWithin")
+	}
+	if filter.DueAfter != nil || filter.DueBefore != nil {
+		return domain.Validation("overdue and dueWithin cannot be combined with dueAfter or dueBefore")
+	}
+
+	now := uc.Now()
+	if filter.Overdue {
+		filter.DueBefore = &now
+		filter.ExcludeStatus = domain.StatusCompleted
+		return nil
+	}
+	if filter.DueWithinDays < 0 {
+		return domain.FieldValidation("dueWithin", "dueWithin must be a positive number of days")
+	}
+	until := now.AddDate(0, 0, filter.DueWithinDays)
+	filter.DueAfter = &now
+	filter.DueBefore = &until
+	return nil
+}
+
+// findTask loads a live task by its id after checking the id format, trashed tasks are not found
+func (uc *TaskUseCase) findTask(ctx context.Context, id string) (*domain.Task, error) {
+	task, err := uc.loadTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.IsTrashed() {
+		return nil, domain.NotFound("task not found")
+	}
+	return task, nil
+}
+
+// loadTask loads a task whether it is trashed or not
+func (uc *TaskUseCase) loadTask(ctx context.Context, id string) (*domain.Task, error) {
+	if !domain.IsValidID(id) {
+		return nil, domain.Validation("invalid task ID")
+	}
+
+	task, err := uc.TaskRepo.GetTaskByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.NotFound("task not found")
+	}
+	if err != nil {
+		return nil, domain.Internal("failed to load task", err)
+	}
+	return task, nil
+}
+
+// findTrashedTask loads a trashed task the actor owns
+func (uc *TaskUseCase) findTrashedTask(ctx context.Context, actor domain.Actor, id string) (*domain.Task, error) {
+	task, err := uc.loadTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin() && task.OwnerID != actor.UserID {
+		return nil, domain.NotFound("task not found")
+	}
+	if !task.IsTrashed() {
+		return nil, domain.Conflict("task is not in the trash")
+	}
+	return task, nil
+}
+
+// findVisibleTask loads a task the actor owns or is assigned to.
+// tasks the actor cannot see are reported as not found so their existence is not leaked
+func (uc *TaskUseCase) findVisibleTask(ctx context.Context, actor domain.Actor, id string) (*domain.Task, error) {
+	task, err := uc.findTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin() && task.OwnerID != actor.UserID && !task.IsAssignee(actor.UserID) {
+		return nil, domain.NotFound("task not found")
+	}
+	return task, nil
+}
+
+// findOwnTask loads a task the actor owns, admins own every task.
+// assignees can see the task but are told they are not its owner
+func (uc *TaskUseCase) findOwnTask(ctx context.Context, actor domain.Actor, id string) (*domain.Task, error) {
+	task, err := uc.findVisibleTask(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.IsAdmin() && task.OwnerID != actor.UserID {
+		return nil, domain.Forbidden("only the task owner can do this")
+	}
+	return task, nil
 }
@@ -1,60 +1,139 @@
 package usecases_test
 
 import (
+	"context"
 	"errors"
+	"strings"
 	domain "task_management/Domain"
 	"task_management/usecases"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MockTaskRepository struct {
 	mock.Mock
 }
 
-func (m *MockTaskRepository)CreateTask(task *domain.Task) error{
+func (m *MockTaskRepository) CreateTask(ctx context.Context, task *domain.Task) error {
 	args:=m.Called(task)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) GetAllTasks() ([]domain.Task, error) {
-    args:=m.Called()
-	return args.Get(0).([]domain.Task),args.Error(1)
+func (m *MockTaskRepository) GetAllTasks(ctx context.Context, filter domain.TaskFilter) (*domain.TaskPage, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.TaskPage), args.Error(1)
 }
 
-func (m *MockTaskRepository) GetTaskByID( taskID string) (*domain.Task, error) {
+func (m *MockTaskRepository) GetTaskByID(ctx context.Context, taskID string) (*domain.Task, error) {
 	args:=m.Called(taskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).(*domain.Task),args.Error(1)
 }
 
-func (m *MockTaskRepository) UpdateTaskByID(taskID string, updatedTask *domain.Task) error {
-	args:=m.Called(taskID)
+func (m *MockTaskRepository) UpdateTaskFields(ctx context.Context, taskID string, task *domain.Task, fields []string, version int64) error {
+	args := m.Called(taskID, task, fields, version)
 	return args.Error(0)
-	
 }
 
-func (m *MockTaskRepository) DeleteTaskByID( taskID string) error {
-	args:=m.Called(taskID)
+func (m *MockTaskRepository) DeleteTaskByID(ctx context.Context, taskID string, version int64) error {
+	args := m.Called(taskID, version)
 	return args.Error(0)
 	
 }
 
+func (m *MockTaskRepository) TrashTaskByID(ctx context.Context, taskID string, deletedBy string, at time.Time, version int64) error {
+	args := m.Called(taskID, deletedBy, at, version)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) RestoreTaskByID(ctx context.Context, taskID string, version int64) error {
+	args := m.Called(taskID, version)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) PurgeTrashedBefore(ctx context.Context, cutoff time.Time) ([]domain.Task, error) {
+	args := m.Called(cutoff)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) TransitionTaskStatus(ctx context.Context, taskID string, transition domain.StatusTransition, version int64) error {
+	args := m.Called(taskID, transition, version)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) AddAssignee(ctx context.Context, taskID string, userID string) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) RemoveAssignee(ctx context.Context, taskID string, userID string) error {
+	args := m.Called(taskID, userID)
+	return args.Error(0)
+}
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) AddRecord(ctx context.Context, record *domain.AuditRecord) error {
+	args := m.Called(record)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) GetRecords(ctx context.Context, filter domain.AuditFilter) (*domain.AuditPage, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AuditPage), args.Error(1)
+}
+
+// lastRecord returns the audit record written last
+func (m *MockAuditRepository) lastRecord() *domain.AuditRecord {
+	for i := len(m.Calls) - 1; i >= 0; i-- {
+		if m.Calls[i].Method == "AddRecord" {
+			return m.Calls[i].Arguments.Get(0).(*domain.AuditRecord)
+		}
+	}
+	return nil
+}
+
 //test suite
 type TaskUsecaseTestSuite struct{
 	suite.Suite
 	taskRepo *MockTaskRepository
+	userRepo *MockUserRepostitoy
+	auditRepo *MockAuditRepository
 	useCase *usecases.TaskUseCase
+	admin    domain.Actor
+	user     domain.Actor
 }
 
 //setting up the test
 func (suite *TaskUsecaseTestSuite) SetupTest(){
 	suite.taskRepo=new(MockTaskRepository)
+	suite.userRepo = new(MockUserRepostitoy)
+	suite.auditRepo = new(MockAuditRepository)
+	//every write is audited, tests that care about the record look at lastRecord
+	suite.auditRepo.On("AddRecord", mock.Anything).Return(nil).Maybe()
 	suite.useCase=usecases.NewTaskUseCase(
 		suite.taskRepo,
+		suite.userRepo,
+		suite.auditRepo,
 	)
+	suite.admin = domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}
+	suite.user = domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser}
 }
 
 func TestTaskUseCaseSuite( t *testing.T){
@@ -66,7 +145,7 @@ func (suite *TaskUsecaseTestSuite) TestAddTask() {
     input := &domain.InputTask{
         Title:       "Test Task",
         Description: "Test Description",
-        Status:      "pending",
+        Status:      domain.StatusInProgress,
     }
 
     // Test 1 Successful task creation
@@ -76,17 +155,45 @@ func (suite *TaskUsecaseTestSuite) TestAddTask() {
 
         suite.taskRepo.On("CreateTask", mock.AnythingOfType("*domain.Task")).Return(nil).Once()
 
-        task, err := suite.useCase.AddTask(input)
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, input)
         
         suite.NoError(err)
         suite.NotNil(task)
         suite.Equal(input.Title, task.Title)
         suite.Equal(input.Description, task.Description)
         suite.Equal(input.Status, task.Status)
+        suite.Equal(suite.user.UserID, task.OwnerID)
+        suite.Empty(task.Assignees)
         suite.NotEmpty(task.ID)
+        suite.Require().Len(task.StatusHistory, 1)
+        suite.Equal(domain.StatusInProgress, task.StatusHistory[0].To)
+        suite.Equal(suite.user.UserID, task.StatusHistory[0].ActorID)
         suite.taskRepo.AssertExpectations(suite.T())
     })
 
+    // Test 3  Status defaults to not-started
+    suite.Run("default status", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("CreateTask", mock.AnythingOfType("*domain.Task")).Return(nil).Once()
+
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Test Task"})
+
+        suite.NoError(err)
+        suite.Equal(domain.StatusNotStarted, task.Status)
+    })
+
+    // Test 4  Unknown statuses are rejected
+    suite.Run("invalid status", func() {
+        suite.SetupTest()
+
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Test Task", Status: "banana"})
+
+        suite.Nil(task)
+        suite.EqualError(err, `invalid status "banana": must be one of not-started, in-progress, completed`)
+        suite.taskRepo.AssertNotCalled(suite.T(), "CreateTask", mock.Anything)
+    })
+
     // Test 2  Repository returns error
     suite.Run("repository returns error", func() {
         suite.SetupTest()
@@ -94,7 +201,7 @@ func (suite *TaskUsecaseTestSuite) TestAddTask() {
         expectedErr := errors.New("database error")
         suite.taskRepo.On("CreateTask", mock.AnythingOfType("*domain.Task")).Return(expectedErr).Once()
 
-        task, err := suite.useCase.AddTask(input)
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, input)
         
         suite.Error(err)
         suite.Nil(task)
@@ -105,55 +212,113 @@ func (suite *TaskUsecaseTestSuite) TestAddTask() {
 
 func (suite *TaskUsecaseTestSuite) TestGetAllTasks() {
     // Setup test data
-    mockTasks := []domain.Task{
+    mockTasks := &domain.TaskPage{
+        Tasks: []domain.Task{
         {
-            ID:          primitive.NewObjectID(),
+                ID:          domain.NewID(),
             Title:       "Task 1",
             Description: "Description 1",
             Status:      "pending",
         },
         {
-            ID:          primitive.NewObjectID(),
+                ID:          domain.NewID(),
             Title:       "Task 2",
             Description: "Description 2",
             Status:      "completed",
         },
+        },
+        Total: 2,
     }
+    // the filter the repository receives once paging defaults are applied
+    defaults := domain.TaskFilter{Limit: usecases.DefaultPageSize, SortBy: domain.SortByID}
 
-    // Test 1  Successful retrieval
-    suite.Run("successful retrieval", func() {
+    // Test 1  Admin sees every task
+    suite.Run("admin retrieves all tasks", func() {
         suite.SetupTest()
         
-        suite.taskRepo.On("GetAllTasks").Return(mockTasks, nil).Once()
+        suite.taskRepo.On("GetAllTasks", defaults).Return(mockTasks, nil).Once()
 
-        tasks, err := suite.useCase.GetAllTasks()
+        tasks, err := suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{})
         
         suite.NoError(err)
         suite.Equal(mockTasks, tasks)
         suite.taskRepo.AssertExpectations(suite.T())
     })
 
-    // Test 2  Repository returns error
+    // Test 2  Users only see the tasks they own or are assigned to
+    suite.Run("user retrieves own tasks", func() {
+        suite.SetupTest()
+
+        expected := defaults
+        expected.VisibleTo = suite.user.UserID
+        suite.taskRepo.On("GetAllTasks", expected).Return(mockTasks, nil).Once()
+
+        tasks, err := suite.useCase.GetAllTasks(context.Background(), suite.user, domain.TaskFilter{})
+
+        suite.NoError(err)
+        suite.Equal(mockTasks, tasks)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 3  Assignee filter is kept next to the visibility restriction
+    suite.Run("user filters by assignee", func() {
+        suite.SetupTest()
+
+        assignee := domain.NewID()
+        expected := defaults
+        expected.AssigneeID = assignee
+        expected.VisibleTo = suite.user.UserID
+        suite.taskRepo.On("GetAllTasks", expected).Return(mockTasks, nil).Once()
+
+        _, err := suite.useCase.GetAllTasks(context.Background(), suite.user, domain.TaskFilter{AssigneeID: assignee})
+
+        suite.NoError(err)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 4  Repository returns error
     suite.Run("repository returns error", func() {
         suite.SetupTest()
         
         expectedErr := errors.New("database error")
-        suite.taskRepo.On("GetAllTasks").Return(nil, expectedErr).Once()
+        suite.taskRepo.On("GetAllTasks", defaults).Return(nil, expectedErr).Once()
 
-        tasks, err := suite.useCase.GetAllTasks()
+        tasks, err := suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{})
         
         suite.Error(err)
         suite.Nil(tasks)
         suite.EqualError(err, "failed to retrieve")
         suite.taskRepo.AssertExpectations(suite.T())
     })
+
+    // Test 5  Paging options are validated and capped
+    suite.Run("paging options", func() {
+        suite.SetupTest()
+
+        expected := domain.TaskFilter{Limit: usecases.MaxPageSize, SortBy: domain.SortByDueDate, SortDesc: true, Status: domain.StatusInProgress}
+        suite.taskRepo.On("GetAllTasks", expected).Return(mockTasks, nil).Once()
+
+        _, err := suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{Limit: 1000, SortBy: domain.SortByDueDate, SortDesc: true, Status: domain.StatusInProgress})
+        suite.NoError(err)
+
+        _, err = suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{SortBy: "password"})
+        suite.EqualError(err, "invalid sort field")
+
+        _, err = suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{Limit: -1})
+        suite.EqualError(err, "limit must be positive")
+
+        _, err = suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{Cursor: "%%%"})
+        suite.EqualError(err, "invalid cursor")
+
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
 }
 
 func (suite *TaskUsecaseTestSuite) TestGetTaskByID() {
     // Setup test data
-    taskID := primitive.NewObjectID().Hex()
+    taskID := domain.NewID()
     mockTask := &domain.Task{
-        ID:          primitive.NewObjectID(),
+        ID:          domain.NewID(),
         Title:       "Test Task",
         Description: "Test Description",
         Status:      "pending",
@@ -165,7 +330,7 @@ func (suite *TaskUsecaseTestSuite) TestGetTaskByID() {
         
         suite.taskRepo.On("GetTaskByID", taskID).Return(mockTask, nil).Once()
 
-        task, err := suite.useCase.GetTaskByID(taskID)
+        task, err := suite.useCase.GetTaskByID(context.Background(), suite.admin, taskID)
         
         suite.NoError(err)
         suite.Equal(mockTask, task)
@@ -176,48 +341,102 @@ func (suite *TaskUsecaseTestSuite) TestGetTaskByID() {
     suite.Run("task not found", func() {
         suite.SetupTest()
         
-        expectedErr := errors.New("not found")
+        expectedErr := domain.NotFound("task not found")
         suite.taskRepo.On("GetTaskByID", taskID).Return(nil, expectedErr).Once()
 
-        task, err := suite.useCase.GetTaskByID(taskID)
+        task, err := suite.useCase.GetTaskByID(context.Background(), suite.admin, taskID)
         
         suite.Error(err)
         suite.Nil(task)
         suite.EqualError(err, "task not found")
+        suite.ErrorIs(err, domain.ErrNotFound)
         suite.taskRepo.AssertExpectations(suite.T())
     })
 
+    // Test 3 Database failures are internal errors, not missing tasks
+    suite.Run("database error", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(nil, errors.New("connection reset")).Once()
+
+        task, err := suite.useCase.GetTaskByID(context.Background(), suite.admin, taskID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "failed to load task")
+        suite.ErrorIs(err, domain.ErrInternal)
+    })
+
     // Test 3  Invalid ID format
     suite.Run("invalid ID format", func() {
         suite.SetupTest()
 
-        
-        task, err := suite.useCase.GetTaskByID("invalid-id")
+        task, err := suite.useCase.GetTaskByID(context.Background(), suite.admin, "invalid-id")
         
         suite.Error(err)
         suite.Nil(task)
         suite.taskRepo.AssertNotCalled(suite.T(), "GetTaskByID")
     })
+
+    // Test 4  Assignees can see the task
+    suite.Run("task assigned to the user", func() {
+        suite.SetupTest()
+
+        assigned := &domain.Task{ID: domain.NewID(), OwnerID: domain.NewID(), Assignees: []string{suite.user.UserID}}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(assigned, nil).Once()
+
+        task, err := suite.useCase.GetTaskByID(context.Background(), suite.user, taskID)
+
+        suite.NoError(err)
+        suite.Equal(assigned, task)
+    })
+
+    // Test 5  Task owned by another user is hidden
+    suite.Run("task of another user", func() {
+        suite.SetupTest()
+
+        othersTask := &domain.Task{ID: domain.NewID(), OwnerID: domain.NewID()}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(othersTask, nil).Once()
+
+        task, err := suite.useCase.GetTaskByID(context.Background(), suite.user, taskID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "task not found")
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
 }
 
 func (suite *TaskUsecaseTestSuite) TestUpdateTaskByID() {
     // Setup test data
-    taskID := primitive.NewObjectID().Hex()
-    updatedTask := &domain.Task{
+    taskID := domain.NewID()
+    input := &domain.InputTask{
         Title:       "Updated Task",
         Description: "Updated Description",
+        DueDate:     "2020-01-31T17:00:00Z",
         Status:      "completed",
     }
 
-    // Test 1  Successful update
+    // Test 1  Successful update, past due dates are fine on existing tasks
     suite.Run("successful update", func() {
         suite.SetupTest()
         
-        suite.taskRepo.On("UpdateTaskByID", taskID, updatedTask).Return(nil).Once()
+        existing := &domain.Task{ID: domain.NewID(), OwnerID: suite.user.UserID, Assignees: []string{"a"}, Status: domain.StatusInProgress, Version: 4}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing, nil).Once()
+        //the status and the other fields are written together at the version the client read
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.AnythingOfType("*domain.Task"), []string{"title", "description", "dueDate", "status"}, int64(4)).Return(nil).Once()
 
-        err := suite.useCase.UpdateTaskByID(taskID, updatedTask)
+        task, err := suite.useCase.UpdateTaskByID(context.Background(), suite.user, taskID, input, 4)
         
         suite.NoError(err)
+        suite.Equal(input.Title, task.Title)
+        suite.Equal(input.Description, task.Description)
+        suite.Equal(input.Status, task.Status)
+        suite.Equal(time.Date(2020, 1, 31, 17, 0, 0, 0, time.UTC), *task.DueDate)
+        suite.Equal(suite.user.UserID, task.OwnerID)
+        suite.Equal([]string{"a"}, task.Assignees)
+        suite.Equal(int64(5), task.Version)
+        suite.Require().Len(task.StatusHistory, 1)
+        suite.Equal(domain.StatusTransition{From: domain.StatusInProgress, To: domain.StatusCompleted, ActorID: suite.user.UserID, At: task.StatusHistory[0].At}, task.StatusHistory[0])
+        suite.taskRepo.AssertNotCalled(suite.T(), "TransitionTaskStatus", mock.Anything, mock.Anything, mock.Anything)
         suite.taskRepo.AssertExpectations(suite.T())
     })
 
@@ -226,11 +445,12 @@ func (suite *TaskUsecaseTestSuite) TestUpdateTaskByID() {
         suite.SetupTest()
         
         expectedErr := errors.New("database error")
-        suite.taskRepo.On("UpdateTaskByID", taskID, updatedTask).Return(expectedErr).Once()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Status: domain.StatusCompleted}, nil).Once()
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.AnythingOfType("*domain.Task"), []string{"title", "description", "dueDate"}, int64(0)).Return(expectedErr).Once()
 
-        err := suite.useCase.UpdateTaskByID(taskID, updatedTask)
+        task, err := suite.useCase.UpdateTaskByID(context.Background(), suite.admin, taskID, input, 0)
         
-        suite.Error(err)
+        suite.Nil(task)
         suite.Equal(expectedErr, err) 
         suite.taskRepo.AssertExpectations(suite.T())
     })
@@ -239,24 +459,314 @@ func (suite *TaskUsecaseTestSuite) TestUpdateTaskByID() {
     suite.Run("invalid ID format", func() {
         suite.SetupTest()
 
-        err := suite.useCase.UpdateTaskByID("invalid-id", updatedTask)
+        _, err := suite.useCase.UpdateTaskByID(context.Background(), suite.admin, "invalid-id", input, 0)
         
         suite.Error(err)
-        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskByID")
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields")
+    })
+
+    // Test 4  Users cannot modify tasks of other users
+    suite.Run("task of another user", func() {
+        suite.SetupTest()
+
+        othersTask := &domain.Task{ID: domain.NewID(), OwnerID: domain.NewID()}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(othersTask, nil).Once()
+
+        _, err := suite.useCase.UpdateTaskByID(context.Background(), suite.user, taskID, input, 0)
+
+        suite.EqualError(err, "task not found")
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields")
+    })
+
+    // Test 5  Stale version is rejected without writing
+    suite.Run("stale version", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Status: domain.StatusCompleted, Version: 3}, nil).Once()
+
+        task, err := suite.useCase.UpdateTaskByID(context.Background(), suite.admin, taskID, input, 2)
+
+        suite.Nil(task)
+        suite.ErrorIs(err, domain.ErrVersionMismatch)
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+    })
+
+    // Test 6  Malformed due date
+    suite.Run("invalid due date", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{}, nil).Once()
+
+        _, err := suite.useCase.UpdateTaskByID(context.Background(), suite.admin, taskID, &domain.InputTask{Title: "x", DueDate: "31/01/2025"}, 0)
+
+        suite.EqualError(err, `invalid dueDate "31/01/2025": expected an RFC3339 date such as 2025-01-31T17:00:00Z`)
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields")
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestAddTaskDueDate() {
+    now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+    // Test 1  Due date is stored on the task
+    suite.Run("future due date", func() {
+        suite.SetupTest()
+        suite.useCase.Now = func() time.Time { return now }
+
+        suite.taskRepo.On("CreateTask", mock.AnythingOfType("*domain.Task")).Return(nil).Once()
+
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Report", DueDate: "2025-06-10T09:00:00+03:00"})
+
+        suite.NoError(err)
+        suite.Require().NotNil(task.DueDate)
+        suite.True(task.DueDate.Equal(time.Date(2025, 6, 10, 6, 0, 0, 0, time.UTC)))
+    })
+
+    // Test 2  Past due dates are rejected unless explicitly allowed
+    suite.Run("past due date", func() {
+        suite.SetupTest()
+        suite.useCase.Now = func() time.Time { return now }
+
+        _, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Report", DueDate: "2025-05-01T00:00:00Z"})
+        suite.EqualError(err, "dueDate is in the past, set allowPastDueDate to create the task anyway")
+        suite.taskRepo.AssertNotCalled(suite.T(), "CreateTask", mock.Anything)
+
+        suite.taskRepo.On("CreateTask", mock.AnythingOfType("*domain.Task")).Return(nil).Once()
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Report", DueDate: "2025-05-01T00:00:00Z", AllowPastDueDate: true})
+        suite.NoError(err)
+        suite.NotNil(task.DueDate)
+    })
+
+    // Test 3  Malformed due date
+    suite.Run("invalid due date", func() {
+        suite.SetupTest()
+
+        _, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Report", DueDate: "tomorrow"})
+        suite.EqualError(err, `invalid dueDate "tomorrow": expected an RFC3339 date such as 2025-01-31T17:00:00Z`)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetAllTasksDueDateModes() {
+    now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+    page := &domain.TaskPage{Tasks: []domain.Task{}}
+
+    // Test 1  Overdue tasks are past due and not completed
+    suite.Run("overdue", func() {
+        suite.SetupTest()
+        suite.useCase.Now = func() time.Time { return now }
+
+        expected := domain.TaskFilter{Overdue: true, DueBefore: &now, ExcludeStatus: domain.StatusCompleted, Limit: usecases.DefaultPageSize, SortBy: domain.SortByID}
+        suite.taskRepo.On("GetAllTasks", expected).Return(page, nil).Once()
+
+        _, err := suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{Overdue: true})
+
+        suite.NoError(err)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  Tasks due within the next N days
+    suite.Run("due within", func() {
+        suite.SetupTest()
+        suite.useCase.Now = func() time.Time { return now }
+
+        until := now.AddDate(0, 0, 7)
+        expected := domain.TaskFilter{DueWithinDays: 7, DueAfter: &now, DueBefore: &until, Limit: usecases.DefaultPageSize, SortBy: domain.SortByID}
+        suite.taskRepo.On("GetAllTasks", expected).Return(page, nil).Once()
+
+        _, err := suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{DueWithinDays: 7})
+
+        suite.NoError(err)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 3  Conflicting modes
+    suite.Run("conflicting modes", func() {
+        suite.SetupTest()
+
+        _, err := suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{Overdue: true, DueWithinDays: 3})
+        suite.EqualError(err, "overdue cannot be combined with dueWithin")
+
+        _, err = suite.useCase.GetAllTasks(context.Background(), suite.admin, domain.TaskFilter{Overdue: true, DueAfter: &now})
+        suite.EqualError(err, "overdue and dueWithin cannot be combined with dueAfter or dueBefore")
+        suite.taskRepo.AssertNotCalled(suite.T(), "GetAllTasks", mock.Anything)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestPatchTask() {
+    taskID := domain.NewID()
+    title := "Patched title"
+    empty := ""
+    status := domain.StatusInProgress
+    existing := func() *domain.Task {
+        due := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+        return &domain.Task{
+            ID:          domain.NewID(),
+            Title:       "Title",
+            Description: "Keep me",
+            DueDate:     &due,
+            Status:      domain.StatusNotStarted,
+            OwnerID:     suite.user.UserID,
+            Version:     2,
+        }
+    }
+
+    // Test 1  Only the provided fields are written
+    suite.Run("partial update", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing(), nil).Once()
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.AnythingOfType("*domain.Task"), []string{"title", "dueDate"}, int64(2)).Return(nil).Once()
+
+        task, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{Title: &title, DueDate: &empty}, 2)
+
+        suite.NoError(err)
+        suite.Equal(title, task.Title)
+        suite.Equal("Keep me", task.Description)
+        suite.Nil(task.DueDate)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  A status-only patch goes through the transition graph and leaves the other fields alone
+    suite.Run("status only", func() {
+        suite.SetupTest()
+
+        task := existing()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(task, nil).Once()
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.AnythingOfType("*domain.Task"), []string{"status"}, int64(2)).Return(nil).Once()
+
+        patched, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{Status: &status}, 2)
+
+        suite.NoError(err)
+        suite.Equal(domain.StatusInProgress, patched.Status)
+        suite.Equal("Keep me", patched.Description)
+        suite.Equal(int64(3), patched.Version)
+        suite.Len(patched.StatusHistory, 1)
+        suite.taskRepo.AssertNotCalled(suite.T(), "TransitionTaskStatus", mock.Anything, mock.Anything, mock.Anything)
+    })
+
+    // Test 3  A status and field patch is one write and one audit record
+    suite.Run("status and fields", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing(), nil).Once()
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.AnythingOfType("*domain.Task"), []string{"title", "status"}, int64(2)).Return(nil).Once()
+
+        patched, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{Title: &title, Status: &status}, 2)
+
+        suite.NoError(err)
+        suite.Equal(title, patched.Title)
+        suite.Equal(domain.StatusInProgress, patched.Status)
+        suite.Equal(int64(3), patched.Version)
+        suite.taskRepo.AssertNumberOfCalls(suite.T(), "UpdateTaskFields", 1)
+        suite.auditRepo.AssertNumberOfCalls(suite.T(), "AddRecord", 1)
+    })
+
+    // Test 4  Invalid values are rejected before anything is written
+    suite.Run("invalid field", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing(), nil).Twice()
+
+        _, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{Title: &empty, Status: &status}, 2)
+        suite.EqualError(err, "title cannot be empty")
+
+        bad := "next friday"
+        _, err = suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{DueDate: &bad}, 2)
+        suite.EqualError(err, `invalid dueDate "next friday": expected an RFC3339 date such as 2025-01-31T17:00:00Z`)
+
+        suite.taskRepo.AssertNotCalled(suite.T(), "TransitionTaskStatus", mock.Anything, mock.Anything, mock.Anything)
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+    })
+
+    // Test 5  An empty patch is neither written nor audited
+    suite.Run("empty patch", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing(), nil).Once()
+
+        task, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{}, 2)
+
+        suite.NoError(err)
+        suite.Equal("Title", task.Title)
+        suite.Equal(int64(2), task.Version)
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+        suite.auditRepo.AssertNotCalled(suite.T(), "AddRecord", mock.Anything)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestTransitionTask() {
+    taskID := domain.NewID()
+    now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+    // Test 1  Allowed transition is stored with the actor and time
+    suite.Run("allowed transition", func() {
+        suite.SetupTest()
+        suite.useCase.Now = func() time.Time { return now }
+
+        existing := &domain.Task{ID: domain.NewID(), OwnerID: suite.user.UserID, Status: domain.StatusNotStarted}
+        expected := domain.StatusTransition{From: domain.StatusNotStarted, To: domain.StatusInProgress, ActorID: suite.user.UserID, At: now}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing, nil).Once()
+        suite.taskRepo.On("TransitionTaskStatus", existing.ID, expected, int64(0)).Return(nil).Once()
+
+        task, err := suite.useCase.TransitionTask(context.Background(), suite.user, taskID, domain.StatusInProgress)
+
+        suite.NoError(err)
+        suite.Equal(domain.StatusInProgress, task.Status)
+        suite.Equal([]domain.StatusTransition{expected}, task.StatusHistory)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  Transitions missing from the graph are rejected
+    suite.Run("forbidden transition", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Status: domain.StatusCompleted}, nil).Once()
+
+        task, err := suite.useCase.TransitionTask(context.Background(), suite.admin, taskID, domain.StatusNotStarted)
+
+        suite.Nil(task)
+        suite.EqualError(err, `cannot move task from "completed" to "not-started"`)
+        suite.taskRepo.AssertNotCalled(suite.T(), "TransitionTaskStatus", mock.Anything, mock.Anything, mock.Anything)
+    })
+
+    // Test 3  Unknown status
+    suite.Run("invalid status", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Status: domain.StatusNotStarted}, nil).Once()
+
+        _, err := suite.useCase.TransitionTask(context.Background(), suite.admin, taskID, "banana")
+
+        suite.EqualError(err, `invalid status "banana": must be one of not-started, in-progress, completed`)
+    })
+
+    // Test 4  A custom graph replaces the default one
+    suite.Run("custom graph", func() {
+        suite.SetupTest()
+        suite.useCase.Transitions = domain.TransitionGraph{domain.StatusCompleted: {domain.StatusNotStarted}}
+
+        existing := &domain.Task{ID: domain.NewID(), Status: domain.StatusCompleted}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing, nil).Once()
+        suite.taskRepo.On("TransitionTaskStatus", existing.ID, mock.AnythingOfType("domain.StatusTransition"), int64(0)).Return(nil).Once()
+
+        task, err := suite.useCase.TransitionTask(context.Background(), suite.admin, taskID, domain.StatusNotStarted)
+
+        suite.NoError(err)
+        suite.Equal(domain.StatusNotStarted, task.Status)
     })
 }
 
 func (suite *TaskUsecaseTestSuite) TestDeleteTaskByID() {
     // Setup test data
-    taskID := primitive.NewObjectID().Hex()
+    taskID := domain.NewID()
 
     // Test 1  Successful deletion
     suite.Run("successful deletion", func() {
         suite.SetupTest()
         
-        suite.taskRepo.On("DeleteTaskByID", taskID).Return(nil).Once()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: suite.user.UserID}, nil).Once()
+        suite.taskRepo.On("TrashTaskByID", taskID, suite.user.UserID, mock.AnythingOfType("time.Time"), int64(0)).Return(nil).Once()
 
-        err := suite.useCase.DeleteTaskByID(taskID)
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.user, taskID, 0)
         
         suite.NoError(err)
         suite.taskRepo.AssertExpectations(suite.T())
@@ -267,9 +777,10 @@ func (suite *TaskUsecaseTestSuite) TestDeleteTaskByID() {
         suite.SetupTest()
         
         expectedErr := errors.New("database error")
-        suite.taskRepo.On("DeleteTaskByID", taskID).Return(expectedErr).Once()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{}, nil).Once()
+        suite.taskRepo.On("TrashTaskByID", taskID, suite.admin.UserID, mock.AnythingOfType("time.Time"), int64(0)).Return(expectedErr).Once()
 
-        err := suite.useCase.DeleteTaskByID(taskID)
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.admin, taskID, 0)
         
         suite.Error(err)
         suite.Equal(expectedErr, err) 
@@ -280,9 +791,509 @@ func (suite *TaskUsecaseTestSuite) TestDeleteTaskByID() {
     suite.Run("invalid ID format", func() {
         suite.SetupTest()
 
-        err := suite.useCase.DeleteTaskByID("invalid-id")
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.admin, "invalid-id", 0)
         
         suite.Error(err)
-        suite.taskRepo.AssertNotCalled(suite.T(), "DeleteTaskByID")
+        suite.taskRepo.AssertNotCalled(suite.T(), "TrashTaskByID")
     })
-}
\ No newline at end of file
+
+    // Test 4  Users cannot delete tasks of other users
+    suite.Run("task of another user", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: "someone-else"}, nil).Once()
+
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.user, taskID, 0)
+
+        suite.EqualError(err, "task not found")
+        suite.taskRepo.AssertNotCalled(suite.T(), "TrashTaskByID")
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestAddAssignee() {
+    taskID := domain.NewID()
+    assigneeID := domain.NewID()
+
+    // Test 1  Owner assigns an existing user
+    suite.Run("successful assignment", func() {
+        suite.SetupTest()
+
+        owned := &domain.Task{OwnerID: suite.user.UserID}
+        updated := &domain.Task{OwnerID: suite.user.UserID, Assignees: []string{assigneeID}}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(owned, nil).Once()
+        suite.userRepo.On("FindByID", assigneeID).Return(&domain.User{Username: "abebe"}, nil).Once()
+        suite.taskRepo.On("AddAssignee", taskID, assigneeID).Return(nil).Once()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(updated, nil).Once()
+
+        task, err := suite.useCase.AddAssignee(context.Background(), suite.user, taskID, assigneeID)
+
+        suite.NoError(err)
+        suite.Equal(updated, task)
+        suite.taskRepo.AssertExpectations(suite.T())
+        suite.userRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  Unknown users cannot be assigned
+    suite.Run("user not found", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: suite.user.UserID}, nil).Once()
+        suite.userRepo.On("FindByID", assigneeID).Return(nil, domain.NotFound("user not found")).Once()
+
+        task, err := suite.useCase.AddAssignee(context.Background(), suite.user, taskID, assigneeID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "user not found")
+        suite.taskRepo.AssertNotCalled(suite.T(), "AddAssignee", taskID, assigneeID)
+    })
+
+    // Test 3  Assignees cannot manage the assignees of a task they do not own
+    suite.Run("assignee is not the owner", func() {
+        suite.SetupTest()
+
+        assigned := &domain.Task{OwnerID: domain.NewID(), Assignees: []string{suite.user.UserID}}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(assigned, nil).Once()
+
+        task, err := suite.useCase.AddAssignee(context.Background(), suite.user, taskID, assigneeID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "only the task owner can do this")
+        suite.userRepo.AssertNotCalled(suite.T(), "FindByID", assigneeID)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestRemoveAssignee() {
+    taskID := domain.NewID()
+    assigneeID := domain.NewID()
+
+    // Test 1  Admin removes an assignee
+    suite.Run("successful removal", func() {
+        suite.SetupTest()
+
+        assigned := &domain.Task{Assignees: []string{assigneeID}}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(assigned, nil).Once()
+        suite.taskRepo.On("RemoveAssignee", taskID, assigneeID).Return(nil).Once()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Assignees: []string{}}, nil).Once()
+
+        task, err := suite.useCase.RemoveAssignee(context.Background(), suite.admin, taskID, assigneeID)
+
+        suite.NoError(err)
+        suite.Empty(task.Assignees)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  Removing a user that is not assigned
+    suite.Run("user not assigned", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{}, nil).Once()
+
+        task, err := suite.useCase.RemoveAssignee(context.Background(), suite.admin, taskID, assigneeID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "user is not assigned to this task")
+        suite.taskRepo.AssertNotCalled(suite.T(), "RemoveAssignee", taskID, assigneeID)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestDeleteTaskByIDStaleVersion() {
+    taskID := domain.NewID()
+
+    // Test 1  Delete with an outdated version is rejected
+    suite.Run("stale version", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Version: 7}, nil).Once()
+
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.admin, taskID, 6)
+
+        suite.ErrorIs(err, domain.ErrVersionMismatch)
+        suite.taskRepo.AssertNotCalled(suite.T(), "TrashTaskByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+    })
+
+    // Test 2  Concurrent write detected by the repository
+    suite.Run("concurrent write", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Version: 7}, nil).Once()
+        suite.taskRepo.On("TrashTaskByID", taskID, suite.admin.UserID, mock.AnythingOfType("time.Time"), int64(7)).Return(domain.ErrVersionMismatch).Once()
+
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.admin, taskID, 7)
+
+        suite.ErrorIs(err, domain.ErrVersionMismatch)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestDeleteTaskByIDHidesTrashedTasks() {
+    taskID := domain.NewID()
+
+    // Test 1  Trashed tasks cannot be deleted twice
+    suite.Run("already trashed", func() {
+        suite.SetupTest()
+
+        deletedAt := time.Now()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: suite.user.UserID, DeletedAt: &deletedAt}, nil).Once()
+
+        err := suite.useCase.DeleteTaskByID(context.Background(), suite.user, taskID, 0)
+
+        suite.EqualError(err, "task not found")
+        suite.taskRepo.AssertNotCalled(suite.T(), "TrashTaskByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetTrash() {
+    // Test 1  Users only see their own trash
+    suite.Run("visible to the user", func() {
+        suite.SetupTest()
+
+        page := &domain.TaskPage{Tasks: []domain.Task{{Title: "old"}}, Total: 1}
+        expected := domain.TaskFilter{Trashed: true, VisibleTo: suite.user.UserID, Limit: usecases.DefaultPageSize, SortBy: domain.SortByID}
+        suite.taskRepo.On("GetAllTasks", expected).Return(page, nil).Once()
+
+        result, err := suite.useCase.GetTrash(context.Background(), suite.user, domain.TaskFilter{})
+
+        suite.NoError(err)
+        suite.Equal(page, result)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestRestoreTask() {
+    taskID := domain.NewID()
+    deletedAt := time.Now()
+
+    // Test 1  Owner restores a trashed task
+    suite.Run("successful restore", func() {
+        suite.SetupTest()
+
+        trashed := &domain.Task{OwnerID: suite.user.UserID, DeletedAt: &deletedAt, DeletedBy: suite.user.UserID, Version: 3}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(trashed, nil).Once()
+        suite.taskRepo.On("RestoreTaskByID", taskID, int64(3)).Return(nil).Once()
+
+        task, err := suite.useCase.RestoreTask(context.Background(), suite.user, taskID)
+
+        suite.NoError(err)
+        suite.False(task.IsTrashed())
+        suite.Empty(task.DeletedBy)
+        suite.Equal(int64(4), task.Version)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  Tasks that are not trashed cannot be restored
+    suite.Run("task not in the trash", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: suite.user.UserID}, nil).Once()
+
+        task, err := suite.useCase.RestoreTask(context.Background(), suite.user, taskID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "task is not in the trash")
+        suite.taskRepo.AssertNotCalled(suite.T(), "RestoreTaskByID", mock.Anything, mock.Anything)
+    })
+
+    // Test 3  Users cannot restore the tasks of other users
+    suite.Run("task of another user", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: "someone-else", DeletedAt: &deletedAt}, nil).Once()
+
+        task, err := suite.useCase.RestoreTask(context.Background(), suite.user, taskID)
+
+        suite.Nil(task)
+        suite.EqualError(err, "task not found")
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestPurgeTask() {
+    taskID := domain.NewID()
+    deletedAt := time.Now()
+
+    // Test 1  Admin purges a trashed task
+    suite.Run("successful purge", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{DeletedAt: &deletedAt, Version: 2}, nil).Once()
+        suite.taskRepo.On("DeleteTaskByID", taskID, int64(2)).Return(nil).Once()
+
+        err := suite.useCase.PurgeTask(context.Background(), suite.admin, taskID)
+
+        suite.NoError(err)
+        suite.taskRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  Only admins can purge
+    suite.Run("not an admin", func() {
+        suite.SetupTest()
+
+        err := suite.useCase.PurgeTask(context.Background(), suite.user, taskID)
+
+        suite.EqualError(err, "only admins can purge tasks")
+        suite.taskRepo.AssertNotCalled(suite.T(), "GetTaskByID", taskID)
+    })
+
+    // Test 3  Live tasks must be trashed first
+    suite.Run("task not in the trash", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{}, nil).Once()
+
+        err := suite.useCase.PurgeTask(context.Background(), suite.admin, taskID)
+
+        suite.EqualError(err, "task is not in the trash")
+        suite.taskRepo.AssertNotCalled(suite.T(), "DeleteTaskByID", mock.Anything, mock.Anything)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestPurgeExpiredTrash() {
+    // Test 1  Tasks older than the retention window are purged
+    suite.Run("purges before the cutoff", func() {
+        suite.SetupTest()
+
+        now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+        suite.useCase.Now = func() time.Time { return now }
+        suite.useCase.TrashRetention = 7 * 24 * time.Hour
+        tasks := []domain.Task{{ID: domain.NewID(), Title: "Old"}, {ID: domain.NewID(), Title: "Older"}}
+        suite.taskRepo.On("PurgeTrashedBefore", now.AddDate(0, 0, -7)).Return(tasks, nil).Once()
+
+        purged, err := suite.useCase.PurgeExpiredTrash(context.Background())
+
+        suite.NoError(err)
+        suite.Equal(int64(2), purged)
+        suite.taskRepo.AssertExpectations(suite.T())
+        //every purged task gets a record by the system actor
+        for _, task := range tasks {
+            suite.auditRepo.AssertCalled(suite.T(), "AddRecord", mock.MatchedBy(func(record *domain.AuditRecord) bool {
+                return record.TaskID == task.ID && record.Action == domain.AuditPurge && record.ActorID == domain.SystemActor.UserID
+            }))
+        }
+    })
+
+    // Test 2  The tasks removed before a failure are still audited
+    suite.Run("partial failure", func() {
+        suite.SetupTest()
+
+        expectedErr := errors.New("connection reset")
+        tasks := []domain.Task{{ID: domain.NewID(), Title: "Old"}}
+        suite.taskRepo.On("PurgeTrashedBefore", mock.Anything).Return(tasks, expectedErr).Once()
+
+        purged, err := suite.useCase.PurgeExpiredTrash(context.Background())
+
+        suite.Equal(expectedErr, err)
+        suite.Equal(int64(1), purged)
+        suite.auditRepo.AssertNumberOfCalls(suite.T(), "AddRecord", 1)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestAuditTrail() {
+    taskID := domain.NewID()
+
+    // Test 1  Creating a task records every set field
+    suite.Run("create is audited", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("CreateTask", mock.Anything).Return(nil).Once()
+
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Write report"})
+
+        suite.NoError(err)
+        record := suite.auditRepo.lastRecord()
+        suite.Require().NotNil(record)
+        suite.Equal(domain.AuditCreate, record.Action)
+        suite.Equal(task.ID, record.TaskID)
+        suite.Equal(suite.user.UserID, record.ActorID)
+        suite.Contains(record.Changes, domain.FieldChange{Field: "title", To: "Write report"})
+        suite.Contains(record.Changes, domain.FieldChange{Field: "status", To: string(domain.StatusNotStarted)})
+    })
+
+    // Test 2  Updates only record the fields that changed
+    suite.Run("update records a field diff", func() {
+        suite.SetupTest()
+
+        existing := &domain.Task{Title: "Old", Description: "same", Status: domain.StatusNotStarted, OwnerID: suite.user.UserID, Version: 2}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing, nil).Once()
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.Anything, []string{"title"}, int64(2)).Return(nil).Once()
+
+        title := "New"
+        _, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{Title: &title}, 2)
+
+        suite.NoError(err)
+        record := suite.auditRepo.lastRecord()
+        suite.Require().NotNil(record)
+        suite.Equal(domain.AuditUpdate, record.Action)
+        suite.Equal([]domain.FieldChange{{Field: "title", From: "Old", To: "New"}}, record.Changes)
+    })
+
+    // Test 3  Failing to write the audit record is reported
+    suite.Run("audit failure", func() {
+        suite.taskRepo = new(MockTaskRepository)
+        suite.auditRepo = new(MockAuditRepository)
+        suite.useCase = usecases.NewTaskUseCase(suite.taskRepo, suite.userRepo, suite.auditRepo)
+
+        suite.taskRepo.On("CreateTask", mock.Anything).Return(nil).Once()
+        suite.auditRepo.On("AddRecord", mock.Anything).Return(errors.New("disk full")).Once()
+
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: "Write report"})
+
+        suite.Nil(task)
+        suite.EqualError(err, "failed to record the change in the audit trail")
+    })
+
+    // Test 4  Purging records what was lost
+    suite.Run("purge is audited", func() {
+        suite.SetupTest()
+
+        deletedAt := time.Now()
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{Title: "Gone", DeletedAt: &deletedAt}, nil).Once()
+        suite.taskRepo.On("DeleteTaskByID", taskID, int64(0)).Return(nil).Once()
+
+        err := suite.useCase.PurgeTask(context.Background(), suite.admin, taskID)
+
+        suite.NoError(err)
+        record := suite.auditRepo.lastRecord()
+        suite.Require().NotNil(record)
+        suite.Equal(domain.AuditPurge, record.Action)
+        suite.Contains(record.Changes, domain.FieldChange{Field: "title", From: "Gone"})
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetTaskHistory() {
+    taskID := domain.NewID()
+    page := &domain.AuditPage{Records: []domain.AuditRecord{{TaskID: taskID, Action: domain.AuditCreate}}, Total: 1}
+
+    // Test 1  Owners read the history of their task
+    suite.Run("owner", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: suite.user.UserID}, nil).Once()
+        suite.auditRepo.On("GetRecords", domain.AuditFilter{TaskID: taskID, Limit: usecases.DefaultPageSize}).Return(page, nil).Once()
+
+        result, err := suite.useCase.GetTaskHistory(context.Background(), suite.user, taskID, domain.AuditFilter{})
+
+        suite.NoError(err)
+        suite.Equal(page, result)
+        suite.auditRepo.AssertExpectations(suite.T())
+    })
+
+    // Test 2  The history of other tasks is hidden
+    suite.Run("task of another user", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: "someone-else"}, nil).Once()
+
+        result, err := suite.useCase.GetTaskHistory(context.Background(), suite.user, taskID, domain.AuditFilter{})
+
+        suite.Nil(result)
+        suite.EqualError(err, "task not found")
+        suite.auditRepo.AssertNotCalled(suite.T(), "GetRecords", mock.Anything)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestGetAuditTrail() {
+    actorID := domain.NewID()
+
+    // Test 1  Admins search the audit trail by actor and time
+    suite.Run("admin", func() {
+        suite.SetupTest()
+
+        from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+        filter := domain.AuditFilter{ActorID: actorID, From: &from, Limit: 5}
+        page := &domain.AuditPage{Records: []domain.AuditRecord{}}
+        suite.auditRepo.On("GetRecords", filter).Return(page, nil).Once()
+
+        result, err := suite.useCase.GetAuditTrail(context.Background(), suite.admin, filter)
+
+        suite.NoError(err)
+        suite.Equal(page, result)
+    })
+
+    // Test 2  Users cannot read the audit trail
+    suite.Run("not an admin", func() {
+        suite.SetupTest()
+
+        result, err := suite.useCase.GetAuditTrail(context.Background(), suite.user, domain.AuditFilter{})
+
+        suite.Nil(result)
+        suite.EqualError(err, "only admins can read the audit trail")
+    })
+
+    // Test 3  Time range must be in order
+    suite.Run("invalid time range", func() {
+        suite.SetupTest()
+
+        from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+        to := from.AddDate(0, 0, -1)
+
+        result, err := suite.useCase.GetAuditTrail(context.Background(), suite.admin, domain.AuditFilter{From: &from, To: &to})
+
+        suite.Nil(result)
+        suite.EqualError(err, "from must be before to")
+        suite.auditRepo.AssertNotCalled(suite.T(), "GetRecords", mock.Anything)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestTaskValidation() {
+    taskID := domain.NewID()
+
+    // Test 1  Oversized fields are rejected before anything is stored
+    suite.Run("length limits", func() {
+        suite.SetupTest()
+
+        input := &domain.InputTask{Title: strings.Repeat("t", 201), Description: strings.Repeat("d", 5001)}
+
+        task, err := suite.useCase.AddTask(context.Background(), suite.user, input)
+
+        suite.Nil(task)
+        suite.EqualError(err, "invalid input: title must be at most 200 characters; description must be at most 5000 characters")
+        suite.taskRepo.AssertNotCalled(suite.T(), "CreateTask", mock.Anything)
+    })
+
+    // Test 2  Lengths count characters, not bytes
+    suite.Run("multibyte title", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("CreateTask", mock.Anything).Return(nil).Once()
+
+        _, err := suite.useCase.AddTask(context.Background(), suite.user, &domain.InputTask{Title: strings.Repeat("ሰ", 200)})
+
+        suite.NoError(err)
+    })
+
+    // Test 3  Updates cannot empty the title
+    suite.Run("update with empty title", func() {
+        suite.SetupTest()
+
+        suite.taskRepo.On("GetTaskByID", taskID).Return(&domain.Task{OwnerID: suite.user.UserID}, nil).Once()
+
+        _, err := suite.useCase.UpdateTaskByID(context.Background(), suite.user, taskID, &domain.InputTask{}, 0)
+
+        suite.EqualError(err, "title cannot be empty")
+        suite.taskRepo.AssertNotCalled(suite.T(), "UpdateTaskFields", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+    })
+
+    // Test 4  Patches only validate the fields they change
+    suite.Run("patch description only", func() {
+        suite.SetupTest()
+
+        existing := &domain.Task{Title: "Keep", OwnerID: suite.user.UserID}
+        suite.taskRepo.On("GetTaskByID", taskID).Return(existing, nil).Once()
+        suite.taskRepo.On("UpdateTaskFields", taskID, mock.Anything, []string{"description"}, int64(0)).Return(nil).Once()
+
+        description := "new description"
+        _, err := suite.useCase.PatchTask(context.Background(), suite.user, taskID, &domain.TaskPatch{Description: &description}, 0)
+
+        suite.NoError(err)
+    })
+}
+
+func (suite *TaskUsecaseTestSuite) TestValidationRules() {
+    rules := usecases.ValidationRules()
+
+    suite.Equal(usecases.TaskRules, rules["task"])
+    suite.Equal(usecases.UserRules, rules["user"])
+    suite.Equal(usecases.PersonalTokenRules, rules["personalToken"])
+
+    //the patterns are compiled once when the tables are built, a bad one stops the startup
+    suite.Equal(usecases.UserRules[0].Pattern, usecases.UserRules[0].Regexp.String())
+    suite.Panics(func() { domain.CompileRules([]domain.FieldRule{{Field: "code", Pattern: "[a-z"}}) })
+}
@@ -1,15 +1,16 @@
 package usecases_test
 
 import (
+	"context"
 	"errors"
+	"strings"
 	domain "task_management/Domain"
 	"task_management/usecases"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	// "go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -19,36 +20,76 @@ type MockUserRepostitoy struct {
 }
 
 // mocks the repo method createuser
-func (m *MockUserRepostitoy) CreateUser(user *domain.User) error {
+func (m *MockUserRepostitoy) CreateUser(ctx context.Context, user *domain.User) error {
 	args := m.Called(user) //record calls
 	return args.Error(0)   //return configured error
 }
 
 // mocks findbyusername method
-func (m *MockUserRepostitoy) FindByUsername(username string) (*domain.User, error) {
+func (m *MockUserRepostitoy) FindByUsername(ctx context.Context, username string) (*domain.User, error) {
     args := m.Called(username)
     if args.Get(0) == nil {
         return nil, args.Error(1)
     }
     return args.Get(0).(*domain.User), args.Error(1)
 }
+// mocks findbyid method
+func (m *MockUserRepostitoy) FindByID(ctx context.Context, userID string) (*domain.User, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
 //mocks countbyusername method
 
-func (m *MockUserRepostitoy) CountByUsername(username string) (int64, error) {
+func (m *MockUserRepostitoy) CountByUsername(ctx context.Context, username string) (int64, error) {
 	args := m.Called(username)
 	return args.Get(0).(int64), args.Error(1)
 }
 
 //mocks countall method
 
-func (m *MockUserRepostitoy) CountAll() (int64, error) {
+func (m *MockUserRepostitoy) CountAll(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
 
-//mocks promoteuser method
+//mocks countbyrole method
+
+func (m *MockUserRepostitoy) CountByRole(ctx context.Context, role domain.Role) (int64, error) {
+	args := m.Called(role)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+//mocks updaterole method
+
+func (m *MockUserRepostitoy) UpdateRole(ctx context.Context, userID string, role domain.Role) error {
+	args := m.Called(userID, role)
+	return args.Error(0)
+}
+
+//mocks listusers method
 
-func (m *MockUserRepostitoy) PromoteUser(userID string) error {
+func (m *MockUserRepostitoy) ListUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserPage, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.UserPage), args.Error(1)
+}
+
+//mocks updatedeactivatedat method
+
+func (m *MockUserRepostitoy) UpdateDeactivatedAt(ctx context.Context, userID string, at *time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+//mocks deleteuser method
+
+func (m *MockUserRepostitoy) DeleteUser(ctx context.Context, userID string) error {
 	args := m.Called(userID)
 	return args.Error(0)
 }
@@ -76,11 +117,198 @@ type MockJWTService struct {
 	mock.Mock
 }
 
-func (m *MockJWTService) GenerateToken(userID string, role domain.Role) (string, error) {
-	args := m.Called(userID, role)
+func (m *MockJWTService) GenerateToken(userID string, role domain.Role, sessionID string) (string, error) {
+	args := m.Called(userID, role, sessionID)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockJWTService) ParseToken(token string) (*domain.AccessClaims, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.AccessClaims), args.Error(1)
+}
+
+func (m *MockJWTService) GenerateRefreshToken() (string, string, error) {
+	args := m.Called()
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+// the hash is derived from the token so tests can find a stored token again
+func (m *MockJWTService) HashRefreshToken(token string) string {
+	return "hash-" + token
+}
+
+func (m *MockJWTService) GeneratePersonalToken() (string, string, error) {
+	args := m.Called()
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockJWTService) AccessTTL() time.Duration {
+	return 15 * time.Minute
+}
+
+func (m *MockJWTService) RefreshTTL() time.Duration {
+	return 7 * 24 * time.Hour
+}
+
+//mock refresh token repository
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) CreateToken(ctx context.Context, token *domain.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) MarkUsed(ctx context.Context, tokenID string, at time.Time) error {
+	args := m.Called(tokenID, at)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	args := m.Called(familyID, at)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+//mock session repository
+
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+// fills the session ID in like the repositories do
+func (m *MockSessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	session.ID = domain.NewID()
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) FindSession(ctx context.Context, sessionID string) (*domain.Session, error) {
+	args := m.Called(sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) TouchSession(ctx context.Context, sessionID string, seenAt time.Time) error {
+	args := m.Called(sessionID, seenAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) ExtendSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	args := m.Called(sessionID, seenAt, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	args := m.Called(sessionID, at)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+//mock personal token repository
+
+type MockPersonalTokenRepository struct {
+	mock.Mock
+}
+
+// fills the token ID in like the repositories do
+func (m *MockPersonalTokenRepository) CreatePersonalToken(ctx context.Context, token *domain.PersonalToken) error {
+	token.ID = domain.NewID()
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockPersonalTokenRepository) FindPersonalToken(ctx context.Context, tokenID string) (*domain.PersonalToken, error) {
+	args := m.Called(tokenID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PersonalToken), args.Error(1)
+}
+
+func (m *MockPersonalTokenRepository) FindPersonalTokenByHash(ctx context.Context, hash string) (*domain.PersonalToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.PersonalToken), args.Error(1)
+}
+
+func (m *MockPersonalTokenRepository) ListPersonalTokens(ctx context.Context, userID string) ([]domain.PersonalToken, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.PersonalToken), args.Error(1)
+}
+
+func (m *MockPersonalTokenRepository) TouchPersonalToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	args := m.Called(tokenID, usedAt)
+	return args.Error(0)
+}
+
+func (m *MockPersonalTokenRepository) RevokePersonalToken(ctx context.Context, tokenID string, at time.Time) error {
+	args := m.Called(tokenID, at)
+	return args.Error(0)
+}
+
+func (m *MockPersonalTokenRepository) RevokeUserPersonalTokens(ctx context.Context, userID string, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+//mock token revocation repository
+
+type MockTokenRevocationRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRevocationRepository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	args := m.Called(tokenID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationRepository) RevokeUserTokens(ctx context.Context, userID string, at, expiresAt time.Time) error {
+	args := m.Called(userID, at, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevocationRepository) IsRevoked(ctx context.Context, tokenID, userID string, issuedAt time.Time) (bool, error) {
+	args := m.Called(tokenID, userID, issuedAt)
+	return args.Bool(0), args.Error(1)
+}
+
 //Test suite
 
 type UserUseCaseTestSuite struct {
@@ -88,7 +316,14 @@ type UserUseCaseTestSuite struct {
 	userRepo        *MockUserRepostitoy
 	passwordService *MockPasswordService
 	jwtService      *MockJWTService
+	auditRepo       *MockAuditRepository
+	taskRepo        *MockTaskRepository
+	refreshTokens   *MockRefreshTokenRepository
+	revocations     *MockTokenRevocationRepository
+	sessions        *MockSessionRepository
+	personalTokens  *MockPersonalTokenRepository
 	useCase         *usecases.UserUseCase
+	now             time.Time
 }
 
 // setting up the test
@@ -96,11 +331,25 @@ func (suite *UserUseCaseTestSuite) SetupTest() {
 	suite.userRepo = new(MockUserRepostitoy)
 	suite.passwordService = new(MockPasswordService)
 	suite.jwtService = new(MockJWTService)
+	suite.auditRepo = new(MockAuditRepository)
+	suite.taskRepo = new(MockTaskRepository)
+	suite.refreshTokens = new(MockRefreshTokenRepository)
+	suite.revocations = new(MockTokenRevocationRepository)
+	suite.sessions = new(MockSessionRepository)
+	suite.personalTokens = new(MockPersonalTokenRepository)
 	suite.useCase = usecases.NewUserUseCase(
 		suite.userRepo,
 		suite.passwordService,
 		suite.jwtService,
+		suite.auditRepo,
+		suite.taskRepo,
+		suite.refreshTokens,
+		suite.revocations,
+		suite.sessions,
+		suite.personalTokens,
 	)
+	suite.now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	suite.useCase.Now = func() time.Time { return suite.now }
 }
 
 func TestUserUseCaseSuite(t *testing.T) {
@@ -133,7 +382,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
 			suite.Equal(domain.RoleAdmin, userArg.Role)
 		}).Once()
 		//call the register method
-		user, err := suite.useCase.Register(input)
+		user, err := suite.useCase.Register(context.Background(), input)
 
 		//assertions
 		suite.NoError(err)
@@ -159,7 +408,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
         suite.Equal(domain.RoleUser, userArg.Role)
     }).Once()
 
-    user, err := suite.useCase.Register(input)
+    user, err := suite.useCase.Register(context.Background(), input)
 
     suite.NoError(err)
     suite.NotNil(user)
@@ -179,7 +428,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
 		suite.userRepo.On("CountByUsername", input.Username).Return(int64(1), nil).Once()
 
 		//call the register method
-		user, err := suite.useCase.Register(input)
+		user, err := suite.useCase.Register(context.Background(), input)
 
 		//assertions
 		suite.Error(err)
@@ -197,7 +446,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
 		suite.userRepo.On("CountByUsername", input.Username).Return(int64(0), errors.New("error counting"))
 
 		//call the register method
-		user, err := suite.useCase.Register(input)
+		user, err := suite.useCase.Register(context.Background(), input)
 
 		//assertions
 		suite.Error(err)
@@ -217,7 +466,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
 		suite.userRepo.On("CountAll").Return(int64(0), errors.New("error counting")).Once()
 
 		//calll the register method
-		user, err := suite.useCase.Register(input)
+		user, err := suite.useCase.Register(context.Background(), input)
 
 		//assertions
 		suite.Error(err)
@@ -236,7 +485,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
     suite.userRepo.On("CountAll").Return(int64(0), nil).Once()
     suite.passwordService.On("HashPassword", input.Password).Return("", errors.New("error during hashing")).Once()
 
-    user, err := suite.useCase.Register(input)
+    user, err := suite.useCase.Register(context.Background(), input)
 
     suite.Error(err)
     suite.Nil(user)
@@ -256,7 +505,7 @@ func (suite *UserUseCaseTestSuite) TestRegister() {
 
 		// Call the Register method
 
-		user, err := suite.useCase.Register(input)
+		user, err := suite.useCase.Register(context.Background(), input)
 
 		//assertions
 		suite.Error(err)
@@ -276,11 +525,12 @@ func (suite *UserUseCaseTestSuite) TestLogin() {
 		Password: "123123123",
 	}
 	hashedPassword := "hashed123123123"
-	// userID:=primitive.NewObjectID().Hex()
+	// userID:=domain.NewID()
 	expectedToken := "mockedjwttoken"
+	client := domain.ClientInfo{UserAgent: "curl/8.0", IP: "192.0.2.1"}
 
 	existingUser := &domain.User{
-		ID:       primitive.NewObjectID(),
+		ID:       domain.NewID(),
 		Username: input.Username,
 		Password: hashedPassword,
 		Role:     domain.RoleUser,
@@ -295,13 +545,34 @@ func (suite *UserUseCaseTestSuite) TestLogin() {
 
 		suite.passwordService.On("ComparePassword", hashedPassword, input.Password).Return(true).Once()
 
-		suite.jwtService.On("GenerateToken", existingUser.ID.Hex(), existingUser.Role).Return(expectedToken, nil).Once()
+		suite.sessions.On("CreateSession", mock.Anything).Return(nil).Once()
+		suite.jwtService.On("GenerateToken", existingUser.ID, existingUser.Role, mock.Anything).Return(expectedToken, nil).Once()
+		suite.jwtService.On("GenerateRefreshToken").Return("refresh", "hash-refresh", nil).Once()
+		suite.refreshTokens.On("CreateToken", mock.Anything).Return(nil).Once()
 
-		token, user, err := suite.useCase.Login(*input)
+		pair, user, err := suite.useCase.Login(context.Background(), *input, client)
 
 		suite.NoError(err)
 		suite.NotNil(user)
-		suite.Equal(expectedToken, token)
+		suite.Equal(expectedToken, pair.AccessToken)
+		suite.Equal(suite.now.Add(15*time.Minute), pair.AccessExpiresAt)
+		suite.Equal("refresh", pair.RefreshToken)
+		suite.Equal(suite.now.Add(7*24*time.Hour), pair.RefreshExpiresAt)
+		//only the hash of the refresh token is stored, in a new family
+		stored := suite.refreshTokens.Calls[0].Arguments.Get(0).(*domain.RefreshToken)
+		suite.Equal("hash-refresh", stored.TokenHash)
+		suite.Equal(existingUser.ID, stored.UserID)
+		suite.Equal(pair.RefreshExpiresAt, stored.ExpiresAt)
+		//the login is recorded as a session, the access token and the refresh token family belong to it
+		session := suite.sessions.Calls[0].Arguments.Get(0).(*domain.Session)
+		suite.Equal(existingUser.ID, session.UserID)
+		suite.Equal(client.UserAgent, session.UserAgent)
+		suite.Equal(client.IP, session.IP)
+		suite.Equal(suite.now, session.CreatedAt)
+		suite.Equal(suite.now, session.LastSeenAt)
+		suite.Equal(pair.RefreshExpiresAt, session.ExpiresAt)
+		suite.Equal(session.ID, stored.FamilyID)
+		suite.jwtService.AssertCalled(suite.T(), "GenerateToken", existingUser.ID, existingUser.Role, session.ID)
 		suite.NotNil(user)
 		suite.Equal(existingUser.ID, user.ID)
 		suite.Equal(existingUser.Username, user.Username)
@@ -315,15 +586,16 @@ func (suite *UserUseCaseTestSuite) TestLogin() {
 	suite.Run("user not found", func() {
 		suite.SetupTest()
 
-		// This setup is correct - returns nil user and mongo.ErrNoDocuments
-		suite.userRepo.On("FindByUsername", input.Username).Return(nil, mongo.ErrNoDocuments).Once()
+		// the repository translates mongo.ErrNoDocuments into a not found error
+		suite.userRepo.On("FindByUsername", input.Username).Return(nil, domain.NotFound("user not found")).Once()
 
-		token, loggedInUser, err := suite.useCase.Login(*input)
+		pair, loggedInUser, err := suite.useCase.Login(context.Background(), *input, client)
 
 		suite.Error(err)
-		suite.Empty(token)
+		suite.Nil(pair)
 		suite.Nil(loggedInUser) 
 		suite.EqualError(err, "invalid username or password")
+		suite.ErrorIs(err, domain.ErrUnauthorized)
 		suite.userRepo.AssertExpectations(suite.T())
 		suite.passwordService.AssertNotCalled(suite.T(), "ComparePassword")
 		suite.jwtService.AssertNotCalled(suite.T(), "GenerateToken")
@@ -340,10 +612,10 @@ func (suite *UserUseCaseTestSuite) TestLogin() {
 		suite.passwordService.On("ComparePassword", hashedPassword, input.Password).Return(false).Once()
 
 		
-		token, loggedInUser, err := suite.useCase.Login(*input)
+		pair, loggedInUser, err := suite.useCase.Login(context.Background(), *input, client)
 
 		suite.Error(err)
-		suite.Empty(token)
+		suite.Nil(pair)
 		suite.Nil(loggedInUser) 
 		suite.EqualError(err, "invalid username or password")
 		suite.userRepo.AssertExpectations(suite.T())
@@ -352,61 +624,806 @@ func (suite *UserUseCaseTestSuite) TestLogin() {
 
 	})
 
+	//test 4 deactivated account
+
+	suite.Run("deactivated account", func() {
+		suite.SetupTest()
+
+		deactivated := *existingUser
+		deactivated.DeactivatedAt = &suite.now
+		suite.userRepo.On("FindByUsername", input.Username).Return(&deactivated, nil).Once()
+		suite.passwordService.On("ComparePassword", hashedPassword, input.Password).Return(true).Once()
+
+		pair, loggedInUser, err := suite.useCase.Login(context.Background(), *input, client)
+
+		suite.Nil(pair)
+		suite.Nil(loggedInUser)
+		suite.ErrorIs(err, domain.ErrAccountDeactivated)
+		suite.jwtService.AssertNotCalled(suite.T(), "GenerateToken")
+	})
+
+}
+
+// TestRefresh tests the rotation of refresh tokens and the reuse detection
+func (suite *UserUseCaseTestSuite) TestRefresh() {
+	user := &domain.User{ID: domain.NewID(), Username: "tsige", Role: domain.RoleUser}
+	familyID := domain.NewID()
+	stored := func() *domain.RefreshToken {
+		return &domain.RefreshToken{
+			ID:        domain.NewID(),
+			UserID:    user.ID,
+			FamilyID:  familyID,
+			TokenHash: "hash-refresh",
+			CreatedAt: suite.now.Add(-time.Hour),
+			ExpiresAt: suite.now.Add(time.Hour),
+		}
+	}
+	session := &domain.Session{ID: familyID, UserID: user.ID, ExpiresAt: suite.now.Add(time.Hour)}
+
+	// Test 1 a fresh token is exchanged for a new pair of the same family
+	suite.Run("rotates the token", func() {
+		suite.SetupTest()
+		token := stored()
+		suite.refreshTokens.On("FindByHash", "hash-refresh").Return(token, nil).Once()
+		suite.userRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		suite.sessions.On("FindSession", familyID).Return(session, nil).Once()
+		suite.refreshTokens.On("MarkUsed", token.ID, suite.now).Return(nil).Once()
+		suite.jwtService.On("GenerateToken", user.ID, user.Role, familyID).Return("access", nil).Once()
+		suite.jwtService.On("GenerateRefreshToken").Return("next", "hash-next", nil).Once()
+		suite.refreshTokens.On("CreateToken", mock.Anything).Return(nil).Once()
+		//the session lives as long as its newest refresh token
+		suite.sessions.On("ExtendSession", familyID, suite.now, suite.now.Add(7*24*time.Hour)).Return(nil).Once()
+
+		pair, refreshed, err := suite.useCase.Refresh(context.Background(), "refresh")
+
+		suite.NoError(err)
+		suite.Equal(user.ID, refreshed.ID)
+		suite.Equal("access", pair.AccessToken)
+		suite.Equal("next", pair.RefreshToken)
+		next := suite.refreshTokens.Calls[2].Arguments.Get(0).(*domain.RefreshToken)
+		suite.Equal(familyID, next.FamilyID)
+		suite.Equal("hash-next", next.TokenHash)
+		suite.refreshTokens.AssertNotCalled(suite.T(), "RevokeFamily", mock.Anything, mock.Anything)
+		suite.sessions.AssertExpectations(suite.T())
+	})
+
+	// Test 2 a used token comes back, the family is revoked
+	suite.Run("reused token", func() {
+		suite.SetupTest()
+		token := stored()
+		usedAt := suite.now.Add(-time.Minute)
+		token.UsedAt = &usedAt
+		suite.refreshTokens.On("FindByHash", "hash-refresh").Return(token, nil).Once()
+		suite.refreshTokens.On("RevokeFamily", familyID, suite.now).Return(nil).Once()
+		suite.sessions.On("RevokeSession", familyID, suite.now).Return(nil).Once()
+
+		pair, _, err := suite.useCase.Refresh(context.Background(), "refresh")
+
+		suite.Nil(pair)
+		suite.ErrorIs(err, domain.ErrRefreshTokenReused)
+		suite.refreshTokens.AssertExpectations(suite.T())
+		suite.sessions.AssertExpectations(suite.T())
+		suite.jwtService.AssertNotCalled(suite.T(), "GenerateToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	// Test 3 losing a concurrent refresh counts as a reuse
+	suite.Run("concurrent refresh", func() {
+		suite.SetupTest()
+		token := stored()
+		suite.refreshTokens.On("FindByHash", "hash-refresh").Return(token, nil).Once()
+		suite.userRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		suite.sessions.On("FindSession", familyID).Return(session, nil).Once()
+		suite.refreshTokens.On("MarkUsed", token.ID, suite.now).Return(domain.Conflict("refresh token was already used")).Once()
+		suite.refreshTokens.On("RevokeFamily", familyID, suite.now).Return(nil).Once()
+		suite.sessions.On("RevokeSession", familyID, suite.now).Return(nil).Once()
+
+		_, _, err := suite.useCase.Refresh(context.Background(), "refresh")
+
+		suite.ErrorIs(err, domain.ErrRefreshTokenReused)
+		suite.refreshTokens.AssertExpectations(suite.T())
+	})
+
+	// Test 4 unknown, expired and revoked tokens are refused
+	suite.Run("invalid tokens", func() {
+		suite.SetupTest()
+		expired := stored()
+		expired.ExpiresAt = suite.now
+		revoked := stored()
+		revoked.RevokedAt = &suite.now
+		suite.refreshTokens.On("FindByHash", "hash-unknown").Return(nil, domain.NotFound("refresh token not found")).Once()
+		suite.refreshTokens.On("FindByHash", "hash-expired").Return(expired, nil).Once()
+		suite.refreshTokens.On("FindByHash", "hash-revoked").Return(revoked, nil).Once()
+
+		for _, token := range []string{"", "unknown", "expired", "revoked"} {
+			_, _, err := suite.useCase.Refresh(context.Background(), token)
+			suite.ErrorIs(err, domain.ErrInvalidRefreshToken, token)
+		}
+		suite.refreshTokens.AssertNotCalled(suite.T(), "MarkUsed", mock.Anything, mock.Anything)
+	})
+
+	// Test 5 the user was deactivated since the login
+	suite.Run("deactivated user", func() {
+		suite.SetupTest()
+		deactivated := *user
+		deactivated.DeactivatedAt = &suite.now
+		suite.refreshTokens.On("FindByHash", "hash-refresh").Return(stored(), nil).Once()
+		suite.userRepo.On("FindByID", user.ID).Return(&deactivated, nil).Once()
+		suite.refreshTokens.On("RevokeFamily", familyID, suite.now).Return(nil).Once()
+		suite.sessions.On("RevokeSession", familyID, suite.now).Return(nil).Once()
+
+		_, _, err := suite.useCase.Refresh(context.Background(), "refresh")
+
+		suite.ErrorIs(err, domain.ErrAccountDeactivated)
+		suite.refreshTokens.AssertExpectations(suite.T())
+	})
+
+	// Test 6 the session was revoked from another device, or predates the sessions
+	suite.Run("revoked session", func() {
+		suite.SetupTest()
+		revoked := *session
+		revoked.RevokedAt = &suite.now
+		suite.refreshTokens.On("FindByHash", "hash-refresh").Return(stored(), nil).Twice()
+		suite.userRepo.On("FindByID", user.ID).Return(user, nil).Twice()
+		suite.sessions.On("FindSession", familyID).Return(&revoked, nil).Once()
+		suite.sessions.On("FindSession", familyID).Return(nil, domain.NotFound("session not found")).Once()
+		suite.refreshTokens.On("RevokeFamily", familyID, suite.now).Return(nil).Twice()
+		suite.sessions.On("RevokeSession", familyID, suite.now).Return(nil).Once()
+		suite.sessions.On("RevokeSession", familyID, suite.now).Return(domain.NotFound("session not found")).Once()
+
+		for i := 0; i < 2; i++ {
+			_, _, err := suite.useCase.Refresh(context.Background(), "refresh")
+			suite.ErrorIs(err, domain.ErrInvalidRefreshToken)
+		}
+		suite.refreshTokens.AssertNotCalled(suite.T(), "MarkUsed", mock.Anything, mock.Anything)
+		suite.refreshTokens.AssertExpectations(suite.T())
+	})
+}
+
+// TestLogout tests that logging out revokes the access token and the family of the refresh token
+func (suite *UserUseCaseTestSuite) TestLogout() {
+	claims := &domain.AccessClaims{TokenID: domain.NewID(), UserID: domain.NewID(), ExpiresAt: suite.now.Add(time.Minute)}
+
+	suite.Run("revokes both tokens", func() {
+		suite.SetupTest()
+		token := &domain.RefreshToken{ID: domain.NewID(), FamilyID: domain.NewID()}
+		suite.jwtService.On("ParseToken", "access").Return(claims, nil).Once()
+		suite.revocations.On("RevokeToken", claims.TokenID, claims.ExpiresAt).Return(nil).Once()
+		suite.refreshTokens.On("FindByHash", "hash-refresh").Return(token, nil).Once()
+		suite.refreshTokens.On("RevokeFamily", token.FamilyID, suite.now).Return(nil).Once()
+		suite.sessions.On("RevokeSession", token.FamilyID, suite.now).Return(nil).Once()
+
+		suite.NoError(suite.useCase.Logout(context.Background(), "access", "refresh"))
+		suite.sessions.AssertExpectations(suite.T())
+		suite.revocations.AssertExpectations(suite.T())
+		suite.refreshTokens.AssertExpectations(suite.T())
+	})
+
+	suite.Run("unknown tokens", func() {
+		suite.SetupTest()
+		suite.jwtService.On("ParseToken", mock.Anything).Return(nil, domain.Unauthorized("unauthorized: invalid token"))
+		suite.refreshTokens.On("FindByHash", "hash-unknown").Return(nil, domain.NotFound("refresh token not found")).Once()
+
+		suite.NoError(suite.useCase.Logout(context.Background(), "expired", "unknown"))
+		suite.NoError(suite.useCase.Logout(context.Background(), "", ""))
+		suite.revocations.AssertNotCalled(suite.T(), "RevokeToken", mock.Anything, mock.Anything)
+		suite.refreshTokens.AssertNotCalled(suite.T(), "RevokeFamily", mock.Anything, mock.Anything)
+	})
+}
+
+// TestLogoutEverywhere tests that an admin revokes every token of a user
+func (suite *UserUseCaseTestSuite) TestLogoutEverywhere() {
+	admin := domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}
+	userID := domain.NewID()
+
+	suite.Run("admin", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleUser}, nil).Once()
+		suite.refreshTokens.On("RevokeUserTokens", userID, suite.now).Return(nil).Once()
+		suite.revocations.On("RevokeUserTokens", userID, suite.now, suite.now.Add(15*time.Minute)).Return(nil).Once()
+		suite.sessions.On("RevokeUserSessions", userID, suite.now).Return(nil).Once()
+		suite.personalTokens.On("RevokeUserPersonalTokens", userID, suite.now).Return(nil).Once()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil).Once()
+
+		suite.NoError(suite.useCase.LogoutEverywhere(context.Background(), admin, userID))
+
+		suite.refreshTokens.AssertExpectations(suite.T())
+		suite.revocations.AssertExpectations(suite.T())
+		suite.sessions.AssertExpectations(suite.T())
+		suite.personalTokens.AssertExpectations(suite.T())
+		record := suite.auditRepo.lastRecord()
+		suite.Equal(domain.AuditLogoutEverywhere, record.Action)
+		suite.Equal(userID, record.UserID)
+		suite.Equal(admin.UserID, record.ActorID)
+	})
+
+	suite.Run("not an admin", func() {
+		suite.SetupTest()
+
+		err := suite.useCase.LogoutEverywhere(context.Background(), domain.Actor{UserID: userID, Role: domain.RoleUser}, userID)
+
+		suite.ErrorIs(err, domain.ErrForbidden)
+		suite.revocations.AssertNotCalled(suite.T(), "RevokeUserTokens", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	suite.Run("unknown user", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(nil, domain.NotFound("user not found")).Once()
+
+		err := suite.useCase.LogoutEverywhere(context.Background(), admin, userID)
+
+		suite.ErrorIs(err, domain.ErrNotFound)
+		suite.revocations.AssertNotCalled(suite.T(), "RevokeUserTokens", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// TestListSessions tests that users see their usable sessions
+func (suite *UserUseCaseTestSuite) TestListSessions() {
+	actor := domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser, SessionID: domain.NewID()}
+	current := domain.Session{ID: actor.SessionID, UserID: actor.UserID, ExpiresAt: suite.now.Add(time.Hour)}
+	other := domain.Session{ID: domain.NewID(), UserID: actor.UserID, ExpiresAt: suite.now.Add(time.Hour)}
+	expired := domain.Session{ID: domain.NewID(), UserID: actor.UserID, ExpiresAt: suite.now}
+
+	suite.Run("active sessions", func() {
+		suite.SetupTest()
+		suite.sessions.On("ListSessions", actor.UserID).Return([]domain.Session{other, current, expired}, nil).Once()
+
+		sessions, err := suite.useCase.ListSessions(context.Background(), actor)
+
+		suite.NoError(err)
+		suite.Require().Len(sessions, 2)
+		suite.Equal(other.ID, sessions[0].ID)
+		suite.False(sessions[0].Current)
+		suite.Equal(current.ID, sessions[1].ID)
+		suite.True(sessions[1].Current)
+	})
+
+	suite.Run("repository error", func() {
+		suite.SetupTest()
+		suite.sessions.On("ListSessions", actor.UserID).Return(nil, errors.New("db down")).Once()
+
+		_, err := suite.useCase.ListSessions(context.Background(), actor)
+
+		suite.ErrorIs(err, domain.ErrInternal)
+	})
+}
+
+// TestRevokeSession tests that users log out one of their own sessions
+func (suite *UserUseCaseTestSuite) TestRevokeSession() {
+	actor := domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser}
+	session := &domain.Session{ID: domain.NewID(), UserID: actor.UserID, ExpiresAt: suite.now.Add(time.Hour)}
+
+	suite.Run("own session", func() {
+		suite.SetupTest()
+		suite.sessions.On("FindSession", session.ID).Return(session, nil).Once()
+		suite.refreshTokens.On("RevokeFamily", session.ID, suite.now).Return(nil).Once()
+		suite.sessions.On("RevokeSession", session.ID, suite.now).Return(nil).Once()
+
+		suite.NoError(suite.useCase.RevokeSession(context.Background(), actor, session.ID))
+		suite.refreshTokens.AssertExpectations(suite.T())
+		suite.sessions.AssertExpectations(suite.T())
+	})
+
+	suite.Run("someone else's session", func() {
+		suite.SetupTest()
+		suite.sessions.On("FindSession", session.ID).Return(session, nil).Once()
+
+		err := suite.useCase.RevokeSession(context.Background(), domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}, session.ID)
+
+		suite.ErrorIs(err, domain.ErrNotFound)
+		suite.sessions.AssertNotCalled(suite.T(), "RevokeSession", mock.Anything, mock.Anything)
+	})
+
+	suite.Run("already revoked", func() {
+		suite.SetupTest()
+		revoked := *session
+		revoked.RevokedAt = &suite.now
+		suite.sessions.On("FindSession", session.ID).Return(&revoked, nil).Once()
+
+		suite.ErrorIs(suite.useCase.RevokeSession(context.Background(), actor, session.ID), domain.ErrNotFound)
+	})
+}
+
+// TestCreatePersonalToken tests that personal access tokens are validated and stored as hashes
+func (suite *UserUseCaseTestSuite) TestCreatePersonalToken() {
+	actor := domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser}
+
+	suite.Run("default expiry", func() {
+		suite.SetupTest()
+		suite.jwtService.On("GeneratePersonalToken").Return("pat_secret", "hash-pat_secret", nil).Once()
+		suite.personalTokens.On("CreatePersonalToken", mock.Anything).Return(nil).Once()
+
+		input := domain.PersonalTokenInput{Name: "  deploy job ", Scopes: []domain.Scope{domain.ScopeTasksWrite, domain.ScopeTasksRead, domain.ScopeTasksWrite}}
+		personal, token, err := suite.useCase.CreatePersonalToken(context.Background(), actor, input)
+
+		suite.Require().NoError(err)
+		suite.Equal("pat_secret", token)
+		suite.Equal(actor.UserID, personal.UserID)
+		suite.Equal("deploy job", personal.Name)
+		suite.Equal([]domain.Scope{domain.ScopeTasksRead, domain.ScopeTasksWrite}, personal.Scopes)
+		suite.Equal("hash-pat_secret", personal.TokenHash)
+		suite.Equal(suite.now, personal.CreatedAt)
+		suite.Equal(suite.now.Add(usecases.DefaultPersonalTokenTTL), personal.ExpiresAt)
+		suite.personalTokens.AssertExpectations(suite.T())
+	})
+
+	suite.Run("given expiry", func() {
+		suite.SetupTest()
+		suite.jwtService.On("GeneratePersonalToken").Return("pat_secret", "hash-pat_secret", nil).Once()
+		suite.personalTokens.On("CreatePersonalToken", mock.Anything).Return(nil).Once()
+		expiresAt := suite.now.Add(48 * time.Hour)
+
+		personal, _, err := suite.useCase.CreatePersonalToken(context.Background(), actor, domain.PersonalTokenInput{Name: "ci", Scopes: []domain.Scope{domain.ScopeTasksRead}, ExpiresAt: &expiresAt})
+
+		suite.Require().NoError(err)
+		suite.Equal(expiresAt, personal.ExpiresAt)
+	})
+
+	suite.Run("invalid input", func() {
+		suite.SetupTest()
+		past := suite.now.Add(-time.Minute)
+		tooLate := suite.now.Add(usecases.MaxPersonalTokenTTL + time.Hour)
+
+		cases := map[string]domain.PersonalTokenInput{
+			"name":    {Name: " ", Scopes: []domain.Scope{domain.ScopeTasksRead}},
+			"scopes":  {Name: "ci"},
+			"unknown": {Name: "ci", Scopes: []domain.Scope{"users:write"}},
+			"past":    {Name: "ci", Scopes: []domain.Scope{domain.ScopeTasksRead}, ExpiresAt: &past},
+			"too far": {Name: "ci", Scopes: []domain.Scope{domain.ScopeTasksRead}, ExpiresAt: &tooLate},
+		}
+		for name, input := range cases {
+			_, _, err := suite.useCase.CreatePersonalToken(context.Background(), actor, input)
+			suite.ErrorIs(err, domain.ErrValidation, name)
+		}
+		suite.jwtService.AssertNotCalled(suite.T(), "GeneratePersonalToken")
+		suite.personalTokens.AssertNotCalled(suite.T(), "CreatePersonalToken", mock.Anything)
+	})
+}
+
+// TestListPersonalTokens tests that expired tokens are left out
+func (suite *UserUseCaseTestSuite) TestListPersonalTokens() {
+	actor := domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser}
+	active := domain.PersonalToken{ID: domain.NewID(), UserID: actor.UserID, ExpiresAt: suite.now.Add(time.Hour)}
+	expired := domain.PersonalToken{ID: domain.NewID(), UserID: actor.UserID, ExpiresAt: suite.now}
+	suite.personalTokens.On("ListPersonalTokens", actor.UserID).Return([]domain.PersonalToken{active, expired}, nil).Once()
+
+	tokens, err := suite.useCase.ListPersonalTokens(context.Background(), actor)
+
+	suite.NoError(err)
+	suite.Equal([]domain.PersonalToken{active}, tokens)
+}
+
+// TestRevokePersonalToken tests that users only revoke their own tokens
+func (suite *UserUseCaseTestSuite) TestRevokePersonalToken() {
+	actor := domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser}
+	personal := &domain.PersonalToken{ID: domain.NewID(), UserID: actor.UserID, ExpiresAt: suite.now.Add(time.Hour)}
+
+	suite.Run("own token", func() {
+		suite.SetupTest()
+		suite.personalTokens.On("FindPersonalToken", personal.ID).Return(personal, nil).Once()
+		suite.personalTokens.On("RevokePersonalToken", personal.ID, suite.now).Return(nil).Once()
+
+		suite.NoError(suite.useCase.RevokePersonalToken(context.Background(), actor, personal.ID))
+		suite.personalTokens.AssertExpectations(suite.T())
+	})
+
+	suite.Run("someone else's token", func() {
+		suite.SetupTest()
+		suite.personalTokens.On("FindPersonalToken", personal.ID).Return(personal, nil).Once()
+
+		err := suite.useCase.RevokePersonalToken(context.Background(), domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}, personal.ID)
+
+		suite.ErrorIs(err, domain.ErrNotFound)
+		suite.personalTokens.AssertNotCalled(suite.T(), "RevokePersonalToken", mock.Anything, mock.Anything)
+	})
+
+	suite.Run("unknown token", func() {
+		suite.SetupTest()
+		suite.personalTokens.On("FindPersonalToken", "not-an-id").Return(nil, domain.Validation("invalid personal token ID")).Once()
+
+		suite.ErrorIs(suite.useCase.RevokePersonalToken(context.Background(), actor, "not-an-id"), domain.ErrValidation)
+	})
+}
+
+func (suite *UserUseCaseTestSuite) TestChangeRole() {
+	admin := domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}
+	userID := domain.NewID()
+
+	// Test 1 an admin grants the admin role
+	suite.Run("grant admin", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Username: "alice", Role: domain.RoleUser}, nil).Once()
+		suite.userRepo.On("UpdateRole", userID, domain.RoleAdmin).Return(nil).Once()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil).Once()
+
+		user, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.RoleAdmin)
+
+		suite.NoError(err)
+		suite.Equal(domain.RoleAdmin, user.Role)
+		record := suite.auditRepo.lastRecord()
+		suite.Equal(userID, record.UserID)
+		suite.Equal(domain.AuditRoleChange, record.Action)
+		suite.Equal(admin.UserID, record.ActorID)
+		suite.Equal([]domain.FieldChange{{Field: "role", From: "User", To: "Admin"}}, record.Changes)
+		suite.userRepo.AssertNotCalled(suite.T(), "CountByRole", mock.Anything)
+		suite.userRepo.AssertExpectations(suite.T())
+	})
+
+	// Test 2 an admin revokes the admin role while other admins are left
+	suite.Run("revoke admin", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleAdmin}, nil).Once()
+		suite.userRepo.On("CountByRole", domain.RoleAdmin).Return(int64(2), nil).Once()
+		suite.userRepo.On("UpdateRole", userID, domain.RoleUser).Return(nil).Once()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil).Once()
+
+		user, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.RoleUser)
+
+		suite.NoError(err)
+		suite.Equal(domain.RoleUser, user.Role)
+		suite.userRepo.AssertExpectations(suite.T())
+	})
+
+	// Test 3 the last admin keeps the admin role
+	suite.Run("last admin", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", admin.UserID).Return(&domain.User{ID: admin.UserID, Role: domain.RoleAdmin}, nil).Once()
+		suite.userRepo.On("CountByRole", domain.RoleAdmin).Return(int64(1), nil).Once()
+
+		user, err := suite.useCase.ChangeRole(context.Background(), admin, admin.UserID, domain.RoleUser)
+
+		suite.Nil(user)
+		suite.ErrorIs(err, domain.ErrLastAdmin)
+		suite.userRepo.AssertNotCalled(suite.T(), "UpdateRole", mock.Anything, mock.Anything)
+		suite.auditRepo.AssertNotCalled(suite.T(), "AddRecord", mock.Anything)
+	})
+
+	// Test 4 an unchanged role is not written nor audited
+	suite.Run("unchanged role", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleUser}, nil).Once()
+
+		user, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.RoleUser)
+
+		suite.NoError(err)
+		suite.Equal(domain.RoleUser, user.Role)
+		suite.userRepo.AssertNotCalled(suite.T(), "UpdateRole", mock.Anything, mock.Anything)
+		suite.auditRepo.AssertNotCalled(suite.T(), "AddRecord", mock.Anything)
+	})
+
+	// Test 5 only admins change roles
+	suite.Run("not an admin", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.ChangeRole(context.Background(), domain.Actor{UserID: userID, Role: domain.RoleUser}, userID, domain.RoleAdmin)
+
+		suite.ErrorIs(err, domain.ErrForbidden)
+		suite.userRepo.AssertNotCalled(suite.T(), "FindByID", mock.Anything)
+	})
+
+	// Test 6 unknown roles are rejected
+	suite.Run("invalid role", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.Role("Owner"))
+
+		suite.ErrorIs(err, domain.ErrValidation)
+		suite.userRepo.AssertNotCalled(suite.T(), "FindByID", mock.Anything)
+	})
+
+	// Test 7 unknown users are reported as not found
+	suite.Run("unknown user", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(nil, domain.NotFound("user not found")).Once()
+
+		_, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.RoleAdmin)
+
+		suite.ErrorIs(err, domain.ErrNotFound)
+	})
+
+	// Test 8 repository failures are internal errors
+	suite.Run("repository error", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleUser}, nil).Once()
+		suite.userRepo.On("UpdateRole", userID, domain.RoleAdmin).Return(errors.New("database error")).Once()
+
+		_, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.RoleAdmin)
+
+		suite.ErrorIs(err, domain.ErrInternal)
+		suite.auditRepo.AssertNotCalled(suite.T(), "AddRecord", mock.Anything)
+	})
+
+	// Test 9 the other admin was demoted after the count, the repository refuses the write
+	suite.Run("concurrent demotion", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleAdmin}, nil).Once()
+		suite.userRepo.On("CountByRole", domain.RoleAdmin).Return(int64(2), nil).Once()
+		suite.userRepo.On("UpdateRole", userID, domain.RoleUser).Return(domain.ErrLastAdmin).Once()
+
+		user, err := suite.useCase.ChangeRole(context.Background(), admin, userID, domain.RoleUser)
+
+		suite.Nil(user)
+		suite.ErrorIs(err, domain.ErrLastAdmin)
+		suite.auditRepo.AssertNotCalled(suite.T(), "AddRecord", mock.Anything)
+	})
+}
+func (suite *UserUseCaseTestSuite) TestRegisterValidation() {
+	// Test 1 every invalid field is reported
+	suite.Run("invalid username and password", func() {
+		suite.SetupTest()
+
+		user, err := suite.useCase.Register(context.Background(), &domain.RegisterUserInput{Username: "ab", Password: "short"})
+
+		suite.Nil(user)
+		suite.ErrorIs(err, domain.ErrValidation)
+		var domainErr *domain.Error
+		suite.Require().ErrorAs(err, &domainErr)
+		suite.Equal([]domain.FieldError{
+			{Field: "username", Message: "username must be at least 3 characters"},
+			{Field: "password", Message: "password must be at least 8 characters"},
+		}, domainErr.Fields)
+		suite.userRepo.AssertNotCalled(suite.T(), "CountByUsername", mock.Anything)
+	})
+
+	// Test 2 usernames are limited to a safe charset
+	suite.Run("username charset", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.Register(context.Background(), &domain.RegisterUserInput{Username: "tsige<script>", Password: "123123123"})
+
+		suite.EqualError(err, "username may only contain letters, digits, '.', '_' and '-'")
+	})
+
+	// Test 3 empty fields are required
+	suite.Run("empty username", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.Register(context.Background(), &domain.RegisterUserInput{Password: "123123123"})
+
+		suite.EqualError(err, "username cannot be empty")
+	})
+
+	// Test 4 the password limit is in bytes, 72 two byte characters are too long for bcrypt
+	suite.Run("multi-byte password", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.Register(context.Background(), &domain.RegisterUserInput{Username: "alice", Password: strings.Repeat("é", 72)})
+
+		suite.ErrorIs(err, domain.ErrValidation)
+		suite.EqualError(err, "password must be at most 72 bytes")
+		suite.passwordService.AssertNotCalled(suite.T(), "HashPassword", mock.Anything)
+	})
+}
+
+func (suite *UserUseCaseTestSuite) TestListUsers() {
+	admin := domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}
+
+	// Test 1 the page size defaults and the filter reaches the repository
+	suite.Run("admin", func() {
+		suite.SetupTest()
+		page := &domain.UserPage{Users: []domain.User{{ID: domain.NewID(), Username: "alice"}}, Total: 1}
+		suite.userRepo.On("ListUsers", domain.UserFilter{Search: "ali", Limit: usecases.DefaultPageSize}).Return(page, nil).Once()
+
+		result, err := suite.useCase.ListUsers(context.Background(), admin, domain.UserFilter{Search: "ali"})
+
+		suite.NoError(err)
+		suite.Equal(page, result)
+		suite.userRepo.AssertExpectations(suite.T())
+	})
+
+	// Test 2 only admins list users
+	suite.Run("not an admin", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.ListUsers(context.Background(), domain.Actor{UserID: domain.NewID(), Role: domain.RoleUser}, domain.UserFilter{})
+
+		suite.ErrorIs(err, domain.ErrForbidden)
+		suite.userRepo.AssertNotCalled(suite.T(), "ListUsers", mock.Anything)
+	})
+
+	// Test 3 invalid filters are rejected
+	suite.Run("invalid filter", func() {
+		suite.SetupTest()
+
+		for _, filter := range []domain.UserFilter{{Limit: -1}, {Role: "Owner"}, {Cursor: "!"}} {
+			_, err := suite.useCase.ListUsers(context.Background(), admin, filter)
+
+			suite.ErrorIs(err, domain.ErrValidation)
+		}
+		suite.userRepo.AssertNotCalled(suite.T(), "ListUsers", mock.Anything)
+	})
+}
+
+func (suite *UserUseCaseTestSuite) TestDeactivateUser() {
+	admin := domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}
+	userID := domain.NewID()
+
+	// Test 1 deactivating a user stamps the time and audits it
+	suite.Run("deactivate", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleUser}, nil).Once()
+		suite.userRepo.On("UpdateDeactivatedAt", userID, &suite.now).Return(nil).Once()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil).Once()
+
+		user, err := suite.useCase.DeactivateUser(context.Background(), admin, userID)
+
+		suite.NoError(err)
+		suite.False(user.IsActive())
+		record := suite.auditRepo.lastRecord()
+		suite.Equal(domain.AuditDeactivate, record.Action)
+		suite.Equal(userID, record.UserID)
+		suite.Equal([]domain.FieldChange{{Field: "deactivatedAt", To: "2025-03-01T12:00:00Z"}}, record.Changes)
+		suite.userRepo.AssertExpectations(suite.T())
+	})
+
+	// Test 2 reactivating clears the time
+	suite.Run("reactivate", func() {
+		suite.SetupTest()
+		deactivatedAt := suite.now.Add(-time.Hour)
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, Role: domain.RoleUser, DeactivatedAt: &deactivatedAt}, nil).Once()
+		suite.userRepo.On("UpdateDeactivatedAt", userID, (*time.Time)(nil)).Return(nil).Once()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil).Once()
+
+		user, err := suite.useCase.ReactivateUser(context.Background(), admin, userID)
+
+		suite.NoError(err)
+		suite.True(user.IsActive())
+		suite.Equal(domain.AuditReactivate, suite.auditRepo.lastRecord().Action)
+		suite.userRepo.AssertExpectations(suite.T())
+	})
+
+	// Test 3 deactivating a deactivated user changes nothing
+	suite.Run("already deactivated", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", userID).Return(&domain.User{ID: userID, DeactivatedAt: &suite.now}, nil).Once()
+
+		_, err := suite.useCase.DeactivateUser(context.Background(), admin, userID)
+
+		suite.NoError(err)
+		suite.userRepo.AssertNotCalled(suite.T(), "UpdateDeactivatedAt", mock.Anything, mock.Anything)
+		suite.auditRepo.AssertNotCalled(suite.T(), "AddRecord", mock.Anything)
+	})
+
+	// Test 4 the last active admin stays active
+	suite.Run("last admin", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", admin.UserID).Return(&domain.User{ID: admin.UserID, Role: domain.RoleAdmin}, nil).Once()
+		suite.userRepo.On("CountByRole", domain.RoleAdmin).Return(int64(1), nil).Once()
+
+		_, err := suite.useCase.DeactivateUser(context.Background(), admin, admin.UserID)
+
+		suite.ErrorIs(err, domain.ErrLastAdmin)
+		suite.userRepo.AssertNotCalled(suite.T(), "UpdateDeactivatedAt", mock.Anything, mock.Anything)
+	})
+
+	// Test 5 only admins deactivate users
+	suite.Run("not an admin", func() {
+		suite.SetupTest()
+
+		_, err := suite.useCase.DeactivateUser(context.Background(), domain.Actor{UserID: userID, Role: domain.RoleUser}, userID)
+
+		suite.ErrorIs(err, domain.ErrForbidden)
+	})
+}
+
+func (suite *UserUseCaseTestSuite) TestDeleteUser() {
+	admin := domain.Actor{UserID: domain.NewID(), Role: domain.RoleAdmin}
+	user := &domain.User{ID: domain.NewID(), Username: "alice", Role: domain.RoleUser}
+	owned := domain.Task{ID: domain.NewID(), Title: "Owned", OwnerID: user.ID, Version: 2}
+	assigned := domain.Task{ID: domain.NewID(), Title: "Assigned", OwnerID: admin.UserID, Assignees: []string{user.ID, admin.UserID}, Version: 4}
+	empty := &domain.TaskPage{Tasks: []domain.Task{}}
+	ownedBy := func(trashed bool) domain.TaskFilter {
+		return domain.TaskFilter{OwnerID: user.ID, Trashed: trashed, Limit: usecases.MaxPageSize, SortBy: domain.SortByID}
+	}
+	assignedTo := func(trashed bool) domain.TaskFilter {
+		return domain.TaskFilter{AssigneeID: user.ID, Trashed: trashed, Limit: usecases.MaxPageSize, SortBy: domain.SortByID}
+	}
+	//the assignments are the same for both task policies
+	expectAssignments := func() {
+		suite.taskRepo.On("GetAllTasks", assignedTo(false)).Return(&domain.TaskPage{Tasks: []domain.Task{assigned}}, nil).Once()
+		suite.taskRepo.On("GetAllTasks", assignedTo(false)).Return(empty, nil).Once()
+		suite.taskRepo.On("GetAllTasks", assignedTo(true)).Return(empty, nil).Once()
+		suite.taskRepo.On("RemoveAssignee", assigned.ID, user.ID).Return(nil).Once()
+	}
+
+	// Test 1 the tasks are transferred to the admin by default
+	suite.Run("transfer", func() {
+		suite.SetupTest()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil)
+		suite.userRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		suite.userRepo.On("FindByID", admin.UserID).Return(&domain.User{ID: admin.UserID, Role: domain.RoleAdmin}, nil).Once()
+		suite.taskRepo.On("GetAllTasks", ownedBy(false)).Return(&domain.TaskPage{Tasks: []domain.Task{owned}}, nil).Once()
+		suite.taskRepo.On("GetAllTasks", ownedBy(false)).Return(empty, nil).Once()
+		suite.taskRepo.On("GetAllTasks", ownedBy(true)).Return(empty, nil).Once()
+		suite.taskRepo.On("UpdateTaskFields", owned.ID, mock.MatchedBy(func(task *domain.Task) bool {
+			return task.OwnerID == admin.UserID
+		}), []string{"ownerId"}, owned.Version).Return(nil).Once()
+		expectAssignments()
+		suite.userRepo.On("DeleteUser", user.ID).Return(nil).Once()
+
+		err := suite.useCase.DeleteUser(context.Background(), admin, user.ID, domain.OrphanedTasksTransfer, "")
+
+		suite.NoError(err)
+		suite.taskRepo.AssertExpectations(suite.T())
+		suite.userRepo.AssertExpectations(suite.T())
+		var actions []domain.AuditAction
+		for _, call := range suite.auditRepo.Calls {
+			actions = append(actions, call.Arguments.Get(0).(*domain.AuditRecord).Action)
+		}
+		suite.Equal([]domain.AuditAction{domain.AuditUpdate, domain.AuditUnassign, domain.AuditDelete}, actions)
+		record := suite.auditRepo.lastRecord()
+		suite.Equal(user.ID, record.UserID)
+		suite.Equal([]domain.FieldChange{{Field: "username", From: "alice"}, {Field: "role", From: "User"}}, record.Changes)
+	})
+
+	// Test 2 the live tasks are moved to the trash
+	suite.Run("trash", func() {
+		suite.SetupTest()
+		suite.auditRepo.On("AddRecord", mock.Anything).Return(nil)
+		suite.userRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		suite.taskRepo.On("GetAllTasks", ownedBy(false)).Return(&domain.TaskPage{Tasks: []domain.Task{owned}}, nil).Once()
+		suite.taskRepo.On("GetAllTasks", ownedBy(false)).Return(empty, nil).Once()
+		suite.taskRepo.On("TrashTaskByID", owned.ID, admin.UserID, suite.now, owned.Version).Return(nil).Once()
+		expectAssignments()
+		suite.userRepo.On("DeleteUser", user.ID).Return(nil).Once()
+
+		err := suite.useCase.DeleteUser(context.Background(), admin, user.ID, domain.OrphanedTasksTrash, "")
+
+		suite.NoError(err)
+		suite.taskRepo.AssertExpectations(suite.T())
+		suite.taskRepo.AssertNotCalled(suite.T(), "GetAllTasks", ownedBy(true))
+		suite.userRepo.AssertExpectations(suite.T())
+	})
+
+	// Test 3 the task policy is required
+	suite.Run("missing task policy", func() {
+		suite.SetupTest()
+
+		err := suite.useCase.DeleteUser(context.Background(), admin, user.ID, "", "")
+
+		suite.ErrorIs(err, domain.ErrValidation)
+		suite.userRepo.AssertNotCalled(suite.T(), "FindByID", mock.Anything)
+	})
+
+	// Test 4 the tasks go to an existing active user other than the deleted one
+	suite.Run("invalid transfer target", func() {
+		deactivated := &domain.User{ID: domain.NewID(), DeactivatedAt: &suite.now}
+		missing := domain.NewID()
+		for _, to := range []string{user.ID, deactivated.ID, missing} {
+			suite.SetupTest()
+			suite.userRepo.On("FindByID", user.ID).Return(user, nil).Once()
+			suite.userRepo.On("FindByID", deactivated.ID).Return(deactivated, nil).Maybe()
+			suite.userRepo.On("FindByID", missing).Return(nil, domain.NotFound("user not found")).Maybe()
+
+			err := suite.useCase.DeleteUser(context.Background(), admin, user.ID, domain.OrphanedTasksTransfer, to)
+
+			suite.ErrorIs(err, domain.ErrValidation, to)
+			suite.taskRepo.AssertNotCalled(suite.T(), "GetAllTasks", mock.Anything)
+			suite.userRepo.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything)
+		}
+	})
+
+	// Test 5 the last active admin cannot be deleted
+	suite.Run("last admin", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", admin.UserID).Return(&domain.User{ID: admin.UserID, Role: domain.RoleAdmin}, nil).Once()
+		suite.userRepo.On("CountByRole", domain.RoleAdmin).Return(int64(1), nil).Once()
+
+		err := suite.useCase.DeleteUser(context.Background(), admin, admin.UserID, domain.OrphanedTasksTrash, "")
+
+		suite.ErrorIs(err, domain.ErrLastAdmin)
+		suite.userRepo.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything)
+	})
+
+	// Test 6 a task changing under the handover is a conflict
+	suite.Run("concurrent task change", func() {
+		suite.SetupTest()
+		suite.userRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		suite.taskRepo.On("GetAllTasks", ownedBy(false)).Return(&domain.TaskPage{Tasks: []domain.Task{owned}}, nil).Once()
+		suite.taskRepo.On("TrashTaskByID", owned.ID, admin.UserID, suite.now, owned.Version).Return(domain.ErrVersionMismatch).Once()
+
+		err := suite.useCase.DeleteUser(context.Background(), admin, user.ID, domain.OrphanedTasksTrash, "")
+
+		suite.ErrorIs(err, domain.ErrConflict)
+		suite.userRepo.AssertNotCalled(suite.T(), "DeleteUser", mock.Anything)
+	})
 }
-func (suite *UserUseCaseTestSuite) TestPromoteUser() {
-    validUserID := primitive.NewObjectID().Hex()
-
-    // Test 1  Successful promotion
-    suite.Run("successful promotion", func() {
-        suite.SetupTest()
-        
-
-        suite.userRepo.On("PromoteUser", validUserID).Return(nil).Once()
-
-        err := suite.useCase.PromoteUser(validUserID)
-        
-        suite.NoError(err)
-        suite.userRepo.AssertExpectations(suite.T())
-    })
-
-    // Test 2 Repository returns error
-    suite.Run("repository returns error", func() {
-        suite.SetupTest()
-        
-        expectedErr := errors.New("database error")
-        suite.userRepo.On("PromoteUser", validUserID).Return(expectedErr).Once()
-
-        err := suite.useCase.PromoteUser(validUserID)
-        
-        suite.Error(err)
-        suite.Equal(expectedErr, err) 
-        suite.userRepo.AssertExpectations(suite.T())
-    })
-
-    // Test 3 Empty user ID
-    suite.Run("empty user ID", func() {
-        suite.SetupTest()
-
-       
-        suite.userRepo.On("PromoteUser", "").Return(nil).Once()
-
-        err := suite.useCase.PromoteUser("")
-        
-        suite.NoError(err) 
-        suite.userRepo.AssertExpectations(suite.T())
-    })
-
-    // Test 4  Invalid user ID format
-    suite.Run("invalid user ID format", func() {
-        suite.SetupTest()
-        
-        invalidID := "invalid-id"
-        
-        suite.userRepo.On("PromoteUser", invalidID).Return(nil).Once()
-
-        err := suite.useCase.PromoteUser(invalidID)
-        
-        suite.NoError(err) 
-        suite.userRepo.AssertExpectations(suite.T())
-    })
-}
\ No newline at end of file
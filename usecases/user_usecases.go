@@ -1,44 +1,75 @@
 package usecases
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	domain "task_management/Domain"
+	"time"
 )
 
 type UserUseCase struct {
 	UserRepo        IUserRepository
 	PasswordService IPasswordService
 	JWTService      IJWTService
+	// AuditRepo records the changes admins make to users
+	AuditRepo IAuditRepo
+	// TaskRepo hands the tasks of deleted users over
+	TaskRepo ITaskRepo
+	// RefreshTokens keeps the hashes of the issued refresh tokens
+	RefreshTokens IRefreshTokenRepo
+	// Revocations holds the access tokens revoked before they expire
+	Revocations ITokenRevocationRepo
+	// Sessions records every login, a session lives as long as its refresh tokens
+	Sessions ISessionRepo
+	// PersonalTokens keeps the hashes of the personal access tokens users create
+	PersonalTokens IPersonalTokenRepo
+	// Now is the clock of the audit records, replaceable in tests
+	Now func() time.Time
 }
 
-func NewUserUseCase(repo IUserRepository, ps IPasswordService, jw IJWTService) *UserUseCase {
+func NewUserUseCase(repo IUserRepository, ps IPasswordService, jw IJWTService, auditRepo IAuditRepo, taskRepo ITaskRepo, refreshTokens IRefreshTokenRepo, revocations ITokenRevocationRepo, sessions ISessionRepo, personalTokens IPersonalTokenRepo) *UserUseCase {
 	return &UserUseCase{
 		UserRepo:        repo,
 		PasswordService: ps,
 		JWTService:      jw,
+		AuditRepo:       auditRepo,
+		TaskRepo:        taskRepo,
+		RefreshTokens:   refreshTokens,
+		Revocations:     revocations,
+		Sessions:        sessions,
+		PersonalTokens:  personalTokens,
+		Now:             time.Now,
 	}
 }
 
 // register use case
-func (uc *UserUseCase) Register(input *domain.RegisterUserInput) (*domain.User, error) {
+func (uc *UserUseCase) Register(ctx context.Context, input *domain.RegisterUserInput) (*domain.User, error) {
 
-	count, err := uc.UserRepo.CountByUsername(input.Username)
+	if err := validateFields(UserRules, map[string]string{
+		"username": input.Username,
+		"password": input.Password,
+	}); err != nil {
+		return nil, err
+	}
+	count, err := uc.UserRepo.CountByUsername(ctx, input.Username)
 	if err != nil {
-		return nil, errors.New("error while checking existing user")
+		return nil, domain.Internal("error while checking existing user", err)
 	}
 	if count > 0 {
-		return nil, errors.New("username already exists")
+		return nil, domain.Conflict("username already exists")
 	}
 	//check number of total users and if 0 make the first user and admin
-	totalUsers, err := uc.UserRepo.CountAll()
+	totalUsers, err := uc.UserRepo.CountAll(ctx)
 	if err != nil {
-		return nil, errors.New("error checking total users")
+		return nil, domain.Internal("error checking total users", err)
 
 	}
 	//hash the password
 	hashedPassword, err := uc.PasswordService.HashPassword(input.Password)
 	if err != nil {
-		return nil, errors.New("failed to hash password")
+		return nil, domain.Internal("failed to hash password", err)
 
 	}
 	//set the role as user first then check if it the first user and if so make it admin
@@ -52,38 +83,684 @@ func (uc *UserUseCase) Register(input *domain.RegisterUserInput) (*domain.User,
 		Password: hashedPassword,
 		Role:     role,
 	}
-	err = uc.UserRepo.CreateUser(newUser)
+	err = uc.UserRepo.CreateUser(ctx, newUser)
+	if errors.Is(err, domain.ErrConflict) {
+		return nil, err
+	}
 	if err != nil {
-		return nil, errors.New("failed to add user")
+		return nil, domain.Internal("failed to add user", err)
 	}
 	return newUser, nil
 }
 
-//login use case
+//login use case, starts a session and hands out a short lived access token and a
+//refresh token of that session
 
-func (uc *UserUseCase) Login(input domain.RegisterUserInput) (string, *domain.User, error) {
+func (uc *UserUseCase) Login(ctx context.Context, input domain.RegisterUserInput, client domain.ClientInfo) (*domain.TokenPair, *domain.User, error) {
 
 	//find username
-	user, err := uc.UserRepo.FindByUsername(input.Username)
+	user, err := uc.UserRepo.FindByUsername(ctx, input.Username)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil, domain.Unauthorized("invalid username or password")
+	}
 	if err != nil {
-		return "", nil, errors.New("invalid username or password")
+		return nil, nil, domain.Internal("failed to find user", err)
 	}
 
 	//compare password
 	ok := uc.PasswordService.ComparePassword(user.Password, input.Password)
 	if !ok {
-		return "", nil, errors.New("invalid username or password")
+		return nil, nil, domain.Unauthorized("invalid username or password")
+	}
+	if !user.IsActive() {
+		return nil, nil, domain.ErrAccountDeactivated
+	}
+
+	now := uc.Now()
+	session := &domain.Session{
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(uc.JWTService.RefreshTTL()),
+	}
+	if err := uc.Sessions.CreateSession(ctx, session); err != nil {
+		return nil, nil, domain.Internal("failed to create session", err)
+	}
+	pair, err := uc.issueTokens(ctx, user, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// refresh use case, exchanges a refresh token for a new pair. every refresh token
+// works once, a used one coming back means it leaked and the whole family is revoked
+func (uc *UserUseCase) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, *domain.User, error) {
+	if refreshToken == "" {
+		return nil, nil, domain.ErrInvalidRefreshToken
+	}
+	token, err := uc.RefreshTokens.FindByHash(ctx, uc.JWTService.HashRefreshToken(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil, domain.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, domain.Internal("failed to find refresh token", err)
+	}
+	now := uc.Now()
+	if token.RevokedAt != nil || token.IsExpired(now) {
+		return nil, nil, domain.ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		return nil, nil, uc.revokeReused(ctx, token.FamilyID)
+	}
+
+	//the user may have been deleted or deactivated since the login
+	user, err := uc.UserRepo.FindByID(ctx, token.UserID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, nil, domain.Internal("failed to find user", err)
+	}
+	if err != nil || !user.IsActive() {
+		deleted := err != nil
+		if err := uc.revokeSession(ctx, token.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		if deleted {
+			return nil, nil, domain.ErrInvalidRefreshToken
+		}
+		return nil, nil, domain.ErrAccountDeactivated
+	}
+
+	//the session may have been revoked from another device
+	session, err := uc.Sessions.FindSession(ctx, token.FamilyID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, nil, domain.Internal("failed to find session", err)
+	}
+	if err != nil || !session.IsActive(now) {
+		if err := uc.revokeSession(ctx, token.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, domain.ErrInvalidRefreshToken
+	}
+
+	//a concurrent refresh with the same token loses here and counts as a reuse
+	err = uc.RefreshTokens.MarkUsed(ctx, token.ID, now)
+	if errors.Is(err, domain.ErrConflict) {
+		return nil, nil, uc.revokeReused(ctx, token.FamilyID)
 	}
-	//generate token
-	token, err := uc.JWTService.GenerateToken(user.ID.Hex(), user.Role)
 	if err != nil {
-		return "", nil, errors.New("failed to generate token")
+		return nil, nil, domain.Internal("failed to use refresh token", err)
 	}
 
-	return token, user, nil
+	pair, err := uc.issueTokens(ctx, user, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := uc.Sessions.ExtendSession(ctx, session.ID, now, pair.RefreshExpiresAt); err != nil {
+		return nil, nil, domain.Internal("failed to extend session", err)
+	}
+	return pair, user, nil
+}
+
+// logout use case, revokes the access token until it expires and the session of the
+// refresh token. unknown and expired tokens are ignored, logging out always succeeds
+// for the client
+func (uc *UserUseCase) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	if claims, err := uc.JWTService.ParseToken(accessToken); err == nil {
+		if err := uc.Revocations.RevokeToken(ctx, claims.TokenID, claims.ExpiresAt); err != nil {
+			return domain.Internal("failed to revoke token", err)
+		}
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := uc.RefreshTokens.FindByHash(ctx, uc.JWTService.HashRefreshToken(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return domain.Internal("failed to find refresh token", err)
+	}
+	return uc.revokeSession(ctx, token.FamilyID)
+}
+
+// lists the sessions of the actor that can still be used, the session of the
+// request is marked as current
+func (uc *UserUseCase) ListSessions(ctx context.Context, actor domain.Actor) ([]domain.Session, error) {
+	sessions, err := uc.Sessions.ListSessions(ctx, actor.UserID)
+	if err != nil {
+		return nil, domain.Internal("failed to list sessions", err)
+	}
+	now := uc.Now()
+	active := make([]domain.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.IsActive(now) {
+			continue
+		}
+		session.Current = session.ID == actor.SessionID
+		active = append(active, session)
+	}
+	return active, nil
+}
+
+// revokes one session of the actor, its access and refresh tokens stop working.
+// sessions of other users are reported as not found
+func (uc *UserUseCase) RevokeSession(ctx context.Context, actor domain.Actor, sessionID string) error {
+	session, err := uc.Sessions.FindSession(ctx, sessionID)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrValidation) {
+		return err
+	}
+	if err != nil {
+		return domain.Internal("failed to find session", err)
+	}
+	if session.UserID != actor.UserID || !session.IsActive(uc.Now()) {
+		return domain.NotFound("session not found")
+	}
+	return uc.revokeSession(ctx, session.ID)
 }
 
-// promoteuser usecase
-func (uc *UserUseCase) PromoteUser(userID string) error {
-	return uc.UserRepo.PromoteUser(userID)
+// lifetime of a personal access token created without an expiry, and the longest one
+const (
+	DefaultPersonalTokenTTL = 30 * 24 * time.Hour
+	MaxPersonalTokenTTL     = 365 * 24 * time.Hour
+)
+
+// creates a personal access token for the actor. the token is only returned here,
+// the store keeps its hash
+func (uc *UserUseCase) CreatePersonalToken(ctx context.Context, actor domain.Actor, input domain.PersonalTokenInput) (*domain.PersonalToken, string, error) {
+	now := uc.Now()
+	input.Name = strings.TrimSpace(input.Name)
+	if err := checkPersonalToken(input, now); err != nil {
+		return nil, "", err
+	}
+
+	token, hash, err := uc.JWTService.GeneratePersonalToken()
+	if err != nil {
+		return nil, "", domain.Internal("failed to generate personal token", err)
+	}
+	stored := &domain.PersonalToken{
+		UserID:    actor.UserID,
+		Name:      input.Name,
+		Scopes:    normalizeScopes(input.Scopes),
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(DefaultPersonalTokenTTL),
+	}
+	if input.ExpiresAt != nil {
+		stored.ExpiresAt = *input.ExpiresAt
+	}
+	if err := uc.PersonalTokens.CreatePersonalToken(ctx, stored); err != nil {
+		return nil, "", domain.Internal("failed to store personal token", err)
+	}
+	return stored, token, nil
+}
+
+// lists the personal access tokens of the actor that can still be used
+func (uc *UserUseCase) ListPersonalTokens(ctx context.Context, actor domain.Actor) ([]domain.PersonalToken, error) {
+	tokens, err := uc.PersonalTokens.ListPersonalTokens(ctx, actor.UserID)
+	if err != nil {
+		return nil, domain.Internal("failed to list personal tokens", err)
+	}
+	now := uc.Now()
+	active := make([]domain.PersonalToken, 0, len(tokens))
+	for _, token := range tokens {
+		if token.IsActive(now) {
+			active = append(active, token)
+		}
+	}
+	return active, nil
+}
+
+// revokes one personal access token of the actor, tokens of other users are
+// reported as not found
+func (uc *UserUseCase) RevokePersonalToken(ctx context.Context, actor domain.Actor, tokenID string) error {
+	token, err := uc.PersonalTokens.FindPersonalToken(ctx, tokenID)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrValidation) {
+		return err
+	}
+	if err != nil {
+		return domain.Internal("failed to find personal token", err)
+	}
+	now := uc.Now()
+	if token.UserID != actor.UserID || !token.IsActive(now) {
+		return domain.NotFound("personal token not found")
+	}
+	if err := uc.PersonalTokens.RevokePersonalToken(ctx, token.ID, now); err != nil {
+		return domain.Internal("failed to revoke personal token", err)
+	}
+	return nil
+}
+
+// checks the name, scopes and expiry of a new personal access token
+func checkPersonalToken(input domain.PersonalTokenInput, now time.Time) error {
+	var invalid []domain.FieldError
+	nameRule, scopesRule := PersonalTokenRules[0], PersonalTokenRules[1]
+	if msg := checkRule(nameRule, input.Name); msg != "" {
+		invalid = append(invalid, domain.FieldError{Field: nameRule.Field, Message: msg})
+	}
+	if len(input.Scopes) == 0 {
+		invalid = append(invalid, domain.FieldError{Field: scopesRule.Field, Message: checkRule(scopesRule, "")})
+	}
+	for _, scope := range input.Scopes {
+		if msg := checkRule(scopesRule, string(scope)); msg != "" {
+			invalid = append(invalid, domain.FieldError{Field: scopesRule.Field, Message: msg})
+		}
+	}
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(now) {
+			invalid = append(invalid, domain.FieldError{Field: "expiresAt", Message: "expiresAt must be in the future"})
+		} else if input.ExpiresAt.After(now.Add(MaxPersonalTokenTTL)) {
+			invalid = append(invalid, domain.FieldError{Field: "expiresAt", Message: fmt.Sprintf("expiresAt must be at most %d days away", MaxPersonalTokenTTL/(24*time.Hour))})
+		}
+	}
+	return invalidFields(invalid)
+}
+
+// drops duplicate scopes and puts them in the order of domain.Scopes
+func normalizeScopes(requested []domain.Scope) []domain.Scope {
+	scopes := []domain.Scope{}
+	for _, scope := range domain.Scopes {
+		for _, r := range requested {
+			if r == scope {
+				scopes = append(scopes, scope)
+				break
+			}
+		}
+	}
+	return scopes
+}
+
+// log out everywhere use case, an admin revokes every access, refresh and personal
+// access token the user holds. the user can log in again right away
+func (uc *UserUseCase) LogoutEverywhere(ctx context.Context, actor domain.Actor, userID string) error {
+	if !actor.IsAdmin() {
+		return domain.Forbidden("only admins can log users out")
+	}
+	if _, err := uc.findUser(ctx, userID); err != nil {
+		return err
+	}
+
+	//access tokens carry their issue time in milliseconds, a token issued in the same
+	//millisecond as the cutoff is revoked too. a new login takes longer than that
+	now := uc.Now().Truncate(time.Millisecond)
+	if err := uc.RefreshTokens.RevokeUserTokens(ctx, userID, now); err != nil {
+		return domain.Internal("failed to revoke refresh tokens", err)
+	}
+	if err := uc.Sessions.RevokeUserSessions(ctx, userID, now); err != nil {
+		return domain.Internal("failed to revoke sessions", err)
+	}
+	if err := uc.PersonalTokens.RevokeUserPersonalTokens(ctx, userID, now); err != nil {
+		return domain.Internal("failed to revoke personal tokens", err)
+	}
+	//the cutoff is useless once every access token issued before it expired
+	if err := uc.Revocations.RevokeUserTokens(ctx, userID, now, now.Add(uc.JWTService.AccessTTL())); err != nil {
+		return domain.Internal("failed to revoke tokens", err)
+	}
+	return uc.auditUser(ctx, actor, domain.AuditLogoutEverywhere, userID, nil)
+}
+
+// signs an access token and stores a new refresh token of the session, the session
+// ID is the family of the refresh token
+func (uc *UserUseCase) issueTokens(ctx context.Context, user *domain.User, sessionID string) (*domain.TokenPair, error) {
+	accessToken, err := uc.JWTService.GenerateToken(user.ID, user.Role, sessionID)
+	if err != nil {
+		return nil, domain.Internal("failed to generate token", err)
+	}
+	refreshToken, hash, err := uc.JWTService.GenerateRefreshToken()
+	if err != nil {
+		return nil, domain.Internal("failed to generate refresh token", err)
+	}
+	now := uc.Now()
+	stored := &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(uc.JWTService.RefreshTTL()),
+	}
+	if err := uc.RefreshTokens.CreateToken(ctx, stored); err != nil {
+		return nil, domain.Internal("failed to store refresh token", err)
+	}
+	return &domain.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  now.Add(uc.JWTService.AccessTTL()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+func (uc *UserUseCase) revokeReused(ctx context.Context, sessionID string) error {
+	if err := uc.revokeSession(ctx, sessionID); err != nil {
+		return err
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+// revokes the session and every refresh token of its family. families started
+// before sessions were recorded have no session
+func (uc *UserUseCase) revokeSession(ctx context.Context, sessionID string) error {
+	now := uc.Now()
+	if err := uc.RefreshTokens.RevokeFamily(ctx, sessionID, now); err != nil {
+		return domain.Internal("failed to revoke refresh tokens", err)
+	}
+	err := uc.Sessions.RevokeSession(ctx, sessionID, now)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) && !errors.Is(err, domain.ErrValidation) {
+		return domain.Internal("failed to revoke session", err)
+	}
+	return nil
+}
+
+// change role usecase, admins grant and revoke roles. the last admin keeps
+// the admin role so there is always someone left to manage the users
+func (uc *UserUseCase) ChangeRole(ctx context.Context, actor domain.Actor, userID string, role domain.Role) (*domain.User, error) {
+	if !actor.IsAdmin() {
+		return nil, domain.Forbidden("only admins can change roles")
+	}
+	if !role.IsValid() {
+		return nil, domain.FieldValidation("role", "role must be Admin or User")
+	}
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if err := uc.checkNotLastAdmin(ctx, user); err != nil {
+		return nil, err
+	}
+
+	err = uc.UserRepo.UpdateRole(ctx, userID, role)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrLastAdmin) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, domain.Internal("failed to change role", err)
+	}
+
+	changes := []domain.FieldChange{{Field: "role", From: string(user.Role), To: string(role)}}
+	if err := uc.auditUser(ctx, actor, domain.AuditRoleChange, userID, changes); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+// lists one page of the users, only admins can see who exists
+func (uc *UserUseCase) ListUsers(ctx context.Context, actor domain.Actor, filter domain.UserFilter) (*domain.UserPage, error) {
+	if !actor.IsAdmin() {
+		return nil, domain.Forbidden("only admins can list users")
+	}
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = DefaultPageSize
+	case filter.Limit < 0:
+		return nil, domain.FieldValidation("limit", "limit must be positive")
+	case filter.Limit > MaxPageSize:
+		filter.Limit = MaxPageSize
+	}
+	if filter.Role != "" && !filter.Role.IsValid() {
+		return nil, domain.FieldValidation("role", "role must be Admin or User")
+	}
+	if _, err := domain.DecodeCursor(filter.Cursor); err != nil {
+		return nil, err
+	}
+
+	page, err := uc.UserRepo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, domain.Internal("failed to retrieve users", err)
+	}
+	return page, nil
+}
+
+// returns one user to an admin
+func (uc *UserUseCase) GetUser(ctx context.Context, actor domain.Actor, userID string) (*domain.User, error) {
+	if !actor.IsAdmin() {
+		return nil, domain.Forbidden("only admins can read users")
+	}
+	return uc.findUser(ctx, userID)
+}
+
+// deactivates a user, they cannot log in nor use their token until an admin reactivates them
+func (uc *UserUseCase) DeactivateUser(ctx context.Context, actor domain.Actor, userID string) (*domain.User, error) {
+	return uc.setActive(ctx, actor, userID, false)
+}
+
+// reactivates a deactivated user
+func (uc *UserUseCase) ReactivateUser(ctx context.Context, actor domain.Actor, userID string) (*domain.User, error) {
+	return uc.setActive(ctx, actor, userID, true)
+}
+
+func (uc *UserUseCase) setActive(ctx context.Context, actor domain.Actor, userID string, active bool) (*domain.User, error) {
+	if !actor.IsAdmin() {
+		return nil, domain.Forbidden("only admins can deactivate or reactivate users")
+	}
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsActive() == active {
+		return user, nil
+	}
+
+	action := domain.AuditReactivate
+	var deactivatedAt *time.Time
+	if !active {
+		if err := uc.checkNotLastAdmin(ctx, user); err != nil {
+			return nil, err
+		}
+		now := uc.Now()
+		action, deactivatedAt = domain.AuditDeactivate, &now
+	}
+	err = uc.UserRepo.UpdateDeactivatedAt(ctx, userID, deactivatedAt)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrLastAdmin) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, domain.Internal("failed to update user", err)
+	}
+
+	changes := []domain.FieldChange{{Field: "deactivatedAt", From: formatTime(user.DeactivatedAt), To: formatTime(deactivatedAt)}}
+	if err := uc.auditUser(ctx, actor, action, userID, changes); err != nil {
+		return nil, err
+	}
+	user.DeactivatedAt = deactivatedAt
+	return user, nil
+}
+
+// deletes a user for an admin. the tasks the user owns are either transferred to
+// transferTo, the admin when it is empty, or moved to the trash, and the user is
+// removed from the tasks they were assigned to
+func (uc *UserUseCase) DeleteUser(ctx context.Context, actor domain.Actor, userID string, tasks domain.OrphanedTasks, transferTo string) error {
+	if !actor.IsAdmin() {
+		return domain.Forbidden("only admins can delete users")
+	}
+	if tasks != domain.OrphanedTasksTransfer && tasks != domain.OrphanedTasksTrash {
+		return domain.FieldValidation("tasks", "tasks must be transfer or trash")
+	}
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := uc.checkNotLastAdmin(ctx, user); err != nil {
+		return err
+	}
+
+	if tasks == domain.OrphanedTasksTransfer {
+		if transferTo == "" {
+			transferTo = actor.UserID
+		}
+		if err := uc.checkTransferTarget(ctx, user.ID, transferTo); err != nil {
+			return err
+		}
+	}
+	if err := uc.handOverTasks(ctx, actor, user.ID, tasks, transferTo); err != nil {
+		return err
+	}
+	if err := uc.dropAssignments(ctx, actor, user.ID); err != nil {
+		return err
+	}
+
+	//the repository refuses to delete the last active admin even when another request
+	//demoted the other admins after the check above, the tasks stay handed over then
+	err = uc.UserRepo.DeleteUser(ctx, user.ID)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrLastAdmin) {
+		return err
+	}
+	if err != nil {
+		return domain.Internal("failed to delete user", err)
+	}
+	changes := []domain.FieldChange{{Field: "username", From: user.Username}, {Field: "role", From: string(user.Role)}}
+	return uc.auditUser(ctx, actor, domain.AuditDelete, user.ID, changes)
+}
+
+// checkTransferTarget makes sure the tasks of a deleted user go to another active user
+func (uc *UserUseCase) checkTransferTarget(ctx context.Context, userID, transferTo string) error {
+	if transferTo == userID {
+		return domain.FieldValidation("to", "tasks cannot be transferred to the deleted user")
+	}
+	target, err := uc.UserRepo.FindByID(ctx, transferTo)
+	if errors.Is(err, domain.ErrValidation) || errors.Is(err, domain.ErrNotFound) {
+		return domain.FieldValidation("to", "the user receiving the tasks does not exist")
+	}
+	if err != nil {
+		return domain.Internal("failed to find user", err)
+	}
+	if !target.IsActive() {
+		return domain.FieldValidation("to", "the user receiving the tasks is deactivated")
+	}
+	return nil
+}
+
+// handOverTasks transfers or trashes the tasks a user owns. trashed tasks follow
+// their owner on a transfer so they can still be restored
+func (uc *UserUseCase) handOverTasks(ctx context.Context, actor domain.Actor, ownerID string, tasks domain.OrphanedTasks, transferTo string) error {
+	filters := []domain.TaskFilter{{OwnerID: ownerID}}
+	if tasks == domain.OrphanedTasksTransfer {
+		filters = append(filters, domain.TaskFilter{OwnerID: ownerID, Trashed: true})
+	}
+	for _, filter := range filters {
+		err := uc.drainTasks(ctx, actor, filter, func(task *domain.Task) (domain.AuditAction, *domain.Task, error) {
+			after := *task
+			if tasks == domain.OrphanedTasksTransfer {
+				after.OwnerID = transferTo
+				return domain.AuditUpdate, &after, uc.TaskRepo.UpdateTaskFields(ctx, task.ID, &after, []string{"ownerId"}, task.Version)
+			}
+			deletedAt := uc.Now()
+			after.DeletedAt, after.DeletedBy = &deletedAt, actor.UserID
+			return domain.AuditDelete, &after, uc.TaskRepo.TrashTaskByID(ctx, task.ID, actor.UserID, deletedAt, task.Version)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropAssignments removes a user from every task they are assigned to, trashed tasks included
+func (uc *UserUseCase) dropAssignments(ctx context.Context, actor domain.Actor, userID string) error {
+	for _, trashed := range []bool{false, true} {
+		err := uc.drainTasks(ctx, actor, domain.TaskFilter{AssigneeID: userID, Trashed: trashed}, func(task *domain.Task) (domain.AuditAction, *domain.Task, error) {
+			after := *task
+			after.Assignees = nil
+			for _, id := range task.Assignees {
+				if id != userID {
+					after.Assignees = append(after.Assignees, id)
+				}
+			}
+			return domain.AuditUnassign, &after, uc.TaskRepo.RemoveAssignee(ctx, task.ID, userID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainTasks applies change to the tasks matching the filter and audits every change.
+// change must make the task drop out of the filter, the first page is read again
+// until it comes back empty
+func (uc *UserUseCase) drainTasks(ctx context.Context, actor domain.Actor, filter domain.TaskFilter, change func(task *domain.Task) (domain.AuditAction, *domain.Task, error)) error {
+	filter.Limit = MaxPageSize
+	filter.SortBy = domain.SortByID
+	for {
+		page, err := uc.TaskRepo.GetAllTasks(ctx, filter)
+		if err != nil {
+			return domain.Internal("failed to retrieve the tasks of the user", err)
+		}
+		if len(page.Tasks) == 0 {
+			return nil
+		}
+		for i := range page.Tasks {
+			before := &page.Tasks[i]
+			action, after, err := change(before)
+			if errors.Is(err, domain.ErrVersionMismatch) {
+				return domain.Conflict("a task of the user changed while it was handed over, try again")
+			}
+			if err != nil {
+				return domain.Internal("failed to hand over the tasks of the user", err)
+			}
+			record := &domain.AuditRecord{
+				TaskID:  before.ID,
+				Action:  action,
+				ActorID: actor.UserID,
+				At:      uc.Now(),
+				Changes: domain.DiffTasks(before, after),
+			}
+			if err := uc.AuditRepo.AddRecord(ctx, record); err != nil {
+				return domain.Internal("failed to record the change in the audit trail", err)
+			}
+		}
+	}
+}
+
+// findUser loads a user, a malformed id is a validation error
+func (uc *UserUseCase) findUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := uc.UserRepo.FindByID(ctx, userID)
+	if errors.Is(err, domain.ErrValidation) || errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, domain.Internal("failed to find user", err)
+	}
+	return user, nil
+}
+
+// checkNotLastAdmin keeps the last active admin from losing the admin role or the account.
+// it fails early, the repository makes the same check atomically with the write
+func (uc *UserUseCase) checkNotLastAdmin(ctx context.Context, user *domain.User) error {
+	if user.Role != domain.RoleAdmin || !user.IsActive() {
+		return nil
+	}
+	admins, err := uc.UserRepo.CountByRole(ctx, domain.RoleAdmin)
+	if err != nil {
+		return domain.Internal("failed to count admins", err)
+	}
+	if admins <= 1 {
+		return domain.ErrLastAdmin
+	}
+	return nil
+}
+
+// auditUser adds the record of a change an admin made to a user to the audit trail
+func (uc *UserUseCase) auditUser(ctx context.Context, actor domain.Actor, action domain.AuditAction, userID string, changes []domain.FieldChange) error {
+	record := &domain.AuditRecord{
+		UserID:  userID,
+		Action:  action,
+		ActorID: actor.UserID,
+		At:      uc.Now(),
+		Changes: changes,
+	}
+	if err := uc.AuditRepo.AddRecord(ctx, record); err != nil {
+		return domain.Internal("failed to record the change in the audit trail", err)
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
 }
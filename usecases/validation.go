@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	domain "task_management/Domain"
+)
+
+// TaskRules are the rules of the task fields clients can set
+var TaskRules = domain.CompileRules([]domain.FieldRule{
+	{Field: "title", Required: true, MaxLength: 200},
+	{Field: "description", MaxLength: 5000},
+	{Field: "status", OneOf: []string{string(domain.StatusNotStarted), string(domain.StatusInProgress), string(domain.StatusCompleted)}},
+	{Field: "dueDate", Format: domain.FormatDateTime},
+})
+
+// UserRules are the rules of the registration fields.
+// bcrypt refuses passwords longer than 72 bytes, the limit is in bytes not characters
+var UserRules = domain.CompileRules([]domain.FieldRule{
+	{Field: "username", Required: true, MinLength: 3, MaxLength: 32, Pattern: `^[A-Za-z0-9._-]+$`, PatternMessage: "may only contain letters, digits, '.', '_' and '-'"},
+	{Field: "password", Required: true, MinLength: 8, MaxBytes: 72},
+})
+
+// PersonalTokenRules are the rules of the personal access token fields, every entry
+// of the scopes list is checked on its own
+var PersonalTokenRules = domain.CompileRules([]domain.FieldRule{
+	{Field: "name", Required: true, MaxLength: 100},
+	{Field: "scopes", Required: true, OneOf: scopeNames()},
+})
+
+func scopeNames() []string {
+	names := make([]string, len(domain.Scopes))
+	for i, scope := range domain.Scopes {
+		names[i] = string(scope)
+	}
+	return names
+}
+
+// ValidationRules returns every rule set by the name of the input it applies to
+func ValidationRules() map[string][]domain.FieldRule {
+	return map[string][]domain.FieldRule{
+		"task":          TaskRules,
+		"user":          UserRules,
+		"personalToken": PersonalTokenRules,
+	}
+}
+
+// validateFields checks values against the rules and reports every invalid field.
+// fields missing from values are not checked, a partial update only validates what it changes
+func validateFields(rules []domain.FieldRule, values map[string]string) error {
+	var invalid []domain.FieldError
+	for _, rule := range rules {
+		value, ok := values[rule.Field]
+		if !ok {
+			continue
+		}
+		if msg := checkRule(rule, value); msg != "" {
+			invalid = append(invalid, domain.FieldError{Field: rule.Field, Message: msg})
+		}
+	}
+	return invalidFields(invalid)
+}
+
+// invalidFields turns the invalid fields into one validation error, nil when there are none
+func invalidFields(invalid []domain.FieldError) error {
+	switch len(invalid) {
+	case 0:
+		return nil
+	case 1:
+		return domain.InvalidFields(invalid[0].Message, invalid)
+	}
+	messages := make([]string, len(invalid))
+	for i, field := range invalid {
+		messages[i] = field.Message
+	}
+	return domain.InvalidFields("invalid input: "+strings.Join(messages, "; "), invalid)
+}
+
+// checkRule returns what is wrong with the value, empty values are only checked for being required
+func checkRule(rule domain.FieldRule, value string) string {
+	if value == "" {
+		if rule.Required {
+			return rule.Field + " cannot be empty"
+		}
+		return ""
+	}
+
+	length := utf8.RuneCountInString(value)
+	switch {
+	case rule.MinLength > 0 && length < rule.MinLength:
+		return fmt.Sprintf("%s must be at least %d characters", rule.Field, rule.MinLength)
+	case rule.MaxLength > 0 && length > rule.MaxLength:
+		return fmt.Sprintf("%s must be at most %d characters", rule.Field, rule.MaxLength)
+	case rule.MaxBytes > 0 && len(value) > rule.MaxBytes:
+		return fmt.Sprintf("%s must be at most %d bytes", rule.Field, rule.MaxBytes)
+	}
+	if rule.Pattern != "" && !rule.Regexp.MatchString(value) {
+		return rule.Field + " " + rule.PatternMessage
+	}
+	if len(rule.OneOf) > 0 && !contains(rule.OneOf, value) {
+		return fmt.Sprintf("invalid %s %q: must be one of %s", rule.Field, value, strings.Join(rule.OneOf, ", "))
+	}
+	if rule.Format == domain.FormatDateTime {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Sprintf("invalid %s %q: expected an RFC3339 date such as 2025-01-31T17:00:00Z", rule.Field, value)
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}